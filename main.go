@@ -10,6 +10,7 @@ import (
 
 var confPath = flag.String("conf", "./conf/server.json", "server conf path")
 var confDemo = flag.Bool("conf_demo", false, "show the demo conf")
+var strictCheck = flag.Bool("strict_check", false, "exit when the startup self check has errors")
 
 func init() {
 	log.SetFlags(log.Lshortfile | log.LstdFlags | log.Ldate)
@@ -28,6 +29,7 @@ func main() {
 		return
 	}
 	manager := proxy.NewAPIServerManager(*confPath)
+	manager.StrictCheck = *strictCheck
 	manager.Start()
 }
 
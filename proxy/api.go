@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -34,6 +35,94 @@ type apiStruct struct {
 	Proxy        string       `json:"proxy"`         //使用父代理
 	RespModifier RespModifier `json:"resp_modifier"` //
 
+	StatusRemap  map[int]int      `json:"status_remap"`   //返回给客户端前对master的状态码进行替换,如 422->400
+	BodyLimit    map[string]int64 `json:"body_limit"`     //按method限制请求body大小(字节),"*"为默认值,不设置则不限制
+	MaxURLLength int              `json:"max_url_length"` //请求的path和query的最大长度,超过返回414,默认8192
+
+	TimeoutJitterMs int         `json:"timeout_jitter_ms"`     //超时时间随机增加[0,n]ms,避免同时超时
+	RespAssert      *RespAssert `json:"resp_assert,omitempty"` //对master返回内容进行检查,不影响返回给client的内容
+	SubRoutes       []*SubRoute `json:"sub_routes"`            //子路径转发到部分host,按顺序匹配第一个
+
+	RespHeaders         map[string]string `json:"resp_headers"`          //默认添加的response header
+	RespHeadersOverride bool              `json:"resp_headers_override"` //true:覆盖后端返回的同名header,false:后端返回的优先
+
+	DefaultMaster string `json:"default_master"` //默认的master host,没有优先配置时使用,为空则随机选取
+
+	TrailingSlash string `json:"trailing_slash"` //请求路径缺少结尾的/时:strict(默认,不匹配),match(视为相同),redirect(跳转)
+	RootPath      string `json:"root_path"`      //请求接口路径本身(如/a/)时,后端地址:keep(默认,同host的url),slash(以/结尾),bare(去掉结尾的/)
+
+	MaxConcurrent  int         `json:"max_concurrent"`   //最大并发请求数,0为不限制
+	QueueSize      int         `json:"queue_size"`       //超过并发数时排队的请求数,队列满时返回503
+	QueueTimeoutMs int         `json:"queue_timeout_ms"` //排队的超时时间,默认同timeout_ms,超时返回503
+	FairQueue      bool        `json:"fair_queue"`       //排队的请求按调用方(ip)轮流获得空出的并发数,避免一个调用方占满
+	limiter        slotLimiter `json:"-"`
+
+	Golden string `json:"golden"` //回归测试:record(记录master的response),compare(与记录的对比,不一致时记录日志)
+
+	Comparator     *ComparatorConf `json:"comparator,omitempty"` //对比response body的方式,默认按字节对比
+	bodyComparator BodyComparator  `json:"-"`
+
+	StickyJSONPath string `json:"sticky_json_path"` //从json body中按该路径(如 context.transaction_id)取值,相同值的请求使用相同的master
+
+	MaxShadowHosts int   `json:"max_shadow_hosts"` //除master外最多转发到几个host,优先选择连续失败少、耗时短的,0为不限制
+	ShadowMaxBody  int64 `json:"shadow_max_body"`  //请求body超过该大小(字节)时只转发给master,不转发给其他host(如批量上传),0为不限制
+
+	ShadowDiff bool `json:"shadow_diff"` //对比其他host和master的response(状态码、body),不同之处记录在日志的shadow_diff中
+
+	MinifyJSON bool `json:"minify_json"` //转发前去掉json请求body中的空白,非json或者json不合法时不修改
+
+	Methods []string `json:"methods"` //允许的method,如["GET","POST"],其他的返回405,为空则不限制
+
+	HeadAsGet bool `json:"head_as_get"` //HEAD请求以GET转发给后端(后端没有实现HEAD时),只返回header,不返回body
+
+	SchemaVersion int `json:"schema_version"` //配置格式的版本,加载时旧格式会升级到当前版本
+
+	AllowOnly []string     `json:"allow_only"` //只允许这些调用方ip访问(支持CIDR,如 10.0.0.0/8),其他的返回403,为空则不限制
+	allowNets []*net.IPNet `json:"-"`
+
+	CookieDomain map[string]string `json:"cookie_domain"` //后端Set-Cookie的Domain替换,如{"backend.local":"example.com"},"*"匹配所有,替换为""则去掉Domain
+	CookiePath   map[string]string `json:"cookie_path"`   //后端Set-Cookie的Path前缀替换,如{"/v1/":"/api/"},使用最长的匹配
+
+	SuccessCriteria *SuccessCriteria `json:"success,omitempty"` //master的结果是否成功的条件,用于错误统计和all_fail_threshold
+
+	Ack *AckConf `json:"ack,omitempty"` //配置后直接返回该结果给client,不等待后端,master和其他host都在后台调用
+
+	ClientCertHeaders *ClientCertHeaders `json:"client_cert_headers,omitempty"` //把验证过的client证书的subject和指纹通过header转发给后端,client发送的同名header会被去掉
+
+	Enrich *EnrichConf `json:"enrich,omitempty"` //按请求的header或路径中的值查表,给转发的请求添加header,client发送的同名header会被去掉
+
+	Transform *TransformConf `json:"transform,omitempty"` //把master的response body发送(POST)给该服务转换,返回转换后的body给client,不包括stream的response
+
+	Deprecation *DeprecationConf `json:"deprecation,omitempty"` //接口已弃用,master的response添加Deprecation、Sunset header,并记录调用日志
+
+	ReloadHook *ReloadHookConf `json:"reload_hook,omitempty"` //加载(包括修改后重新加载)成功后调用的webhook或者命令,失败只记录日志
+
+	Scheme string `json:"scheme"` //转发到后端时替换host url的scheme(http/https),host的scheme优先,为空则使用url中的
+
+	GzipLevel int `json:"gzip_level"` //host设置gzip_body时的gzip压缩级别,-2(HuffmanOnly)~9,默认使用子服务的gzip_level
+
+	DailyByteQuota int64 `json:"daily_byte_quota"` //每日request+response的字节数配额,用完后返回429,0为不限制
+	QuotaPerCaller bool  `json:"quota_per_caller"` //配额按调用方的ip分别计算,同一caller规则(如网段)下的ip不共享
+
+	AllFailThreshold  int         `json:"all_fail_threshold"`   //连续n次请求所有后端都失败后,在冷却期内直接返回503,0为不启用
+	AllFailCooldownMs int         `json:"all_fail_cooldown_ms"` //冷却时间,默认10000ms
+	backoff           *apiBackoff `json:"-"`
+
+	AccessLog        string      `json:"access_log"`          //单独记录访问日志到confDir/logs/{id}.log:both(同时写入总日志),only(只写入该文件)
+	AccessLogMaxSize int64       `json:"access_log_max_size"` //单独的访问日志超过该大小(字节)后切分,默认100M
+	LogFormat        string      `json:"log_format"`          //访问日志格式:common,combined(Apache Common/Combined Log Format),默认为key=value格式
+	accessLog        *log.Logger `json:"-"`
+
+	ReuseConn          bool           `json:"reuse_conn"`             //后端连接使用keep-alive,相同地址(scheme://host)和连接配置的host(包括master和其他host)共用连接池,默认每个请求新建连接
+	ReuseConnIdleMs    int            `json:"reuse_conn_idle_ms"`     //空闲超过该时间的连接关闭,不再复用,默认90000
+	ReuseConnMaxLifeMs int            `json:"reuse_conn_max_life_ms"` //连接池使用超过该时间后换新的,旧的连接在请求结束后关闭,0为不限制
+	transports         *transportPool `json:"-"`
+
+	PreCheck          string `json:"pre_check"`            //加载时检查是否有host可以连接:warn(都不能连接时记录日志),strict(都不能连接时不启用该接口),默认不检查
+	PreCheckTimeoutMs int    `json:"pre_check_timeout_ms"` //检查连接的超时,默认1000ms
+
+	WriteTimeoutMs int `json:"write_timeout_ms"` //向client写response,超过该时间写不进去(如client不读取)则断开,释放后端连接,0为不限制
+
 	proxyURL *url.URL `json:"-"` //父代理的URL object
 
 	analysisClientNum int `json:"-"` //正在进行协议分析的客户端数量
@@ -60,6 +149,9 @@ func (api *apiStruct) init() (err error) {
 	if api.TimeoutMs < 1 {
 		api.TimeoutMs = 5000
 	}
+	if api.TimeoutJitterMs < 0 {
+		api.TimeoutJitterMs = 0
+	}
 	if api.Caller == nil {
 		api.Caller = newCaller()
 		item, _ := newCallerItem(ipAll)
@@ -74,6 +166,10 @@ func (api *apiStruct) init() (err error) {
 	if api.Proxy != "" {
 		api.proxyURL, _ = url.Parse(api.Proxy)
 	}
+	api.transports = newTransportPool()
+	if api.ClientCertHeaders != nil {
+		api.ClientCertHeaders.init()
+	}
 
 	if api.RespModifier == nil {
 		api.RespModifier = newRespModifierSlice()
@@ -82,6 +178,102 @@ func (api *apiStruct) init() (err error) {
 		return e
 	}
 
+	for from, to := range api.StatusRemap {
+		if !isValidStatusCode(from) || !isValidStatusCode(to) {
+			return fmt.Errorf("status_remap wrong:%d->%d", from, to)
+		}
+	}
+
+	api.initBodyLimit()
+	if api.MaxURLLength < 1 {
+		api.MaxURLLength = defaultMaxURLLength
+	}
+	api.initMethods()
+	if e := api.initAllowOnly(); e != nil {
+		return e
+	}
+	api.initRespHeaders()
+	api.initCookieRewrite()
+
+	if api.Ack != nil {
+		if e := api.Ack.init(); e != nil {
+			return fmt.Errorf("ack wrong:%s", e)
+		}
+	}
+
+	if api.Enrich != nil {
+		if e := api.Enrich.init(); e != nil {
+			return fmt.Errorf("enrich wrong:%s", e)
+		}
+	}
+
+	if api.Transform != nil {
+		if e := api.Transform.init(); e != nil {
+			return fmt.Errorf("transform wrong:%s", e)
+		}
+	}
+
+	if api.Deprecation != nil {
+		if e := api.Deprecation.init(); e != nil {
+			return fmt.Errorf("deprecation wrong:%s", e)
+		}
+	}
+
+	if api.ReloadHook != nil {
+		if e := api.ReloadHook.init(); e != nil {
+			return fmt.Errorf("reload_hook wrong:%s", e)
+		}
+	}
+
+	if api.SuccessCriteria != nil {
+		if e := api.SuccessCriteria.init(); e != nil {
+			return fmt.Errorf("success wrong:%s", e)
+		}
+	}
+
+	if api.RespAssert != nil {
+		if e := api.RespAssert.init(); e != nil {
+			return fmt.Errorf("resp_assert wrong:%s", e)
+		}
+	}
+
+	api.initLimiter()
+	api.initBackoff()
+
+	if e := api.initAccessLog(); e != nil {
+		return e
+	}
+
+	if e := api.initGolden(); e != nil {
+		return e
+	}
+	if e := api.initComparator(); e != nil {
+		return e
+	}
+
+	if e := api.initTrailingSlash(); e != nil {
+		return e
+	}
+	if e := api.initRootPath(); e != nil {
+		return e
+	}
+
+	if e := api.initSubRoutes(); e != nil {
+		return e
+	}
+
+	if e := api.initScheme(); e != nil {
+		return e
+	}
+
+	if e := api.initPreCheck(); e != nil {
+		return e
+	}
+
+	if e := checkGzipLevel(api.GzipLevel); e != nil {
+		return e
+	}
+
 	api.Caller.Sort()
 	err = api.Caller.init()
 
@@ -98,25 +290,50 @@ func (api *apiStruct) isValidPath(myPath string) bool {
 }
 
 func (api *apiStruct) save() error {
+	if api.apiServer != nil && !api.apiServer.confWritable() {
+		return errConfReadOnly
+	}
 	api.rw.Lock()
 	defer api.rw.Unlock()
 
+	for _, citem := range api.Caller {
+		if _, err := citem.parseCIDR(); err != nil {
+			return err
+		}
+	}
+	api.SchemaVersion = apiSchemaVersion
 	data, err := json.MarshalIndent(api, "", "    ")
 	if err != nil {
 		return err
 	}
 	oldData, _ := ioutil.ReadFile(api.ConfPath)
-	if string(oldData) != string(data) {
-		backPath := filepath.Dir(api.ConfPath) + "/_back/" + filepath.Base(api.ConfPath) + "." + time.Now().Format(timeFormatInt)
-		DirCheck(backPath)
-		err = ioutil.WriteFile(backPath, oldData, 0644)
-		log.Println("backup ", backPath, err)
+	if string(oldData) == string(data) {
+		return nil
+	}
+	backPath := filepath.Dir(api.ConfPath) + "/_back/" + filepath.Base(api.ConfPath) + "." + time.Now().Format(timeFormatInt)
+	DirCheck(backPath)
+	err = ioutil.WriteFile(backPath, oldData, 0644)
+	log.Println("backup ", backPath, err)
+
+	api.Version++
+	data, err = json.MarshalIndent(api, "", "    ")
+	if err != nil {
+		return err
 	}
 	err = ioutil.WriteFile(api.ConfPath, data, 0644)
-	return err
+	if err != nil {
+		return err
+	}
+	if e := api.saveVersion(data); e != nil {
+		log.Println("[warning]save version failed:", api.ID, api.Version, e)
+	}
+	return nil
 }
 
 func (api *apiStruct) delete() error {
+	if api.apiServer != nil && !api.apiServer.confWritable() {
+		return errConfReadOnly
+	}
 	api.rw.Lock()
 	defer api.rw.Unlock()
 	backPath := filepath.Dir(api.ConfPath) + "/_back/" + filepath.Base(api.ConfPath) + "." + time.Now().Format(timeFormatInt)
@@ -149,8 +366,8 @@ func (api *apiStruct) clone() *apiStruct {
 	newAPI.ID = api.ID
 	newAPI.ConfPath = api.ConfPath
 	newAPI.Exists = api.Exists
-	newAPI.init()
 	newAPI.apiServer = api.apiServer
+	newAPI.init()
 	newAPI.Hosts.init()
 	return newAPI
 }
@@ -184,17 +401,48 @@ func (api *apiStruct) hostCheckDelete(hostNames []string) {
 
 }
 
-func (api *apiStruct) getMasterHostName(cpf *CallerPrefConf) string {
+// getMasterHostName select the master host from allowNames(nil for all hosts).
+// hosts with match_header are candidates only when the header matches,
+// and the matched ones take precedence over the others
+func (api *apiStruct) getMasterHostName(req *http.Request, cpf *CallerPrefConf, allowNames []string) string {
 	api.rw.RLock()
 	defer api.rw.RUnlock()
 
-	var names []string
+	caller := api.Caller.getCallerItemByIP(cpf.GetIP())
+	var names, matchNames []string
 	for name, host := range api.Hosts {
-		if host.Enable {
+		if !host.Enable || !host.acceptMethod(req.Method) || (allowNames != nil && !InStringSlice(name, allowNames)) {
+			continue
+		}
+		//same as the hosts called,so the master is one of them
+		if !caller.hostAllowed(name) || caller.isHostIgnore(name, cpf) {
+			continue
+		}
+		if host.MatchHeader == nil {
 			names = append(names, name)
+		} else if host.MatchHeader.match(req) {
+			matchNames = append(matchNames, name)
 		}
 	}
-	return api.Caller.getPrefHostName(names, cpf)
+	if len(matchNames) > 0 {
+		names = matchNames
+	}
+	defaultName := api.DefaultMaster
+	if defaultName == "" || !InStringSlice(defaultName, names) {
+		defaultName = api.Hosts.priorityHostName(names)
+	}
+	if key := stickyKeyOf(req); key != "" {
+		defaultName = stickyHostName(key, api.stickyHealthyNames(names))
+	}
+	return api.Caller.getPrefHostName(names, cpf, defaultName)
+}
+
+// remapStatus get the status code send to client
+func (api *apiStruct) remapStatus(code int) int {
+	if to, has := api.StatusRemap[code]; has {
+		return to
+	}
+	return code
 }
 
 func (api *apiStruct) cookieName() string {
@@ -208,7 +456,7 @@ func loadAPIByConf(apiServer *APIServer, apiID string) (*apiStruct, error) {
 
 	log.Println(logMsg, "start")
 
-	data, err := ioutil.ReadFile(api.ConfPath)
+	data, err := apiServer.confSource.Get(apiID)
 	if err != nil {
 		log.Println(logMsg, "failed,", err)
 		return api, err
@@ -218,6 +466,16 @@ func loadAPIByConf(apiServer *APIServer, apiID string) (*apiStruct, error) {
 		log.Println(logMsg, "failed,", err)
 		return api, err
 	}
+	if name := duplicateHostName(data); name != "" {
+		err = fmt.Errorf("duplicate host name:%s", name)
+		log.Println(logMsg, "failed,", err)
+		return api, err
+	}
+	migrated, err := api.migrate()
+	if err != nil {
+		log.Println(logMsg, "failed,", err)
+		return api, err
+	}
 	api.Hosts.init()
 	log.Println(logMsg, "success")
 	if api.Path == "" {
@@ -229,6 +487,11 @@ func loadAPIByConf(apiServer *APIServer, apiID string) (*apiStruct, error) {
 
 	err = api.init()
 	api.Exists = true
+	if err == nil && migrated && apiServer.ServerVhostConf.RewriteMigrated {
+		if e := api.save(); e != nil {
+			log.Println(logMsg, "rewrite migrated conf failed,", e)
+		}
+	}
 	return api, err
 }
 
@@ -255,12 +518,16 @@ func apiCookieName(apiID string) string {
 func (api *apiStruct) getAPIHostsByReq(req *http.Request) (hs []*Host, master string, cpf *CallerPrefConf) {
 	cpf = newCallerPrefConfByHTTPRequest(req, api)
 	caller := api.Caller.getCallerItemByIP(cpf.GetIP())
-	masterHost := api.getMasterHostName(cpf)
+	subHosts := api.subRouteHosts(req.URL.Path)
+	masterHost := api.getMasterHostName(req, cpf, subHosts)
 
 	hs = make([]*Host, 0)
 	var hsTmp []*Host
 	for _, apiHost := range api.Hosts {
-		if !apiHost.Enable || caller.isHostIgnore(apiHost.Name, cpf) {
+		if !apiHost.Enable || !apiHost.acceptMethod(req.Method) || !caller.hostAllowed(apiHost.Name) || caller.isHostIgnore(apiHost.Name, cpf) {
+			continue
+		}
+		if subHosts != nil && !InStringSlice(apiHost.Name, subHosts) {
 			continue
 		}
 		if apiHost.Name == masterHost {
@@ -269,6 +536,10 @@ func (api *apiStruct) getAPIHostsByReq(req *http.Request) (hs []*Host, master st
 			hsTmp = append(hsTmp, apiHost)
 		}
 	}
+	if api.MaxShadowHosts > 0 && len(hsTmp) > api.MaxShadowHosts {
+		sortHostsByHealth(hsTmp)
+		hsTmp = hsTmp[:api.MaxShadowHosts]
+	}
 	hs = append(hs, hsTmp...)
 	return hs, masterHost, cpf
 }
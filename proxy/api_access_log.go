@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// modes of the dedicated access log of api
+const (
+	accessLogBoth = "both" //write to the api's file and the main log
+	accessLogOnly = "only" //write to the api's file only
+)
+
+const (
+	accessLogDefaultMaxSize = 100 << 20
+	accessLogBackups        = 3
+)
+
+// rotateFile file which is renamed to path.1,path.2... when larger than maxSize
+type rotateFile struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+}
+
+// accessLogFiles opened files,shared by the reloaded apis
+var accessLogFiles = struct {
+	sync.Mutex
+	m map[string]*rotateFile
+}{m: make(map[string]*rotateFile)}
+
+func getRotateFile(path string, maxSize int64) *rotateFile {
+	accessLogFiles.Lock()
+	defer accessLogFiles.Unlock()
+	rf, has := accessLogFiles.m[path]
+	if !has {
+		rf = &rotateFile{path: path}
+		accessLogFiles.m[path] = rf
+	}
+	rf.mu.Lock()
+	rf.maxSize = maxSize
+	rf.mu.Unlock()
+	return rf
+}
+
+func (rf *rotateFile) open() error {
+	DirCheck(rf.path)
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotateFile) rotate() error {
+	rf.file.Close()
+	rf.file = nil
+	for i := accessLogBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+	return rf.open()
+}
+
+func (rf *rotateFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (api *apiStruct) initAccessLog() error {
+	if err := checkLogFormat(api.LogFormat); err != nil {
+		return err
+	}
+	switch api.AccessLog {
+	case "":
+		api.accessLog = nil
+		return nil
+	case accessLogBoth, accessLogOnly:
+	default:
+		return fmt.Errorf("access_log wrong:%s", api.AccessLog)
+	}
+	if api.AccessLogMaxSize < 1 {
+		api.AccessLogMaxSize = accessLogDefaultMaxSize
+	}
+	rf := getRotateFile(api.accessLogPath(), api.AccessLogMaxSize)
+	flags := log.LstdFlags
+	if api.LogFormat != "" {
+		//the time is in the line
+		flags = 0
+	}
+	api.accessLog = log.New(rf, "", flags)
+	return nil
+}
+
+// accessLogPath confDir/logs/{apiID}.log
+func (api *apiStruct) accessLogPath() string {
+	return filepath.Join(api.apiServer.getConfDir(), "logs", api.ID+".log")
+}
+
+// printAccessLog write the access log line to the api's file and/or the main log
+func (api *apiStruct) printAccessLog(line string) {
+	if api.accessLog != nil {
+		if err := api.accessLog.Output(2, line); err != nil {
+			log.Println("[error]write access log failed:", api.ID, err)
+		}
+	}
+	if api.AccessLog == accessLogOnly {
+		return
+	}
+	if api.LogFormat != "" {
+		//the line is written as it is,without the time and file prefix of the main log
+		log.New(log.Writer(), "", 0).Output(2, line)
+		return
+	}
+	log.Output(2, line)
+}
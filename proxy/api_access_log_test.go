@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_APIAccessLog(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	api := testLoadAPI(t, apiServer, "al", `{"path":"/al/","enable":true,"access_log":"only",
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "other", `{"path":"/other/","enable":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	testGet(t, ts.URL+"/al/a?k=1")
+	testGet(t, ts.URL+"/other/b")
+
+	logPath := filepath.Join(apiServer.getConfDir(), "logs", "al.log")
+	if logPath != api.accessLogPath() {
+		t.Fatal("wrong log path:", api.accessLogPath())
+	}
+	//the log is written after the response is sent
+	var data []byte
+	for i := 0; i < 100 && len(data) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ = ioutil.ReadFile(logPath)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatal("expect 1 line,got:", string(data))
+	}
+	if !strings.Contains(lines[0], "[access]") || !strings.Contains(lines[0], "uri=/al/a?k=1") {
+		t.Error("wrong log line:", lines[0])
+	}
+	if _, err := os.Stat(filepath.Join(apiServer.getConfDir(), "logs", "other.log")); !os.IsNotExist(err) {
+		t.Error("api without access_log should not have log file")
+	}
+}
+
+func Test_APIAccessLogWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("wrong")
+	api.AccessLog = "none"
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong access_log")
+	}
+}
+
+func Test_RotateFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "a.log")
+	rf := getRotateFile(logPath, 10)
+	for _, line := range []string{"123456\n", "abcdef\n", "ABCDEF\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rf.file.Close()
+
+	expect := map[string]string{
+		logPath:        "ABCDEF\n",
+		logPath + ".1": "abcdef\n",
+		logPath + ".2": "123456\n",
+	}
+	for name, content := range expect {
+		data, _ := ioutil.ReadFile(name)
+		if string(data) != content {
+			t.Errorf("%s wrong content:%q", name, data)
+		}
+	}
+	if rf != getRotateFile(logPath, 10) {
+		t.Error("the file should be shared")
+	}
+}
+
+func Test_APIAccessLogClone(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "alc", `{"path":"/alc/","enable":true,"access_log":"both"}`)
+	if c := api.clone(); c.accessLog == nil {
+		t.Error("the clone should have access log")
+	}
+}
+
+func Test_APIAccessLogCombined(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "hello")
+	shadow := testBackend(t, "shadow")
+	api := testLoadAPI(t, apiServer, "clf", `{"path":"/clf/","enable":true,"access_log":"only","log_format":"combined","default_master":"h1",
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true},"h2":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/clf/a?k=1", nil)
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", `test "ua"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var data []byte
+	for i := 0; i < 100 && len(data) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ = ioutil.ReadFile(api.accessLogPath())
+	}
+	//wait for the shadow host,it should not add a line
+	time.Sleep(100 * time.Millisecond)
+	data, _ = ioutil.ReadFile(api.accessLogPath())
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatal("expect 1 line,got:", string(data))
+	}
+	reg := regexp.MustCompile(`^127\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /clf/a\?k=1 HTTP/1\.1" 200 5 "http://example\.com/" "test \\"ua\\""$`)
+	if !reg.MatchString(lines[0]) {
+		t.Error("wrong combined log line:", lines[0])
+	}
+}
+
+func Test_APIAccessLogFormatMainLog(t *testing.T) {
+	var buf bytes.Buffer
+	flags, out := log.Flags(), log.Writer()
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile | log.LstdFlags)
+	defer func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}()
+
+	api := newTestAPIServer(t).newAPI("clf_main")
+	api.LogFormat = logFormatCommon
+	line := `127.0.0.1 - - [16/Oct/2026:10:00:00 +0800] "GET /a HTTP/1.1" 200 5`
+	api.printAccessLog(line)
+	if !InStringSlice(line, strings.Split(buf.String(), "\n")) {
+		t.Errorf("expect the line without prefix in the main log,got:%q", buf.String())
+	}
+}
+
+func Test_APILogFormatWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("wrong")
+	api.LogFormat = "json"
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong log_format")
+	}
+}
@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// AckConf the fixed response of the fire-and-forget apis,eg the callbacks
+// which only need an ack. the client gets it at once,
+// and all the hosts(the master too) are called in background
+type AckConf struct {
+	Status      int    `json:"status"`       //默认200
+	Body        string `json:"body"`         //
+	ContentType string `json:"content_type"` //默认application/json
+}
+
+func (ack *AckConf) init() error {
+	if ack.Status == 0 {
+		ack.Status = http.StatusOK
+	}
+	if !isValidStatusCode(ack.Status) {
+		return fmt.Errorf("status wrong:%d", ack.Status)
+	}
+	if ack.ContentType == "" {
+		ack.ContentType = "application/json; charset=utf-8"
+	}
+	return nil
+}
+
+func (ack *AckConf) write(rw http.ResponseWriter) {
+	rw.Header().Set("Content-Type", ack.ContentType)
+	rw.Header().Set("Content-Length", fmt.Sprintf("%d", len(ack.Body)))
+	rw.WriteHeader(ack.Status)
+	rw.Write([]byte(ack.Body))
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HandlerAck(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	received := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bd, _ := ioutil.ReadAll(req.Body)
+		time.Sleep(500 * time.Millisecond)
+		received <- string(bd)
+		rw.Write([]byte("backend body"))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "ack", `{"path":"/ack/","enable":true,
+		"ack":{"status":202,"body":"{\"message\":{\"ack\":{\"status\":\"ACK\"}}}"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	start := time.Now()
+	resp, err := http.Post(ts.URL+"/ack/on_search", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bd, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if used := time.Since(start); used > 300*time.Millisecond {
+		t.Error("expect the ack at once,used:", used)
+	}
+	if resp.StatusCode != 202 || string(bd) != `{"message":{"ack":{"status":"ACK"}}}` || resp.Header.Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Error("wrong ack:", resp.StatusCode, string(bd), resp.Header)
+	}
+	select {
+	case body := <-received:
+		if body != `{"a":1}` {
+			t.Error("wrong body received by backend:", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("backend not called")
+	}
+}
+
+func Test_APIAckWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("ack_wrong")
+	api.Ack = &AckConf{Status: 1000}
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong ack status")
+	}
+}
+
+func Test_HandlerAckHoldSlot(t *testing.T) {
+	backend, entered, release := testBlockBackend(t)
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "ack_slot", `{"path":"/ack_slot/","enable":true,"max_concurrent":1,"queue_size":5,"queue_timeout_ms":100,
+		"ack":{"status":202},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	if resp, _ := testGet(t, ts.URL+"/ack_slot/a"); resp.StatusCode != 202 {
+		t.Fatal("expect the ack,got:", resp.StatusCode)
+	}
+	<-entered
+	//the master is still in flight,so the slot is not released
+	if resp, _ := testGet(t, ts.URL+"/ack_slot/a"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expect 503 when the master holds the slot,got:", resp.StatusCode)
+	}
+	close(release)
+	testWaitFanout(t)
+	if resp, _ := testGet(t, ts.URL+"/ack_slot/a"); resp.StatusCode != 202 {
+		t.Error("expect the ack after the master finished,got:", resp.StatusCode)
+	}
+}
+
+func Test_HandlerAckMasterFail(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"message":{"ack":{"status":"NACK"}}}`))
+	}))
+	defer backend.Close()
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "ack_fail", `{"path":"/ack_fail/","enable":true,"all_fail_threshold":2,"all_fail_cooldown_ms":60000,
+		"ack":{"status":202},
+		"success":{"status":["2xx"],"json_path":"message.ack.status","json_value":"ACK"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	errsBefore := testExpvarValue(expvarErrors, "test/ack_fail")
+	for i := 0; i < 2; i++ {
+		if resp, _ := testGet(t, ts.URL+"/ack_fail/a"); resp.StatusCode != 202 {
+			t.Fatal("expect the ack,got:", resp.StatusCode)
+		}
+		testWaitFanout(t)
+	}
+	if n := testExpvarValue(expvarErrors, "test/ack_fail") - errsBefore; n != 2 {
+		t.Error("expect 2 errors,got:", n)
+	}
+	if resp, _ := testGet(t, ts.URL+"/ack_fail/a"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expect 503 in cooldown,got:", resp.StatusCode)
+	}
+}
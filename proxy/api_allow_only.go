@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// initAllowOnly parse the ips and cidrs of AllowOnly
+func (api *apiStruct) initAllowOnly() (err error) {
+	api.allowNets, err = parseIPNets(api.AllowOnly)
+	if err != nil {
+		return fmt.Errorf("allow_only wrong:%s", err)
+	}
+	return nil
+}
+
+// parseIPNets parse the ips and cidrs,an ip is a cidr of itself only
+func parseIPNets(items []string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("ip wrong:%s", item)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			item = fmt.Sprintf("%s/%d", item, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// ipInNets the ip is in one of the nets
+func ipInNets(ipStr string, ipNets []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipAllowed all ips are allowed when AllowOnly is empty
+func (api *apiStruct) ipAllowed(ipStr string) bool {
+	if len(api.AllowOnly) == 0 {
+		return true
+	}
+	return ipInNets(ipStr, api.allowNets)
+}
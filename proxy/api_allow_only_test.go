@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HandlerAllowOnly(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "ao", `{"path":"/ao/","enable":true,"allow_only":["10.0.0.1","192.168.0.0/16"],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	cases := []struct {
+		ip   string
+		code int
+	}{
+		{"10.0.0.1", 200},
+		{"10.0.0.2", 403},
+		{"192.168.3.4", 200},
+		{"192.169.0.1", 403},
+		{"", 403}, //127.0.0.1
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", ts.URL+"/ao/", nil)
+		if c.ip != "" {
+			req.Header.Set("X-Real-Ip", c.ip)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Error("ip:", c.ip, "expect:", c.code, "got:", resp.StatusCode)
+		}
+	}
+}
+
+func Test_HandlerAllowOnlyForgedHeader(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.trustedProxies = nil
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "ao", `{"path":"/ao/","enable":true,"allow_only":["10.0.0.1","::1"],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	//the client is not a trusted proxy,X-Real-Ip is not used
+	req, _ := http.NewRequest("GET", ts.URL+"/ao/", nil)
+	req.Header.Set("X-Real-Ip", "10.0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error("forged X-Real-Ip should be rejected,got:", resp.StatusCode)
+	}
+
+	//an ipv6 peer
+	req = httptest.NewRequest("GET", "/ao/", nil)
+	req.RemoteAddr = "[::1]:5555"
+	rw := httptest.NewRecorder()
+	apiServer.ServeHTTP(rw, req)
+	if rw.Code != 200 {
+		t.Error("ipv6 peer in allow_only expect 200,got:", rw.Code)
+	}
+}
+
+func Test_APIAllowOnlyWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	for _, item := range []string{"10.0.0", "10.0.0.0/33", "10.0.0.*"} {
+		api := apiServer.newAPI("wrong")
+		api.AllowOnly = []string{item}
+		if err := api.init(); err == nil {
+			t.Error("expect error for:", item)
+		}
+	}
+	api := apiServer.newAPI("v6")
+	api.AllowOnly = []string{"::1", "fd00::/8"}
+	if err := api.init(); err != nil {
+		t.Fatal(err)
+	}
+	if !api.ipAllowed("::1") || !api.ipAllowed("fd00::2") || api.ipAllowed("fe80::1") {
+		t.Error("ipv6 match wrong")
+	}
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// apiBackoff shed the requests for a cooldown period
+// after all hosts failed for N consecutive requests
+type apiBackoff struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	fails     int
+	until     time.Time
+}
+
+func (api *apiStruct) initBackoff() {
+	if api.AllFailThreshold < 1 {
+		api.backoff = nil
+		return
+	}
+	if api.AllFailCooldownMs < 1 {
+		api.AllFailCooldownMs = 10000
+	}
+	api.backoff = &apiBackoff{
+		threshold: api.AllFailThreshold,
+		cooldown:  time.Duration(api.AllFailCooldownMs) * time.Millisecond,
+	}
+}
+
+// inCooldown the request should be rejected without calling hosts
+func (api *apiStruct) inCooldown() bool {
+	b := api.backoff
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.until.IsZero() {
+		return false
+	}
+	if time.Now().Before(b.until) {
+		return true
+	}
+	b.until = time.Time{}
+	log.Println("[info]api", api.ID, "leave cooldown")
+	return false
+}
+
+// resetBackoff leave the cooldown and clear the failures
+func (api *apiStruct) resetBackoff() {
+	b := api.backoff
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fails = 0
+	b.until = time.Time{}
+}
+
+// reportAllFail report the result of one request,allFail when none of the called hosts succeeded.
+// the shadows are not called when the master fails to respond,only the master counts then
+func (api *apiStruct) reportAllFail(allFail bool) {
+	b := api.backoff
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !allFail {
+		b.fails = 0
+		return
+	}
+	b.fails++
+	if b.fails >= b.threshold && b.until.IsZero() {
+		b.fails = 0
+		b.until = time.Now().Add(b.cooldown)
+		log.Println("[warning]api", api.ID, "all hosts failed", b.threshold, "times,enter cooldown for", b.cooldown)
+	}
+}
+
+// hostResults whether any host of one request succeeded,
+// the shadows are called async,so it is reported after all of them are done
+type hostResults struct {
+	mu      sync.Mutex
+	success bool
+}
+
+func (r *hostResults) add(success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.success = r.success || success
+}
+
+func (r *hostResults) allFail() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.success
+}
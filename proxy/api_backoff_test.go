@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_HandlerAllFailCooldown(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "bo", `{"path":"/bo/","enable":true,"all_fail_threshold":2,"all_fail_cooldown_ms":200,"hosts":{
+		"h1":{"url":"`+backend.URL+`/","enable":true},
+		"h2":{"url":"http://127.0.0.1:1/","enable":true}
+	}}`)
+	ts := testServe(t, apiServer)
+
+	for i := 0; i < 2; i++ {
+		if resp, _ := testGet(t, ts.URL+"/bo/a"); resp.StatusCode == http.StatusServiceUnavailable {
+			t.Fatal("should call backend before threshold,request:", i)
+		}
+		testWaitFanout(t)
+	}
+
+	hitsBefore := atomic.LoadInt32(&hits)
+	for i := 0; i < 3; i++ {
+		if resp, _ := testGet(t, ts.URL+"/bo/a"); resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatal("expect 503 in cooldown,got:", resp.StatusCode)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != hitsBefore {
+		t.Error("backend should not be called in cooldown,hits:", n-hitsBefore)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if resp, _ := testGet(t, ts.URL+"/bo/a"); resp.StatusCode == http.StatusServiceUnavailable {
+		t.Error("should call backend after cooldown")
+	}
+}
+
+// testWaitFanout wait for the async calls to the other hosts and the reports after them
+func testWaitFanout(t *testing.T) {
+	for i := 0; i < 100 && atomic.LoadInt64(&fanoutActive) > 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func Test_HandlerAllFailCooldownShadowOK(t *testing.T) {
+	master := testBackendStatus(t, 500)
+	shadow := testBackend(t, "ok")
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "bo", `{"path":"/bo/","enable":true,"all_fail_threshold":2,"default_master":"h1","hosts":{
+		"h1":{"url":"`+master.URL+`/","enable":true},
+		"h2":{"url":"`+shadow.URL+`/","enable":true}
+	}}`)
+	ts := testServe(t, apiServer)
+
+	//the master fails,but the shadow is ok,so not all hosts failed
+	for i := 0; i < 4; i++ {
+		if resp, _ := testGet(t, ts.URL+"/bo/a"); resp.StatusCode != http.StatusInternalServerError {
+			t.Fatal("expect the master's 500,got:", resp.StatusCode, "request:", i)
+		}
+		testWaitFanout(t)
+	}
+	if api.inCooldown() {
+		t.Error("should not enter cooldown when a shadow is ok")
+	}
+}
+
+func Test_APIBackoffReset(t *testing.T) {
+	api := &apiStruct{ID: "bo", AllFailThreshold: 2}
+	api.initBackoff()
+	api.reportAllFail(true)
+	api.reportAllFail(false)
+	api.reportAllFail(true)
+	if api.inCooldown() {
+		t.Error("success should reset the consecutive fails")
+	}
+	api.reportAllFail(true)
+	if !api.inCooldown() {
+		t.Error("expect cooldown")
+	}
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+func (api *apiStruct) initBodyLimit() {
+	if len(api.BodyLimit) == 0 {
+		return
+	}
+	limits := make(map[string]int64, len(api.BodyLimit))
+	for method, n := range api.BodyLimit {
+		limits[strings.ToUpper(strings.TrimSpace(method))] = n
+	}
+	api.BodyLimit = limits
+}
+
+// bodyLimit max request body bytes of the method,0 means unlimited
+func (api *apiStruct) bodyLimit(method string) int64 {
+	if n, has := api.BodyLimit[method]; has {
+		return n
+	}
+	return api.BodyLimit["*"]
+}
+
+// readRequestBody read all request body,
+// return errBodyTooLarge when it is larger than limit
+func readRequestBody(req *http.Request, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(req.Body)
+	}
+	if req.ContentLength > limit {
+		return nil, errBodyTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err == nil && int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return body, err
+}
+
+// defaultMaxURLLength the urls longer than it are rejected with 414,
+// most servers limit the request line to 8K too
+const defaultMaxURLLength = 8192
+
+// urlTooLong the length of path and query is larger than max_url_length
+func (api *apiStruct) urlTooLong(req *http.Request) bool {
+	return len(req.URL.RequestURI()) > api.MaxURLLength
+}
+
+// masterOnlyHosts the large requests(eg bulk uploads) are not mirrored,
+// only the master and the host whose response is sent are kept
+func (api *apiStruct) masterOnlyHosts(hosts []*Host, body []byte, names ...string) ([]*Host, bool) {
+	if api.ShadowMaxBody <= 0 || int64(len(body)) <= api.ShadowMaxBody {
+		return hosts, false
+	}
+	var hs []*Host
+	for _, apiHost := range hosts {
+		if InStringSlice(apiHost.Name, names) {
+			hs = append(hs, apiHost)
+		}
+	}
+	return hs, len(hs) < len(hosts)
+}
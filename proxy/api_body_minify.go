@@ -0,0 +1,34 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// isJSONContentType application/json or application/xxx+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// minifyBody remove the spaces of the json body,
+// the body is not changed when it is not json or is invalid
+func (api *apiStruct) minifyBody(req *http.Request, body []byte) []byte {
+	if !api.MinifyJSON || len(body) == 0 || req.Header.Get("Content-Encoding") != "" {
+		return body
+	}
+	if !isJSONContentType(req.Header.Get("Content-Type")) {
+		return body
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
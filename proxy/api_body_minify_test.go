@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_HandlerMinifyJSON(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bd, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(rw, "%d:%s", req.ContentLength, bd)
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "mj", `{"path":"/mj/","enable":true,"minify_json":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	post := func(contentType string, body string) string {
+		resp, err := http.Post(ts.URL+"/mj/", contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return string(bd)
+	}
+
+	pretty := "{\n  \"a\": 1,\n  \"b\": [ 1, 2 ]\n}\n"
+	cases := []struct {
+		contentType string
+		body        string
+		expect      string
+	}{
+		{"application/json", pretty, `17:{"a":1,"b":[1,2]}`},
+		{"application/vnd.api+json; charset=utf-8", pretty, `17:{"a":1,"b":[1,2]}`},
+		{"application/json", "{\n  \"a\": ", fmt.Sprintf("%d:{\n  \"a\": ", len("{\n  \"a\": "))},
+		{"text/plain", pretty, fmt.Sprintf("%d:%s", len(pretty), pretty)},
+	}
+	for _, c := range cases {
+		if got := post(c.contentType, c.body); got != c.expect {
+			t.Errorf("%s:expect %q,got %q", c.contentType, c.expect, got)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package proxy
 
 import (
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 //var API_PREF string = "api_pref"
@@ -29,6 +33,16 @@ type CallerItem struct {
 	Enable bool           `json:"enable"`
 	Pref   []string       `json:"pref"`
 	Ignore []string       `json:"ignore"`
+
+	RespHeaders map[string]string `json:"resp_headers,omitempty"` //该调用方的response添加的header
+
+	Trusted bool `json:"trusted,omitempty"` //可信的调用方,master失败时返回所有后端的结果(json),便于排查问题;ip来自X-Real-Ip时只信任trusted_proxies转发的
+
+	TimeoutMs int `json:"timeout_ms,omitempty"` //该调用方的超时时间,优先于后端和接口的超时
+
+	Only []string `json:"only,omitempty"` //该调用方只能访问的host(包括master和其他host),请求的pref也不能越过,为空不限制
+
+	ipNet *net.IPNet //ip为CIDR时,如 10.0.0.0/8
 }
 
 func newCaller() Caller {
@@ -54,16 +68,100 @@ func newCallerItemMust(ip string) *CallerItem {
 }
 
 func (citem *CallerItem) init() (err error) {
-	citem.IPReg, err = regexp.Compile(strings.Replace(strings.Replace(citem.IP, ".", `\.`, -1), "*", `\d+`, -1))
+	citem.ipNet, err = citem.parseCIDR()
+	if err != nil {
+		return err
+	}
+	citem.IPReg, err = regexp.Compile("^" + strings.Replace(strings.Replace(citem.IP, ".", `\.`, -1), "*", `\d+`, -1) + "$")
 	if err != nil {
 		log.Println("ip wrong:", citem.IP)
 	}
 	if citem.Ignore == nil {
 		citem.Ignore = make([]string, 0)
 	}
+	if len(citem.RespHeaders) > 0 {
+		hs := make(map[string]string, len(citem.RespHeaders))
+		for k, v := range citem.RespHeaders {
+			hs[http.CanonicalHeaderKey(k)] = v
+		}
+		citem.RespHeaders = hs
+	}
 	return err
 }
 
+// isCIDR ip like 10.0.0.0/8
+func (citem *CallerItem) isCIDR() bool {
+	return strings.Contains(citem.IP, "/")
+}
+
+// parseCIDR the network of the ip,nil when it is not a CIDR
+func (citem *CallerItem) parseCIDR() (*net.IPNet, error) {
+	if !citem.isCIDR() {
+		return nil, nil
+	}
+	_, ipNet, err := net.ParseCIDR(citem.IP)
+	if err != nil {
+		return nil, fmt.Errorf("caller ip wrong,invalid CIDR:%s", citem.IP)
+	}
+	return ipNet, nil
+}
+
+// match the ip equals,is in the CIDR,or matches the whole wildcard
+func (citem *CallerItem) match(ip string) bool {
+	if citem.IP == ip {
+		return true
+	}
+	switch citem.matchRank() {
+	case 1:
+		addr := net.ParseIP(ip)
+		return addr != nil && citem.ipNet.Contains(addr)
+	case 2:
+		return citem.IPReg != nil && citem.IPReg.MatchString(ip)
+	}
+	return false
+}
+
+// setRespHeaders set the headers of this caller to the response
+func (citem *CallerItem) setRespHeaders(h http.Header) {
+	for k, v := range citem.RespHeaders {
+		h.Set(k, v)
+	}
+}
+
+// requestTimeout the timeout_ms of the caller replaces the host's and the api's
+func (citem *CallerItem) requestTimeout(hostTimeout time.Duration) time.Duration {
+	if citem.TimeoutMs > 0 {
+		return time.Duration(citem.TimeoutMs) * time.Millisecond
+	}
+	return hostTimeout
+}
+
+// logInfo the matched caller rule and the prefs of the request,
+// for the access log to tell why the master is chosen
+func (citem *CallerItem) logInfo(cpf *CallerPrefConf) map[string]interface{} {
+	info := map[string]interface{}{
+		"rule":   citem.IP,
+		"note":   citem.Note,
+		"pref":   citem.Pref,
+		"ignore": citem.Ignore,
+	}
+	if cpf != nil && len(cpf.prefHostName) > 0 {
+		info["req_pref"] = cpf.prefHostName
+	}
+	if citem.TimeoutMs > 0 {
+		info["timeout_ms"] = citem.TimeoutMs
+	}
+	if len(citem.Only) > 0 {
+		info["only"] = citem.Only
+	}
+	return info
+}
+
+// hostAllowed the caller reaches the hosts in only,all when only is empty
+func (citem *CallerItem) hostAllowed(hostName string) bool {
+	return len(citem.Only) == 0 || InStringSlice(hostName, citem.Only)
+}
+
 func (citem *CallerItem) isHostIgnore(hostHame string, cpf *CallerPrefConf) bool {
 	isIgnore := InStringSlice(hostHame, citem.Ignore)
 	if isIgnore && cpf != nil {
@@ -96,13 +194,15 @@ func (caller *Caller) init() (err error) {
 		citem.init()
 		caller.addNewCallerItem(citem)
 	}
+	//the exact ips are matched before the CIDRs and the wildcards
+	caller.Sort()
 	return nil
 }
 
-func (caller *Caller) getPrefHostName(allowNames []string, cpf *CallerPrefConf) string {
+func (caller *Caller) getPrefHostName(allowNames []string, cpf *CallerPrefConf, defaultName string) string {
 
 	if len(allowNames) == 0 || len(*caller) == 0 {
-		return StrSliceRandItem(allowNames)
+		return pickHostName(allowNames, defaultName)
 	}
 
 	for _, prefType := range prefTypes {
@@ -120,31 +220,54 @@ func (caller *Caller) getPrefHostName(allowNames []string, cpf *CallerPrefConf)
 			return pref
 		}
 	}
+	return pickHostName(allowNames, defaultName)
+}
+
+// pickHostName use defaultName when it is allowed,otherwise a random one
+func pickHostName(allowNames []string, defaultName string) string {
+	if defaultName != "" && InStringSlice(defaultName, allowNames) {
+		return defaultName
+	}
 	return StrSliceRandItem(allowNames)
 }
 
 // Sort sort by host ip
 func (caller Caller) Sort() {
-	sort.Sort(caller)
+	sort.Stable(caller)
 }
 func (caller Caller) Len() int {
 	return len(caller)
 }
 
 /**
-*让 127.0.0.1 排在127.0.0.* 前面
+*让 127.0.0.1 排在 10.0.0.0/8、127.0.0.* 前面,
+*CIDR 排在 127.0.0.* 前面,掩码长的在前
  */
 func (caller Caller) Less(i, j int) bool {
-	aPos := strings.Index(caller[i].IP, "*")
-	if aPos == -1 {
-		return true
+	aRank, bRank := caller[i].matchRank(), caller[j].matchRank()
+	if aRank != bRank {
+		return aRank < bRank
 	}
-	bPos := strings.Index(caller[j].IP, "*")
-	if bPos == -1 {
-		return false
+	switch aRank {
+	case 1:
+		aOnes, _ := caller[i].ipNet.Mask.Size()
+		bOnes, _ := caller[j].ipNet.Mask.Size()
+		return aOnes > bOnes
+	case 2:
+		return strings.Index(caller[i].IP, "*") > strings.Index(caller[j].IP, "*")
 	}
+	return false
+}
 
-	return aPos > bPos
+// matchRank 0:exact ip,1:CIDR,2:wildcard
+func (citem *CallerItem) matchRank() int {
+	if citem.ipNet != nil {
+		return 1
+	}
+	if strings.Contains(citem.IP, "*") {
+		return 2
+	}
+	return 0
 }
 
 func (caller Caller) Swap(i, j int) {
@@ -162,7 +285,7 @@ func (caller Caller) getCallerItemByIP(ip string) *CallerItem {
 		if !item.Enable {
 			continue
 		}
-		if item.IP == ip || item.IPReg.MatchString(ip) {
+		if item.match(ip) {
 			return item
 		}
 	}
@@ -0,0 +1,15 @@
+package proxy
+
+import (
+	"context"
+)
+
+// withClientContext abort the request when the client is gone,
+// including reading the response body,so the backend connection is freed.
+// only for the master called sync,the other hosts outlive the client request
+func (ar *apiHostRequest) withClientContext(ctx context.Context) {
+	ar.req = ar.req.WithContext(ctx)
+	if ar.fallbackReq != nil {
+		ar.fallbackReq = ar.fallbackReq.WithContext(ctx)
+	}
+}
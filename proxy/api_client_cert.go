@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// ClientCertHeaders forward the verified client certificate to the backends,
+// only works when the server is serving TLS with client_ca_file
+type ClientCertHeaders struct {
+	Subject     string `json:"subject"`     //默认 X-Client-Cert-Subject
+	Fingerprint string `json:"fingerprint"` //sha256,默认 X-Client-Cert-Fingerprint
+}
+
+func (c *ClientCertHeaders) init() {
+	if c.Subject == "" {
+		c.Subject = "X-Client-Cert-Subject"
+	}
+	if c.Fingerprint == "" {
+		c.Fingerprint = "X-Client-Cert-Fingerprint"
+	}
+}
+
+// setClientCertHeaders remove the headers sent by the client,
+// then set them with the verified certificate
+func (api *apiStruct) setClientCertHeaders(req *http.Request) {
+	c := api.ClientCertHeaders
+	if c == nil {
+		return
+	}
+	req.Header.Del(c.Subject)
+	req.Header.Del(c.Fingerprint)
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	sum := sha256.Sum256(cert.Raw)
+	req.Header.Set(c.Subject, cert.Subject.String())
+	req.Header.Set(c.Fingerprint, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testClientCert a client certificate signed by a new CA
+func testClientCert(t *testing.T) (*x509.CertPool, tls.Certificate) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDer)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "partner", Organization: []string{"ondc"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func Test_HandlerClientCertHeaders(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.Header.Get("X-Client-Cert-Subject") + "|" + req.Header.Get("X-Client-Cert-Fingerprint")))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "cc", `{"path":"/cc/","enable":true,"client_cert_headers":{},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+
+	pool, cert := testClientCert(t)
+	ts := httptest.NewUnstartedServer(apiServer)
+	ts.TLS = &tls.Config{ClientCAs: pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(withCert bool) string {
+		transport := ts.Client().Transport.(*http.Transport).Clone()
+		if withCert {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		}
+		client := &http.Client{Transport: transport}
+		req, _ := http.NewRequest("GET", ts.URL+"/cc/", nil)
+		req.Header.Set("X-Client-Cert-Subject", "CN=spoofed")
+		req.Header.Set("X-Client-Cert-Fingerprint", "spoofed")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return string(bd)
+	}
+
+	sum := sha256.Sum256(cert.Certificate[0])
+	if body, want := get(true), "CN=partner,O=ondc|"+hex.EncodeToString(sum[:]); body != want {
+		t.Errorf("expect cert headers %q,got %q", want, body)
+	}
+	if body := get(false); body != "|" {
+		t.Error("the spoofed headers should be removed,got:", body)
+	}
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BodyComparator compare the response bodies,return nil when they are equal
+type BodyComparator interface {
+	Compare(expect, actual []byte) error
+}
+
+// comparator types
+const (
+	comparatorBytes        = "bytes"         //byte by byte,the default
+	comparatorJSON         = "json"          //same json value,ignore the key order and spaces
+	comparatorIgnoreFields = "ignore_fields" //same json value except the fields
+)
+
+// ComparatorConf conf of the comparator used by the api
+type ComparatorConf struct {
+	Type         string   `json:"type"`
+	IgnoreFields []string `json:"ignore_fields"` //字段路径,如 "ts","data.time",路径中的数组对每个元素生效
+
+	FullDiffMaxSize int `json:"full_diff_max_size"` //body超过该大小(字节)时只对比长度和sha256,不按type对比,0为不限制
+
+	NormalizeNumbers bool `json:"normalize_numbers"` //json的数字按值对比,如 1、1.0、1e0 相同,只对json和ignore_fields有效
+}
+
+// compare tiers,which is used is logged as compare_tier
+const (
+	compareTierFull = "full" //compared by the comparator of the type
+	compareTierHash = "hash" //large bodies,compared by length and sha256 only
+)
+
+func (c *ComparatorConf) comparator() (BodyComparator, error) {
+	switch c.Type {
+	case "", comparatorBytes:
+		if c.NormalizeNumbers {
+			return nil, fmt.Errorf("normalize_numbers is for json only")
+		}
+		return bytesComparator{}, nil
+	case comparatorJSON:
+		return jsonComparator{normalizeNumbers: c.NormalizeNumbers}, nil
+	case comparatorIgnoreFields:
+		if len(c.IgnoreFields) == 0 {
+			return nil, fmt.Errorf("ignore_fields empty")
+		}
+		cmp := ignoreFieldsComparator{normalizeNumbers: c.NormalizeNumbers}
+		for _, field := range c.IgnoreFields {
+			cmp.paths = append(cmp.paths, strings.Split(field, "."))
+		}
+		return cmp, nil
+	}
+	return nil, fmt.Errorf("unknow type:%s", c.Type)
+}
+
+func (api *apiStruct) initComparator() error {
+	api.bodyComparator = bytesComparator{}
+	if api.Comparator == nil {
+		return nil
+	}
+	cmp, err := api.Comparator.comparator()
+	if err != nil {
+		return fmt.Errorf("comparator wrong:%s", err)
+	}
+	if api.Comparator.FullDiffMaxSize > 0 {
+		cmp = tieredComparator{full: cmp, maxSize: api.Comparator.FullDiffMaxSize}
+	}
+	api.bodyComparator = cmp
+	return nil
+}
+
+// tieredComparator compare the small bodies with the comparator,
+// the large ones by length and sha256 only,to bound the cost
+type tieredComparator struct {
+	full    BodyComparator
+	maxSize int
+}
+
+func (c tieredComparator) tier(expect, actual []byte) string {
+	if len(expect) > c.maxSize || len(actual) > c.maxSize {
+		return compareTierHash
+	}
+	return compareTierFull
+}
+
+func (c tieredComparator) Compare(expect, actual []byte) error {
+	if c.tier(expect, actual) == compareTierFull {
+		return c.full.Compare(expect, actual)
+	}
+	if len(expect) != len(actual) {
+		return fmt.Errorf("body length mismatch,expect:%d actual:%d", len(expect), len(actual))
+	}
+	expectSum, actualSum := sha256.Sum256(expect), sha256.Sum256(actual)
+	if expectSum != actualSum {
+		return fmt.Errorf("body sha256 mismatch,expect:%x actual:%x", expectSum, actualSum)
+	}
+	return nil
+}
+
+// compareTier which tier the comparator uses for the bodies
+func compareTier(cmp BodyComparator, expect, actual []byte) string {
+	if c, ok := cmp.(tieredComparator); ok {
+		return c.tier(expect, actual)
+	}
+	return compareTierFull
+}
+
+type bytesComparator struct{}
+
+func (bytesComparator) Compare(expect, actual []byte) error {
+	if bytes.Equal(expect, actual) {
+		return nil
+	}
+	pos := 0
+	for pos < len(expect) && pos < len(actual) && expect[pos] == actual[pos] {
+		pos++
+	}
+	return fmt.Errorf("body mismatch at byte %d,expect:%q actual:%q", pos, bodySnippet(expect, pos), bodySnippet(actual, pos))
+}
+
+func bodySnippet(bd []byte, pos int) []byte {
+	end := pos + 32
+	if end > len(bd) {
+		end = len(bd)
+	}
+	return bd[pos:end]
+}
+
+type jsonComparator struct {
+	normalizeNumbers bool
+}
+
+func (c jsonComparator) Compare(expect, actual []byte) error {
+	return compareJSON(expect, actual, c.normalize)
+}
+
+func (c jsonComparator) normalize(v interface{}) interface{} {
+	if c.normalizeNumbers {
+		return jsonNormalizeNumbers(v)
+	}
+	return v
+}
+
+type ignoreFieldsComparator struct {
+	paths            [][]string
+	normalizeNumbers bool
+}
+
+func (c ignoreFieldsComparator) Compare(expect, actual []byte) error {
+	return compareJSON(expect, actual, c.normalize)
+}
+
+func (c ignoreFieldsComparator) normalize(v interface{}) interface{} {
+	v = jsonComparator{normalizeNumbers: c.normalizeNumbers}.normalize(v)
+	for _, p := range c.paths {
+		jsonDeletePath(v, p)
+	}
+	return v
+}
+
+// jsonNormalizer the json comparators,the decoded values are normalized before compared
+type jsonNormalizer interface {
+	normalize(v interface{}) interface{}
+}
+
+// compareJSON decode both and compare the normalized values,
+// the keys are in any order,compare as bytes when not json
+func compareJSON(expect, actual []byte, normalize func(v interface{}) interface{}) error {
+	expectVal, errExpect := decodeJSONValue(expect)
+	actualVal, errActual := decodeJSONValue(actual)
+	if errExpect != nil || errActual != nil {
+		return bytesComparator{}.Compare(expect, actual)
+	}
+	expectVal = normalize(expectVal)
+	actualVal = normalize(actualVal)
+	if reflect.DeepEqual(expectVal, actualVal) {
+		return nil
+	}
+	expectBs, _ := json.Marshal(expectVal)
+	actualBs, _ := json.Marshal(actualVal)
+	return bytesComparator{}.Compare(expectBs, actualBs)
+}
+
+func decodeJSONValue(bd []byte) (v interface{}, err error) {
+	dec := json.NewDecoder(bytes.NewReader(bd))
+	dec.UseNumber()
+	err = dec.Decode(&v)
+	return v, err
+}
+
+// jsonNormalizeNumbers replace the numbers with the same form of the value,
+// eg 1.0 and 1e0 are 1,the integers are exact,the others are as float64
+func jsonNormalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(val.String())
+		if !ok {
+			return v
+		}
+		if r.IsInt() {
+			return json.Number(r.RatString())
+		}
+		f, _ := r.Float64()
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = jsonNormalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = jsonNormalizeNumbers(item)
+		}
+	}
+	return v
+}
+
+func jsonDeletePath(v interface{}, p []string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(p) == 1 {
+			delete(val, p[0])
+			return
+		}
+		if sub, has := val[p[0]]; has {
+			jsonDeletePath(sub, p[1:])
+		}
+	case []interface{}:
+		for _, item := range val {
+			jsonDeletePath(item, p)
+		}
+	}
+}
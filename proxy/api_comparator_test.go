@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_BodyComparator(t *testing.T) {
+	cases := []struct {
+		conf   ComparatorConf
+		expect string
+		actual string
+		same   bool
+	}{
+		{ComparatorConf{}, `{"a":1,"b":2}`, `{"b":2,"a":1}`, false},
+		{ComparatorConf{Type: comparatorJSON}, `{"a":1,"b":2}`, `{"b":2, "a":1}`, true},
+		{ComparatorConf{Type: comparatorJSON}, `{"a":1.0}`, `{"a":1}`, false},
+		{ComparatorConf{Type: comparatorJSON}, `not json`, `not json`, true},
+		{ComparatorConf{Type: comparatorIgnoreFields, IgnoreFields: []string{"ts"}}, `{"a":1,"ts":100}`, `{"ts":200,"a":1}`, true},
+		{ComparatorConf{Type: comparatorIgnoreFields, IgnoreFields: []string{"ts"}}, `{"a":1,"ts":100}`, `{"ts":200,"a":2}`, false},
+		{ComparatorConf{Type: comparatorIgnoreFields, IgnoreFields: []string{"data.time"}},
+			`{"data":[{"id":1,"time":"a"},{"id":2,"time":"b"}]}`, `{"data":[{"id":1,"time":"c"},{"id":2}]}`, true},
+	}
+	for i, c := range cases {
+		cmp, err := c.conf.comparator()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		err = cmp.Compare([]byte(c.expect), []byte(c.actual))
+		if (err == nil) != c.same {
+			t.Error(i, "expect same:", c.same, "got:", err)
+		}
+	}
+
+	if _, err := (&ComparatorConf{Type: comparatorIgnoreFields}).comparator(); err == nil {
+		t.Error("expect error for empty ignore_fields")
+	}
+	if _, err := (&ComparatorConf{Type: "xml"}).comparator(); err == nil {
+		t.Error("expect error for wrong type")
+	}
+}
+
+func Test_BodyComparatorNormalizeNumbers(t *testing.T) {
+	jsonConf := ComparatorConf{Type: comparatorJSON, NormalizeNumbers: true}
+	ignoreConf := ComparatorConf{Type: comparatorIgnoreFields, IgnoreFields: []string{"ts"}, NormalizeNumbers: true}
+	cases := []struct {
+		conf   ComparatorConf
+		expect string
+		actual string
+		same   bool
+	}{
+		{jsonConf, `{"a":1.0,"b":[1e2,0.50]}`, `{"b":[100,0.5],"a":1}`, true},
+		{jsonConf, `{"x":{"b":-0.0,"a":12345678901234567890}}`, `{"x":{"a":12345678901234567890.00,"b":0}}`, true},
+		{jsonConf, `{"a":1.5}`, `{"a":1}`, false},
+		{jsonConf, `{"a":12345678901234567890}`, `{"a":12345678901234567891}`, false},
+		{jsonConf, `{"a":"1.0"}`, `{"a":"1"}`, false},
+		{ignoreConf, `{"a":2.00,"ts":1}`, `{"ts":2,"a":2}`, true},
+	}
+	for i, c := range cases {
+		cmp, err := c.conf.comparator()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		err = cmp.Compare([]byte(c.expect), []byte(c.actual))
+		if (err == nil) != c.same {
+			t.Error(i, "expect same:", c.same, "got:", err)
+		}
+	}
+	//the diff is readable
+	cmp, _ := jsonConf.comparator()
+	if err := cmp.Compare([]byte(`{"a":1.0}`), []byte(`{"a":2}`)); err == nil || !strings.Contains(err.Error(), `expect:"1}"`) {
+		t.Error("expect the normalized value in the diff,got:", err)
+	}
+	if _, err := (&ComparatorConf{NormalizeNumbers: true}).comparator(); err == nil {
+		t.Error("expect error for normalize_numbers with bytes")
+	}
+}
+
+func Test_HandlerGoldenIgnoreFields(t *testing.T) {
+	var ts int64
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, `{"data":"hello","ts":%d}`, atomic.AddInt64(&ts, 1))
+	}))
+	defer backend.Close()
+
+	apiServer := newTestAPIServer(t)
+	conf := func(mode string) string {
+		return `{"path":"/gi/","enable":true,"golden":"` + mode + `","comparator":{"type":"ignore_fields","ignore_fields":["ts"]},
+			"hosts":{"h1":{"url":"` + backend.URL + `/","enable":true}}}`
+	}
+	testLoadAPI(t, apiServer, "gi", conf(goldenRecord))
+	server := testServe(t, apiServer)
+	testGet(t, server.URL+"/gi/")
+
+	testLoadAPI(t, apiServer, "gi", conf(goldenCompare))
+	testGet(t, server.URL+"/gi/")
+	if n := testExpvarValue(expvarGoldenMismatch, "test/gi"); n != 0 {
+		t.Error("the volatile field should be ignored,mismatch:", n)
+	}
+}
+
+func Test_TieredComparator(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "tc", `{"path":"/tc/","enable":true,
+		"comparator":{"type":"json","full_diff_max_size":20},"hosts":{}}`)
+	cmp := api.bodyComparator
+
+	small, smallReordered := []byte(`{"a":1,"b":2}`), []byte(`{"b":2,"a":1}`)
+	if tier := compareTier(cmp, small, smallReordered); tier != compareTierFull {
+		t.Error("expect full diff for small bodies,got:", tier)
+	}
+	if err := cmp.Compare(small, smallReordered); err != nil {
+		t.Error("small bodies should be compared as json:", err)
+	}
+
+	large, largeReordered := []byte(`{"a":"0123456789","b":2}`), []byte(`{"b":2,"a":"0123456789"}`)
+	if tier := compareTier(cmp, large, largeReordered); tier != compareTierHash {
+		t.Error("expect hash only for large bodies,got:", tier)
+	}
+	if err := cmp.Compare(large, large); err != nil {
+		t.Error("expect same:", err)
+	}
+	if err := cmp.Compare(large, largeReordered); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Error("large bodies should be compared by hash,got:", err)
+	}
+	if err := cmp.Compare(large, small); err == nil || !strings.Contains(err.Error(), "length") {
+		t.Error("expect length mismatch,got:", err)
+	}
+
+	if tier := compareTier(bytesComparator{}, large, large); tier != compareTierFull {
+		t.Error("expect full without full_diff_max_size,got:", tier)
+	}
+}
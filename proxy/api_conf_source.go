@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConfigSource where the api confs are loaded from
+type ConfigSource interface {
+	// List all api ids
+	List() ([]string, error)
+
+	// Get the raw json conf of the api,os.ErrNotExist when not exists
+	Get(apiID string) ([]byte, error)
+
+	// Watch call onChange with the api id when its conf changed or deleted
+	Watch(onChange func(apiID string)) error
+}
+
+// errConfReadOnly the admin can not change the confs which are not from files
+var errConfReadOnly = errors.New("api conf is read only,it is not from files,change it in the config source")
+
+// confWritable the admin writes the conf files,which are read by the file source only
+func (apiServer *APIServer) confWritable() bool {
+	_, ok := apiServer.confSource.(*fileConfigSource)
+	return ok
+}
+
+// fileConfigSource confs in dir,one file for each api: dir/{apiID}.json
+type fileConfigSource struct {
+	dir string
+}
+
+func newFileConfigSource(dir string) *fileConfigSource {
+	return &fileConfigSource{dir: dir}
+}
+
+func (fs *fileConfigSource) List() ([]string, error) {
+	fileNames, err := filepath.Glob(filepath.Join(fs.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, fileName := range fileNames {
+		log.Println("start load conf file:", fileName)
+		_, baseName := filepath.Split(fileName)
+
+		apiID := baseName[:len(baseName)-5]
+		if apiID == "" || strings.HasPrefix(apiID, "_") {
+			log.Println("skip api conf:", fileName)
+			continue
+		}
+		ids = append(ids, apiID)
+	}
+	return ids, nil
+}
+
+func (fs *fileConfigSource) Get(apiID string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fs.dir, apiID+".json"))
+}
+
+// Watch the files are changed by the admin,which reloads the api itself
+func (fs *fileConfigSource) Watch(onChange func(apiID string)) error {
+	return nil
+}
+
+// KVStore kv storage like etcd or consul,an adapter of its client is needed
+type KVStore interface {
+	Keys(prefix string) ([]string, error)
+
+	// Get the value,os.ErrNotExist when not exists
+	Get(key string) ([]byte, error)
+
+	Watch(prefix string, onChange func(key string)) error
+}
+
+// KVConfigSource confs in kv store,one key for each api: {Prefix}{apiID}
+type KVConfigSource struct {
+	Store  KVStore
+	Prefix string
+}
+
+// NewKVConfigSource new kv config source
+func NewKVConfigSource(store KVStore, prefix string) *KVConfigSource {
+	return &KVConfigSource{Store: store, Prefix: prefix}
+}
+
+// List all api ids
+func (ks *KVConfigSource) List() ([]string, error) {
+	keys, err := ks.Store.Keys(ks.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, key := range keys {
+		apiID := strings.TrimPrefix(key, ks.Prefix)
+		if apiIDReg.MatchString(apiID) && !strings.HasPrefix(apiID, "_") {
+			ids = append(ids, apiID)
+		}
+	}
+	return ids, nil
+}
+
+// Get the raw json conf of the api
+func (ks *KVConfigSource) Get(apiID string) ([]byte, error) {
+	return ks.Store.Get(ks.Prefix + apiID)
+}
+
+// Watch the changes of the keys
+func (ks *KVConfigSource) Watch(onChange func(apiID string)) error {
+	return ks.Store.Watch(ks.Prefix, func(key string) {
+		onChange(strings.TrimPrefix(key, ks.Prefix))
+	})
+}
+
+// MemoryKVStore in memory KVStore
+type MemoryKVStore struct {
+	mu       sync.RWMutex
+	data     map[string][]byte
+	watchers map[string][]func(key string)
+}
+
+// NewMemoryKVStore new in memory kv store
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{
+		data:     make(map[string][]byte),
+		watchers: make(map[string][]func(key string)),
+	}
+}
+
+// Keys all keys which has the prefix
+func (ms *MemoryKVStore) Keys(prefix string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	var keys []string
+	for key := range ms.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Get value of key
+func (ms *MemoryKVStore) Get(key string) ([]byte, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if val, has := ms.data[key]; has {
+		return val, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// Watch call onChange when the key which has the prefix changed
+func (ms *MemoryKVStore) Watch(prefix string, onChange func(key string)) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.watchers[prefix] = append(ms.watchers[prefix], onChange)
+	return nil
+}
+
+// Set set value and notify the watchers
+func (ms *MemoryKVStore) Set(key string, val []byte) {
+	ms.mu.Lock()
+	ms.data[key] = val
+	ms.mu.Unlock()
+	ms.notify(key)
+}
+
+// Delete delete key and notify the watchers
+func (ms *MemoryKVStore) Delete(key string) {
+	ms.mu.Lock()
+	delete(ms.data, key)
+	ms.mu.Unlock()
+	ms.notify(key)
+}
+
+func (ms *MemoryKVStore) notify(key string) {
+	ms.mu.RLock()
+	var fns []func(key string)
+	for prefix, ws := range ms.watchers {
+		if strings.HasPrefix(key, prefix) {
+			fns = append(fns, ws...)
+		}
+	}
+	ms.mu.RUnlock()
+	for _, fn := range fns {
+		fn(key)
+	}
+}
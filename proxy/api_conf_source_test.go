@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_KVConfigSource(t *testing.T) {
+	store := NewMemoryKVStore()
+	backendA := testBackend(t, "a")
+	backendB := testBackend(t, "b")
+	prefix := "/api-front/test/"
+	store.Set(prefix+"kv", []byte(`{"path":"/kv/","enable":true,"hosts":{"h1":{"url":"`+backendA.URL+`/","enable":true}}}`))
+	store.Set("/other/kv2", []byte(`{}`))
+
+	manager := &APIServerManager{
+		ConfPath: filepath.Join(t.TempDir(), "server.json"),
+		mainConf: &mainConf{Users: users{"admin"}},
+		ConfigSourceFunc: func(serverID string, confDir string) ConfigSource {
+			return NewKVConfigSource(store, "/api-front/"+serverID+"/")
+		},
+	}
+	apiServer, err := newAPIServer(&serverVhost{Id: "test", Port: 8080, Enable: true, Users: NewUsers()}, manager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apiServer.Apis) != 1 || apiServer.getAPIByID("kv") == nil {
+		t.Fatal("api kv should be loaded from kv store:", apiServer.Apis)
+	}
+	ts := testServe(t, apiServer)
+
+	if _, body := testGet(t, ts.URL+"/kv/"); body != "a" {
+		t.Error("wrong body:", body)
+	}
+
+	//change the conf,reload by watch
+	store.Set(prefix+"kv", []byte(`{"path":"/kv/","enable":true,"hosts":{"h2":{"url":"`+backendB.URL+`/","enable":true}}}`))
+	if _, body := testGet(t, ts.URL+"/kv/"); body != "b" {
+		t.Error("api should be reloaded,wrong body:", body)
+	}
+
+	//new api
+	store.Set(prefix+"kv_new", []byte(`{"path":"/kv_new/","enable":true,"hosts":{"h1":{"url":"`+backendA.URL+`/","enable":true}}}`))
+	if _, body := testGet(t, ts.URL+"/kv_new/"); body != "a" {
+		t.Error("new api should be loaded,wrong body:", body)
+	}
+
+	//delete
+	store.Delete(prefix + "kv")
+	if apiServer.getAPIByID("kv") != nil {
+		t.Error("api should be removed")
+	}
+	if resp, _ := testGet(t, ts.URL+"/kv/"); resp.StatusCode != http.StatusNotFound {
+		t.Error("removed api should not route,got:", resp.StatusCode)
+	}
+}
+
+func Test_FileConfigSource(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "f1", `{"enable":true}`)
+	testLoadAPI(t, apiServer, "_skip", `{"enable":true}`)
+	ids, err := apiServer.confSource.List()
+	if err != nil || len(ids) != 1 || ids[0] != "f1" {
+		t.Error("wrong api list:", ids, err)
+	}
+}
+
+func Test_KVConfigSourceAdminReadOnly(t *testing.T) {
+	store := NewMemoryKVStore()
+	prefix := "/api-front/test/"
+	conf := `{"path":"/kv/","enable":true,"caller":[{"ip":"10.0.0.1","enable":true,"note":"kv"}],"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`
+	store.Set(prefix+"kv", []byte(conf))
+	manager := &APIServerManager{
+		ConfPath: filepath.Join(t.TempDir(), "server.json"),
+		mainConf: &mainConf{Users: users{"admin"}},
+		ConfigSourceFunc: func(serverID string, confDir string) ConfigSource {
+			return NewKVConfigSource(store, "/api-front/"+serverID+"/")
+		},
+	}
+	apiServer, err := newAPIServer(&serverVhost{Id: "test", Port: 8080, Enable: true, Users: NewUsers()}, manager)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(form url.Values) string {
+		req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+		wr.execute()
+		return rec.Body.String()
+	}
+	body := post(url.Values{
+		"do":      {"caller"},
+		"api_id":  {"kv"},
+		"datas[]": {"ip=10.0.0.1&enable=1&note=changed"},
+	})
+	if !strings.Contains(body, "read only") {
+		t.Error("caller save should be refused,got:", body)
+	}
+	body = post(url.Values{"do": {"changeid"}, "orig_id": {"kv"}, "new_id": {"kv2"}})
+	if !strings.Contains(body, "read only") {
+		t.Error("changeid should be refused,got:", body)
+	}
+
+	if item := apiServer.getAPIByID("kv").Caller.getCallerItemByIP("10.0.0.1"); item.Note != "kv" {
+		t.Error("the live api should not be changed:", item.Note)
+	}
+	if data, _ := store.Get(prefix + "kv"); string(data) != conf {
+		t.Error("the kv conf should not be changed:", string(data))
+	}
+	if _, err := os.Stat(filepath.Join(apiServer.getConfDir(), "kv.json")); !os.IsNotExist(err) {
+		t.Error("no conf file should be written")
+	}
+	if err := apiServer.getAPIByID("kv").save(); err != errConfReadOnly {
+		t.Error("save should fail as read only,got:", err)
+	}
+}
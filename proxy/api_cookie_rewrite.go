@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// initCookieRewrite normalize the backend domains of cookie_domain
+func (api *apiStruct) initCookieRewrite() {
+	if len(api.CookieDomain) == 0 {
+		return
+	}
+	domains := make(map[string]string, len(api.CookieDomain))
+	for from, to := range api.CookieDomain {
+		domains[strings.ToLower(strings.TrimPrefix(from, "."))] = to
+	}
+	api.CookieDomain = domains
+}
+
+// rewriteSetCookies rewrite the Domain and Path attributes of the backend's Set-Cookie headers,
+// the other attributes are kept as they are
+func (api *apiStruct) rewriteSetCookies(h http.Header) {
+	if len(api.CookieDomain) == 0 && len(api.CookiePath) == 0 {
+		return
+	}
+	cookies := h["Set-Cookie"]
+	for i, cookie := range cookies {
+		cookies[i] = api.rewriteSetCookie(cookie)
+	}
+}
+
+func (api *apiStruct) rewriteSetCookie(cookie string) string {
+	parts := strings.Split(cookie, ";")
+	result := []string{parts[0]}
+	for _, part := range parts[1:] {
+		attr := strings.TrimSpace(part)
+		pos := strings.Index(attr, "=")
+		if pos < 0 {
+			result = append(result, part)
+			continue
+		}
+		switch strings.ToLower(attr[:pos]) {
+		case "domain":
+			domain, has := api.cookieDomain(attr[pos+1:])
+			if !has {
+				break
+			}
+			if domain != "" {
+				result = append(result, " Domain="+domain)
+			}
+			//an empty one removes the attribute,the cookie is for the host of the proxy then
+			continue
+		case "path":
+			if p, has := api.cookiePath(attr[pos+1:]); has {
+				result = append(result, " Path="+p)
+				continue
+			}
+		}
+		result = append(result, part)
+	}
+	return strings.Join(result, ";")
+}
+
+func (api *apiStruct) cookieDomain(domain string) (string, bool) {
+	if to, has := api.CookieDomain[strings.ToLower(strings.TrimPrefix(domain, "."))]; has {
+		return to, true
+	}
+	to, has := api.CookieDomain["*"]
+	return to, has
+}
+
+// cookiePath replace the longest matched prefix
+func (api *apiStruct) cookiePath(p string) (string, bool) {
+	matched := ""
+	for from := range api.CookiePath {
+		if strings.HasPrefix(p, from) && len(from) > len(matched) {
+			matched = from
+		}
+	}
+	if matched == "" {
+		return "", false
+	}
+	return api.CookiePath[matched] + p[len(matched):], true
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_APIRewriteSetCookie(t *testing.T) {
+	api := &apiStruct{
+		CookieDomain: map[string]string{".Backend.local": "example.com", "tmp.local": ""},
+		CookiePath:   map[string]string{"/": "/api/", "/v1/": "/api/v2/"},
+	}
+	api.initCookieRewrite()
+	cases := map[string]string{
+		"a=1; Domain=backend.local; Path=/v1/user; HttpOnly": "a=1; Domain=example.com; Path=/api/v2/user; HttpOnly",
+		"b=2; path=/; domain=.backend.local; Secure":         "b=2; Path=/api/; Domain=example.com; Secure",
+		"c=3; Domain=tmp.local; Max-Age=60":                  "c=3; Max-Age=60",
+		"d=4; Domain=other.local; SameSite=Lax":              "d=4; Domain=other.local; SameSite=Lax",
+		"e=5":                                                "e=5",
+	}
+	for cookie, want := range cases {
+		if got := api.rewriteSetCookie(cookie); got != want {
+			t.Errorf("rewrite %q,expect %q,got %q", cookie, want, got)
+		}
+	}
+
+	api.CookieDomain = map[string]string{"*": "example.com"}
+	if got := api.rewriteSetCookie("a=1; Domain=other.local"); got != "a=1; Domain=example.com" {
+		t.Error("expect * to match all domains,got:", got)
+	}
+}
+
+func Test_HandlerRewriteSetCookie(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Add("Set-Cookie", "sid=abc; Domain=backend.local; Path=/; HttpOnly")
+		rw.Header().Add("Set-Cookie", "lang=en; Path=/")
+		rw.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "ck", `{"path":"/ck/","enable":true,
+		"cookie_domain":{"backend.local":"example.com"},"cookie_path":{"/":"/ck/"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, _ := testGet(t, ts.URL+"/ck/")
+	cookies := resp.Header["Set-Cookie"]
+	if len(cookies) != 2 || cookies[0] != "sid=abc; Domain=example.com; Path=/ck/; HttpOnly" || cookies[1] != "lang=en; Path=/ck/" {
+		t.Error("cookies not rewritten:", cookies)
+	}
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// debugHostHeader a trusted caller gets the response of the host in this header
+// instead of the master's,the master is still called as a shadow
+const debugHostHeader = "X-Debug-Host"
+
+// debugHostName the host to respond to the client,"" for the master.
+// the host must be one of the hosts to call.
+// the caller is trusted by its ip,which is from X-Real-Ip only behind trusted_proxies,
+// so a client can not forge it
+func debugHostName(req *http.Request, caller *CallerItem, hosts []*Host) (name string, found bool) {
+	name = strings.TrimSpace(req.Header.Get(debugHostHeader))
+	if name == "" || !caller.Trusted {
+		return "", false
+	}
+	for _, host := range hosts {
+		if host.Name == name {
+			return name, true
+		}
+	}
+	return name, false
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DeprecationConf the api is deprecated,the responses have the Deprecation and Sunset headers
+type DeprecationConf struct {
+	Date   string `json:"date"`   //弃用日期,如 "2026-01-02" 或 RFC3339,为空时表示已弃用
+	Sunset string `json:"sunset"` //下线日期,格式同date,可选
+	Link   string `json:"link"`   //迁移说明的链接,可选
+
+	deprecation string
+	sunset      string
+}
+
+// parseDeprecationDate accept 2006-01-02 and RFC3339
+func parseDeprecationDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (c *DeprecationConf) init() error {
+	c.deprecation = "true"
+	if c.Date != "" {
+		t, err := parseDeprecationDate(c.Date)
+		if err != nil {
+			return fmt.Errorf("date wrong:%s", c.Date)
+		}
+		//RFC 9745,a unix timestamp
+		c.deprecation = fmt.Sprintf("@%d", t.Unix())
+	}
+	c.sunset = ""
+	if c.Sunset != "" {
+		t, err := parseDeprecationDate(c.Sunset)
+		if err != nil {
+			return fmt.Errorf("sunset wrong:%s", c.Sunset)
+		}
+		//RFC 8594,a http date
+		c.sunset = t.UTC().Format(http.TimeFormat)
+	}
+	return nil
+}
+
+// setRespHeaders set the Deprecation,Sunset and Link headers
+func (c *DeprecationConf) setRespHeaders(h http.Header) {
+	h.Set("Deprecation", c.deprecation)
+	if c.sunset != "" {
+		h.Set("Sunset", c.sunset)
+	}
+	if c.Link != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, c.Link))
+	}
+}
+
+// logDeprecatedUse log who still call the deprecated api
+func (api *apiStruct) logDeprecatedUse(req *http.Request) {
+	expvarDeprecated.Add(api.expvarKey(), 1)
+	log.Println("[deprecated]", api.ID, "caller:", api.apiServer.callerIP(req), "uri:", req.URL.RequestURI(), "ua:", req.UserAgent())
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"testing"
+)
+
+func Test_HandlerDeprecation(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "dep", `{"path":"/dep/","enable":true,
+		"deprecation":{"date":"2026-01-02","sunset":"2026-07-01T12:00:00+08:00","link":"https://example.com/migrate"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "dep_nodate", `{"path":"/dep_nodate/","enable":true,
+		"deprecation":{},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "live", `{"path":"/live/","enable":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	before := testExpvarValue(expvarDeprecated, "test/dep")
+	resp, bd := testGet(t, ts.URL+"/dep/a")
+	if bd != "ok" {
+		t.Error("body wrong:", bd)
+	}
+	expect := map[string]string{
+		"Deprecation": "@1767312000",
+		"Sunset":      "Wed, 01 Jul 2026 04:00:00 GMT",
+		"Link":        `<https://example.com/migrate>; rel="deprecation"`,
+	}
+	for k, v := range expect {
+		if got := resp.Header.Get(k); got != v {
+			t.Error(k, "expect:", v, "got:", got)
+		}
+	}
+	if got := testExpvarValue(expvarDeprecated, "test/dep"); got != before+1 {
+		t.Error("deprecated requests not counted", before, got)
+	}
+
+	resp, _ = testGet(t, ts.URL+"/dep_nodate/a")
+	if got := resp.Header.Get("Deprecation"); got != "true" {
+		t.Error("Deprecation without date expect true,got:", got)
+	}
+	if got := resp.Header.Get("Sunset"); got != "" {
+		t.Error("Sunset not expected,got:", got)
+	}
+
+	resp, _ = testGet(t, ts.URL+"/live/a")
+	if got := resp.Header.Get("Deprecation"); got != "" {
+		t.Error("Deprecation not expected,got:", got)
+	}
+}
+
+func Test_APIDeprecationWrong(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.Deprecation = &DeprecationConf{Sunset: "next year"}
+	if err := api.init(); err == nil {
+		t.Error("expect error with the sunset")
+	}
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// diagnosticHost the result of one host,returned to the trusted callers when master failed
+type diagnosticHost struct {
+	Name   string `json:"name"`
+	Master bool   `json:"master"`
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Used   string `json:"used"`
+}
+
+func newDiagnosticHost(apiReq *apiHostRequest, resp *http.Response, err error, used time.Duration) *diagnosticHost {
+	dh := &diagnosticHost{
+		Name:   apiReq.apiHost.Name,
+		Master: apiReq.isMaster,
+		URL:    apiReq.urlNew,
+		Used:   fmt.Sprintf("%.3fms", float64(used.Nanoseconds())/1e6),
+	}
+	if err != nil {
+		dh.Error = err.Error()
+	} else {
+		dh.Status = resp.StatusCode
+	}
+	return dh
+}
+
+// writeDiagnostic call the other hosts and write all the results as a json 502
+func writeDiagnostic(rw http.ResponseWriter, reqs []*apiHostRequest, master *diagnosticHost) {
+	results := make([]*diagnosticHost, len(reqs))
+	var wg sync.WaitGroup
+	for i, apiReq := range reqs {
+		if apiReq.isMaster {
+			results[i] = master
+			continue
+		}
+		wg.Add(1)
+		go func(i int, apiReq *apiHostRequest) {
+			defer wg.Done()
+			start := time.Now()
+			resp, err := apiReq.RoundTrip()
+			if err == nil {
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			results[i] = newDiagnosticHost(apiReq, resp, err, time.Since(start))
+		}(i, apiReq)
+	}
+	wg.Wait()
+
+	data, _ := json.MarshalIndent(map[string]interface{}{
+		"error": "master failed:" + master.Error,
+		"hosts": results,
+	}, "", "  ")
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(http.StatusBadGateway)
+	rw.Write(data)
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EnrichConf add the headers looked up by a key of the request,
+// eg the partner tier by the subscriber id
+type EnrichConf struct {
+	KeyHeader    string                       `json:"key_header"`     //取key的请求header,如 X-Subscriber-Id
+	KeyPathIndex int                          `json:"key_path_index"` //没有key_header时,取接口路径之后的第n段(从1开始)作为key
+	Table        map[string]map[string]string `json:"table"`          //key -> 添加到请求的headers
+	Default      map[string]string            `json:"default"`        //key不在table中时添加的headers,为空不添加
+
+	names []string //all the header names,removed from the request at first
+}
+
+func (c *EnrichConf) init() error {
+	if c.KeyHeader == "" && c.KeyPathIndex < 1 {
+		return fmt.Errorf("key_header or key_path_index is required")
+	}
+	c.names = nil
+	canonical := func(hs map[string]string) map[string]string {
+		m := make(map[string]string, len(hs))
+		for k, v := range hs {
+			name := http.CanonicalHeaderKey(k)
+			m[name] = v
+			if !InStringSlice(name, c.names) {
+				c.names = append(c.names, name)
+			}
+		}
+		return m
+	}
+	for key, hs := range c.Table {
+		c.Table[key] = canonical(hs)
+	}
+	c.Default = canonical(c.Default)
+	return nil
+}
+
+// key the value of the key_header,or the segment of the path relative to the api path
+func (c *EnrichConf) key(req *http.Request, relPath string) string {
+	if c.KeyHeader != "" {
+		return strings.TrimSpace(req.Header.Get(c.KeyHeader))
+	}
+	parts := strings.Split(strings.Trim(relPath, "/"), "/")
+	if c.KeyPathIndex > len(parts) {
+		return ""
+	}
+	return parts[c.KeyPathIndex-1]
+}
+
+// enrichRequest remove the headers sent by the client,then set the ones looked up,
+// return the key and whether it is in the table
+func (api *apiStruct) enrichRequest(req *http.Request, relPath string) (key string, hit bool) {
+	c := api.Enrich
+	if c == nil {
+		return "", false
+	}
+	for _, name := range c.names {
+		req.Header.Del(name)
+	}
+	key = c.key(req, relPath)
+	hs, hit := c.Table[key]
+	if !hit || key == "" {
+		hs, hit = c.Default, false
+	}
+	for k, v := range hs {
+		req.Header.Set(k, v)
+	}
+	return key, hit
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HandlerEnrich(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.Header.Get("X-Tier") + "|" + req.Header.Get("X-Region")))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "en", `{"path":"/en/","enable":true,
+		"enrich":{"key_header":"X-Subscriber-Id","table":{"sub1":{"x-tier":"gold","x-region":"north"},"sub2":{"X-Tier":"silver"}},"default":{"X-Tier":"basic"}},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "enp", `{"path":"/enp/","enable":true,
+		"enrich":{"key_path_index":2,"table":{"sub1":{"X-Tier":"gold"}}},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(urlPath string, header map[string]string) string {
+		req, _ := http.NewRequest("GET", ts.URL+urlPath, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return string(bd)
+	}
+	cases := []struct {
+		urlPath string
+		header  map[string]string
+		expect  string
+	}{
+		{"/en/", map[string]string{"X-Subscriber-Id": "sub1"}, "gold|north"},
+		{"/en/", map[string]string{"X-Subscriber-Id": "sub2"}, "silver|"},
+		{"/en/", map[string]string{"X-Subscriber-Id": "sub3"}, "basic|"},
+		{"/en/", nil, "basic|"},
+		//the headers sent by the client are removed
+		{"/en/", map[string]string{"X-Subscriber-Id": "sub3", "X-Tier": "platinum", "X-Region": "south"}, "basic|"},
+		{"/enp/a/sub1/b", nil, "gold|"},
+		{"/enp/a/sub2/b", map[string]string{"X-Tier": "platinum"}, "|"},
+		{"/enp/a", nil, "|"},
+	}
+	for _, c := range cases {
+		if got := get(c.urlPath, c.header); got != c.expect {
+			t.Error(c.urlPath, c.header, "expect:", c.expect, "got:", got)
+		}
+	}
+}
+
+func Test_APIEnrichWrong(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.Enrich = &EnrichConf{Table: map[string]map[string]string{"a": {"X-A": "1"}}}
+	if err := api.init(); err == nil {
+		t.Error("expect error without the key")
+	}
+}
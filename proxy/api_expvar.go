@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"expvar"
+	"fmt"
+)
+
+// expvar counters,published at /debug/vars
+//
+//	api_front.requests      : serverID/apiID
+//	api_front.errors        : serverID/apiID
+//	api_front.host_requests : serverID/apiID/hostName
+//	api_front.assert_failures : serverID/apiID
+//	api_front.queue_depth   : serverID/apiID
+//	api_front.golden_mismatches : serverID/apiID
+//	api_front.conns         : port -> {active,idle,total,new_per_sec}
+//	api_front.shadow_reaped : serverID/apiID
+//	api_front.environments  : serverID -> environment
+var (
+	expvarAPIFront       = expvar.NewMap("api_front")
+	expvarRequests       = new(expvar.Map).Init()
+	expvarErrors         = new(expvar.Map).Init()
+	expvarHostRequests   = new(expvar.Map).Init()
+	expvarAssertFailures = new(expvar.Map).Init()
+	expvarQueueDepth     = new(expvar.Map).Init()
+	expvarGoldenMismatch = new(expvar.Map).Init()
+	expvarConns          = new(expvar.Map).Init()
+	expvarShadowReaped   = new(expvar.Map).Init()
+	expvarEnvironments   = new(expvar.Map).Init()
+	expvarDeprecated     = new(expvar.Map).Init()
+	expvarShadowDiffs    = new(expvar.Map).Init()
+)
+
+func init() {
+	expvarAPIFront.Set("requests", expvarRequests)
+	expvarAPIFront.Set("errors", expvarErrors)
+	expvarAPIFront.Set("host_requests", expvarHostRequests)
+	expvarAPIFront.Set("assert_failures", expvarAssertFailures)
+	expvarAPIFront.Set("queue_depth", expvarQueueDepth)
+	expvarAPIFront.Set("golden_mismatches", expvarGoldenMismatch)
+	expvarAPIFront.Set("conns", expvarConns)
+	expvarAPIFront.Set("shadow_reaped", expvarShadowReaped)
+	expvarAPIFront.Set("environments", expvarEnvironments)
+	expvarAPIFront.Set("deprecated_requests", expvarDeprecated)
+	expvarAPIFront.Set("shadow_diffs", expvarShadowDiffs)
+}
+
+func (api *apiStruct) expvarKey() string {
+	return fmt.Sprintf("%s/%s", api.apiServer.GetServerID(), api.ID)
+}
+
+func (api *apiStruct) expvarReqInc() {
+	expvarRequests.Add(api.expvarKey(), 1)
+}
+
+func (api *apiStruct) expvarErrInc() {
+	expvarErrors.Add(api.expvarKey(), 1)
+}
+
+func (api *apiStruct) expvarHostReqInc(hostName string) {
+	expvarHostRequests.Add(api.expvarKey()+"/"+hostName, 1)
+}
+
+// expvarQueueDepthAdd the requests waiting in queue
+func (api *apiStruct) expvarQueueDepthAdd(delta int64) {
+	expvarQueueDepth.Add(api.expvarKey(), delta)
+}
+
+const expvarPath = "/debug/vars"
+
+func (wr *webReq) debugVars() {
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	expvar.Handler().ServeHTTP(wr.rw, wr.req)
+}
+
+// expvarEnvSet publish the environment of the server,
+// so the counters keyed by serverID can be told apart
+func (apiServer *APIServer) expvarEnvSet() {
+	env := apiServer.ServerVhostConf.Environment
+	if env == "" {
+		return
+	}
+	v := new(expvar.String)
+	v.Set(env)
+	expvarEnvironments.Set(apiServer.GetServerID(), v)
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testExpvarValue(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func Test_APIExpvar(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "ev", `{"path":"/ev/","enable":true,"hosts":{
+		"h1":{"url":"`+backend.URL+`/","enable":true},
+		"h2":{"url":"`+backend.URL+`/","enable":true}
+	}}`)
+	testLoadAPI(t, apiServer, "ev_bad", `{"path":"/ev_bad/","enable":true,"timeout_ms":200,"hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true}
+	}}`)
+	ts := testServe(t, apiServer)
+
+	//the maps are global,count the changes of this run
+	reqsBefore := testExpvarValue(expvarRequests, "test/ev")
+	errsBefore := testExpvarValue(expvarErrors, "test/ev")
+	badErrsBefore := testExpvarValue(expvarErrors, "test/ev_bad")
+	hostsBefore := testExpvarValue(expvarHostRequests, "test/ev/h1") + testExpvarValue(expvarHostRequests, "test/ev/h2")
+	for i := 0; i < 3; i++ {
+		testGet(t, ts.URL+"/ev/x")
+	}
+	resp, _ := testGet(t, ts.URL+"/ev_bad/x")
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Error("expect 502,got:", resp.StatusCode)
+	}
+
+	if n := testExpvarValue(expvarRequests, "test/ev") - reqsBefore; n != 3 {
+		t.Error("requests wrong:", n)
+	}
+	if n := testExpvarValue(expvarErrors, "test/ev") - errsBefore; n != 0 {
+		t.Error("errors wrong:", n)
+	}
+	if n := testExpvarValue(expvarErrors, "test/ev_bad") - badErrsBefore; n != 1 {
+		t.Error("errors wrong:", n)
+	}
+	// the master is called sync,so at least 3 requests had been counted
+	hostTotal := testExpvarValue(expvarHostRequests, "test/ev/h1") + testExpvarValue(expvarHostRequests, "test/ev/h2") - hostsBefore
+	if hostTotal < 3 {
+		t.Error("host_requests wrong:", hostTotal)
+	}
+}
+
+func Test_APIExpvarAdminOnly(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", expvarPath, nil), nil)
+	wr.execute()
+	var ret *JSONResult
+	json.Unmarshal(rec.Body.Bytes(), &ret)
+	if ret == nil || ret.Code != 403 {
+		t.Error("expect 403 for guest:", rec.Body.String())
+	}
+
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", expvarPath, nil), &User{ID: "admin"})
+	wr.execute()
+	vars := make(map[string]interface{})
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatal("decode vars failed:", err)
+	}
+	if _, has := vars["api_front"]; !has {
+		t.Error("api_front vars not found")
+	}
+}
+
+func Test_APIEnvironment(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.ServerVhostConf.Environment = "staging"
+	apiServer.expvarEnvSet()
+	if v, _ := expvarEnvironments.Get("test").(*expvar.String); v == nil || v.Value() != "staging" {
+		t.Error("expect environment in expvar,got:", expvarEnvironments.Get("test"))
+	}
+
+	backend := testBackend(t, "ok")
+	api := testLoadAPI(t, apiServer, "env", `{"path":"/env/","enable":true,"access_log":"only",
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+	testGet(t, ts.URL+"/env/")
+
+	var line string
+	for i := 0; i < 100 && line == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ := ioutil.ReadFile(api.accessLogPath())
+		for _, l := range strings.Split(string(data), "\n") {
+			if strings.Contains(l, "logindex=1/") {
+				line = l
+			}
+		}
+	}
+	if !strings.Contains(line, " env=staging ") {
+		t.Error("expect env tag in access log,got:", line)
+	}
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// golden modes,compare the master response with the recorded one for regression test.
+// the response to client is not changed
+const (
+	goldenRecord  = "record"  //save the master response as golden
+	goldenCompare = "compare" //compare the master response with golden
+)
+
+// goldenResp the recorded response,body is decoded when gzipped
+type goldenResp struct {
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	Status int    `json:"status"`
+	Body   []byte `json:"body"`
+}
+
+func (api *apiStruct) initGolden() error {
+	switch api.Golden {
+	case "", goldenRecord, goldenCompare:
+		return nil
+	}
+	return fmt.Errorf("golden wrong:%s", api.Golden)
+}
+
+// goldenPath confDir/_golden/{apiID}/{signature}.json,
+// signature is made of method,path,query and body
+func (api *apiStruct) goldenPath(req *http.Request, reqBody []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", req.Method, req.URL.Path, req.URL.Query().Encode())
+	h.Write(reqBody)
+	return filepath.Join(api.apiServer.getConfDir(), "_golden", api.ID, fmt.Sprintf("%x.json", h.Sum(nil)))
+}
+
+// checkGolden record or compare,return error when failed or mismatch,
+// tier is the compare tier used,empty when not compared
+func (api *apiStruct) checkGolden(req *http.Request, reqBody []byte, resp *http.Response, respBody *bytes.Buffer) (tier string, err error) {
+	body := respBody.Bytes()
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		body = []byte(gzipDocode(bytes.NewBuffer(body)))
+	}
+	live := &goldenResp{
+		Method: req.Method,
+		URI:    req.URL.RequestURI(),
+		Status: resp.StatusCode,
+		Body:   body,
+	}
+	goldenPath := api.goldenPath(req, reqBody)
+
+	if api.Golden == goldenRecord {
+		data, err := json.MarshalIndent(live, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		DirCheck(goldenPath)
+		return "", ioutil.WriteFile(goldenPath, data, 0644)
+	}
+
+	var golden *goldenResp
+	if err := LoadJSONFile(goldenPath, &golden); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("golden not found")
+		}
+		return "", err
+	}
+	return compareTier(api.bodyComparator, golden.Body, live.Body), golden.diff(live, api.bodyComparator)
+}
+
+func (g *goldenResp) diff(live *goldenResp, cmp BodyComparator) error {
+	if g.Status != live.Status {
+		return fmt.Errorf("status mismatch,golden:%d live:%d", g.Status, live.Status)
+	}
+	return cmp.Compare(g.Body, live.Body)
+}
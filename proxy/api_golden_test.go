@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_HandlerGolden(t *testing.T) {
+	var backendBody atomic.Value
+	backendBody.Store("v1")
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(backendBody.Load().(string)))
+	}))
+	defer backend.Close()
+
+	apiServer := newTestAPIServer(t)
+	conf := func(mode string) string {
+		return `{"path":"/g/","enable":true,"golden":"` + mode + `","hosts":{"h1":{"url":"` + backend.URL + `/","enable":true}}}`
+	}
+	api := testLoadAPI(t, apiServer, "g", conf(goldenRecord))
+	ts := testServe(t, apiServer)
+
+	testGet(t, ts.URL+"/g/a?x=1")
+	req := httptest.NewRequest("GET", "/g/a?x=1", nil)
+	if !FileExists(api.goldenPath(req, []byte{})) {
+		t.Fatal("golden not recorded")
+	}
+
+	testLoadAPI(t, apiServer, "g", conf(goldenCompare))
+	mismatchBefore := testExpvarValue(expvarGoldenMismatch, "test/g")
+	if _, body := testGet(t, ts.URL+"/g/a?x=1"); body != "v1" {
+		t.Error("wrong body:", body)
+	}
+	if n := testExpvarValue(expvarGoldenMismatch, "test/g") - mismatchBefore; n != 0 {
+		t.Error("expect no mismatch,got:", n)
+	}
+
+	backendBody.Store("v2")
+	//the client gets the live response
+	if _, body := testGet(t, ts.URL+"/g/a?x=1"); body != "v2" {
+		t.Error("wrong body:", body)
+	}
+	if n := testExpvarValue(expvarGoldenMismatch, "test/g") - mismatchBefore; n != 1 {
+		t.Error("expect 1 mismatch,got:", n)
+	}
+
+	//not recorded
+	testGet(t, ts.URL+"/g/a?x=2")
+	if n := testExpvarValue(expvarGoldenMismatch, "test/g") - mismatchBefore; n != 2 {
+		t.Error("expect 2 mismatch,got:", n)
+	}
+}
+
+func Test_GoldenDiff(t *testing.T) {
+	g := &goldenResp{Status: 200, Body: []byte("hello world")}
+	if err := g.diff(&goldenResp{Status: 200, Body: []byte("hello world")}, bytesComparator{}); err != nil {
+		t.Error("expect same:", err)
+	}
+	if err := g.diff(&goldenResp{Status: 500, Body: []byte("hello world")}, bytesComparator{}); err == nil || !strings.Contains(err.Error(), "status") {
+		t.Error("expect status mismatch:", err)
+	}
+	if err := g.diff(&goldenResp{Status: 200, Body: []byte("hello go")}, bytesComparator{}); err == nil || !strings.Contains(err.Error(), "byte 6") {
+		t.Error("expect body mismatch at 6:", err)
+	}
+}
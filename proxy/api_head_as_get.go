@@ -0,0 +1,19 @@
+package proxy
+
+import (
+	"net/http"
+)
+
+// headAsGet HEAD is sent to the backends as GET,for the backends not implement HEAD,
+// the client gets the headers only
+func (api *apiStruct) headAsGet(method string) bool {
+	return api.HeadAsGet && method == http.MethodHead
+}
+
+// backendMethod the method sent to the backends
+func (api *apiStruct) backendMethod(method string) string {
+	if api.headAsGet(method) {
+		return http.MethodGet
+	}
+	return method
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HandlerHeadAsGet(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Header().Set("Etag", `"v1"`)
+		rw.Write([]byte("hello world"))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "hg", `{"path":"/hg/","enable":true,"head_as_get":true,"methods":["GET"],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "hk", `{"path":"/hk/","enable":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	head := func(urlStr string) (*http.Response, []byte) {
+		resp, err := http.Head(urlStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp, bd
+	}
+
+	resp, bd := head(ts.URL + "/hg/a")
+	if resp.StatusCode != 200 {
+		t.Fatal("status wrong:", resp.StatusCode)
+	}
+	if len(bd) != 0 {
+		t.Error("HEAD should get no body,got:", string(bd))
+	}
+	if resp.ContentLength != int64(len("hello world")) {
+		t.Error("Content-Length of GET expected,got:", resp.ContentLength)
+	}
+	if resp.Header.Get("Etag") != `"v1"` || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Error("headers of GET expected,got:", resp.Header)
+	}
+
+	if _, bd := testGet(t, ts.URL+"/hg/a"); bd != "hello world" {
+		t.Error("GET body wrong:", bd)
+	}
+
+	//sent as HEAD without head_as_get
+	if resp, _ := head(ts.URL + "/hk/a"); resp.StatusCode != http.StatusNotImplemented {
+		t.Error("HEAD expected to reach the backend,got:", resp.StatusCode)
+	}
+}
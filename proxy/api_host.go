@@ -1,17 +1,59 @@
 package proxy
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
 	"time"
 )
 
 // Host one api backend host
 type Host struct {
-	Name      string `json:"-"`
-	URLStr    string `json:"url"`
-	Enable    bool   `json:"enable"`
-	Note      string `json:"note"`
-	SortIndex int    `json:"sort"`
-	Checked   bool   `json:"-"`
+	Name        string           `json:"-"`
+	URLStr      string           `json:"url"`
+	Enable      bool             `json:"enable"`
+	Note        string           `json:"note"`
+	SortIndex   int              `json:"sort"`
+	Checked     bool             `json:"-"`
+	MatchHeader *HostMatchHeader `json:"match_header,omitempty"` //请求header匹配时才可作为master,如灰度
+
+	BodyRateLimit   int64 `json:"body_rate_limit"`   //发送request body的限速,bytes/sec,0为不限制
+	RateLimitMaster bool  `json:"rate_limit_master"` //作为master时是否也限速,默认不限
+
+	FallbackURL string `json:"fallback_url"` //连接URL失败时使用的备用地址,如灾备地址,代理模式下无效
+
+	ReadOnly bool `json:"read_only"` //只接收GET/HEAD/OPTIONS请求,其他method的请求不转发到该host
+
+	DNSCacheSec int `json:"dns_cache_sec"` //缓存域名解析结果的秒数,多个ip轮流使用,0为不缓存
+
+	ConnectTimeoutMs int `json:"connect_timeout_ms"` //连接超时,默认为接口的timeout_ms
+	TimeoutMs        int `json:"timeout_ms"`         //总超时,包括读取response body,替代接口的timeout_ms,默认只有接口的timeout_ms(到收到header为止)
+
+	GzipBody bool `json:"gzip_body"` //发送给该host的请求body使用gzip压缩(Content-Encoding: gzip),client已经编码过的不处理
+
+	Scheme string `json:"scheme"` //替换url的scheme(http/https),优先于接口的scheme,为空则不替换
+
+	Priority int `json:"priority"` //没有default_master和调用方偏好时,优先级最高的host作为master,相同时按名称排序,都为0时随机
+
+	stats *hostStats
+	dns   *dnsCache
+}
+
+// HostMatchHeader header condition for a host to be master
+type HostMatchHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"` //为空时header存在即可
+}
+
+func (m *HostMatchHeader) match(req *http.Request) bool {
+	vs, has := req.Header[http.CanonicalHeaderKey(m.Name)]
+	if !has {
+		return false
+	}
+	if m.Value == "" {
+		return true
+	}
+	return InStringSlice(m.Value, vs)
 }
 
 // Hosts api hosts
@@ -23,14 +65,68 @@ func newHosts() Hosts {
 
 func (h *Host) copy() *Host {
 	return &Host{
-		Name:      h.Name,
-		URLStr:    h.URLStr,
-		Enable:    h.Enable,
-		Note:      h.Note,
-		SortIndex: h.SortIndex,
+		Name:        h.Name,
+		URLStr:      h.URLStr,
+		Enable:      h.Enable,
+		Note:        h.Note,
+		SortIndex:   h.SortIndex,
+		MatchHeader: h.MatchHeader,
+
+		BodyRateLimit:   h.BodyRateLimit,
+		RateLimitMaster: h.RateLimitMaster,
+
+		FallbackURL: h.FallbackURL,
+
+		ReadOnly: h.ReadOnly,
+
+		DNSCacheSec: h.DNSCacheSec,
+
+		ConnectTimeoutMs: h.ConnectTimeoutMs,
+		TimeoutMs:        h.TimeoutMs,
+
+		GzipBody: h.GzipBody,
+
+		Scheme: h.Scheme,
+
+		Priority: h.Priority,
 	}
 }
 
+// priorityHostName the host with the highest priority,the smaller name when the same,
+// empty when no priority is set
+func (hosts Hosts) priorityHostName(names []string) string {
+	var best string
+	var prioritySet bool
+	for _, name := range names {
+		h, has := hosts[name]
+		if !has {
+			continue
+		}
+		if h.Priority != 0 {
+			prioritySet = true
+		}
+		if best == "" || h.Priority > hosts[best].Priority || (h.Priority == hosts[best].Priority && name < best) {
+			best = name
+		}
+	}
+	if !prioritySet {
+		return ""
+	}
+	return best
+}
+
+// acceptMethod a read only host accepts the safe methods only
+func (h *Host) acceptMethod(method string) bool {
+	if !h.ReadOnly {
+		return true
+	}
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
 func (hs Hosts) addNewHost(host *Host) {
 	hs[host.Name] = host
 }
@@ -38,9 +134,46 @@ func (hs Hosts) addNewHost(host *Host) {
 func (hs Hosts) init() {
 	for name, host := range hs {
 		host.Name = name
+		host.stats = &hostStats{}
+		host.dns = nil
+		if host.DNSCacheSec > 0 {
+			host.dns = newDNSCache(time.Duration(host.DNSCacheSec) * time.Second)
+		}
 	}
 }
 
+// duplicateHostName the first duplicate name in the hosts of the raw conf,
+// json.Unmarshal keeps the last one silently
+func duplicateHostName(data []byte) string {
+	var raw struct {
+		Hosts json.RawMessage `json:"hosts"`
+	}
+	if json.Unmarshal(data, &raw) != nil || len(raw.Hosts) == 0 {
+		return ""
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw.Hosts))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return ""
+	}
+	names := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		name, _ := tok.(string)
+		if names[name] {
+			return name
+		}
+		names[name] = true
+		var v json.RawMessage
+		if dec.Decode(&v) != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
 func newHost(name string, url string, enable bool) *Host {
 	return &Host{
 		Name:   name,
@@ -71,7 +204,6 @@ func (hs Hosts) activeHostsNum() int {
 }
 
 // GetHostsWithPref tpl call this
-//
 func (hs Hosts) GetHostsWithPref(pref []string) []*Host {
 
 	enableNames := []string{}
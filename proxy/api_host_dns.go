@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dnsResolver the resolver used by the dns cache,replaced in tests
+type dnsResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var hostResolver dnsResolver = net.DefaultResolver
+
+// dnsCache the resolved addresses of the host,refreshed after ttl.
+// the addresses are used by turns
+type dnsCache struct {
+	ttl      time.Duration
+	resolver dnsResolver
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	addrs  []string
+	expire time.Time
+	next   uint32
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:      ttl,
+		resolver: hostResolver,
+		now:      time.Now,
+		entries:  make(map[string]*dnsEntry),
+	}
+}
+
+// lookup one address of the host,the expired addresses are still used
+// when the refresh fails
+func (c *dnsCache) lookup(ctx context.Context, host string) (string, error) {
+	c.mu.Lock()
+	entry := c.entries[host]
+	c.mu.Unlock()
+
+	if entry == nil || c.now().After(entry.expire) {
+		addrs, err := c.resolver.LookupHost(ctx, host)
+		if err == nil && len(addrs) == 0 {
+			err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		if err != nil {
+			if entry == nil {
+				return "", err
+			}
+			log.Println("[warning]dns refresh failed,use the cached", host, err)
+		} else {
+			entry = &dnsEntry{addrs: addrs, expire: c.now().Add(c.ttl)}
+			c.mu.Lock()
+			c.entries[host] = entry
+			c.mu.Unlock()
+		}
+	}
+	n := atomic.AddUint32(&entry.next, 1)
+	return entry.addrs[int(n-1)%len(entry.addrs)], nil
+}
+
+// clear drop all the cached addresses
+func (c *dnsCache) clear() {
+	c.mu.Lock()
+	c.entries = make(map[string]*dnsEntry)
+	c.mu.Unlock()
+}
+
+// dialContext dial the cached address instead of the host name
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		ip, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testResolver struct {
+	mu    sync.Mutex
+	addrs map[string][]string
+	err   error
+	calls int
+}
+
+func (r *testResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.addrs[host], nil
+}
+
+func (r *testResolver) set(host string, addrs []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addrs[host] = addrs
+	r.err = err
+}
+
+func Test_DNSCache(t *testing.T) {
+	resolver := &testResolver{addrs: map[string][]string{"a.test": {"10.0.0.1", "10.0.0.2"}}}
+	now := time.Now()
+	c := newDNSCache(time.Minute)
+	c.resolver = resolver
+	c.now = func() time.Time { return now }
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		ip, err := c.lookup(context.Background(), "a.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ip)
+	}
+	if strings.Join(got, ",") != "10.0.0.1,10.0.0.2,10.0.0.1,10.0.0.2" {
+		t.Error("expect round-robin,got:", got)
+	}
+	if resolver.calls != 1 {
+		t.Error("expect 1 lookup,got:", resolver.calls)
+	}
+
+	//refresh after ttl
+	resolver.set("a.test", []string{"10.0.0.3"}, nil)
+	now = now.Add(2 * time.Minute)
+	if ip, _ := c.lookup(context.Background(), "a.test"); ip != "10.0.0.3" {
+		t.Error("expect refreshed 10.0.0.3,got:", ip)
+	}
+
+	//keep the old one when refresh failed
+	resolver.set("a.test", nil, errors.New("dns down"))
+	now = now.Add(2 * time.Minute)
+	if ip, err := c.lookup(context.Background(), "a.test"); err != nil || ip != "10.0.0.3" {
+		t.Error("expect cached 10.0.0.3,got:", ip, err)
+	}
+	if _, err := c.lookup(context.Background(), "b.test"); err == nil {
+		t.Error("expect error for unknown host")
+	}
+
+	c.clear()
+	if _, err := c.lookup(context.Background(), "a.test"); err == nil {
+		t.Error("expect error after clear")
+	}
+}
+
+func Test_HandlerHostDNSCache(t *testing.T) {
+	resolver := &testResolver{addrs: map[string][]string{"backend.test": {"127.0.0.1"}}}
+	defer func(r dnsResolver) { hostResolver = r }(hostResolver)
+	hostResolver = resolver
+
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	testLoadAPI(t, apiServer, "dns", `{"path":"/dns/","enable":true,
+		"hosts":{"h1":{"url":"http://backend.test:`+port+`/","enable":true,"dns_cache_sec":60}}}`)
+
+	ts := testServe(t, apiServer)
+
+	for i := 0; i < 3; i++ {
+		resp, body := testGet(t, ts.URL+"/dns/")
+		if resp.StatusCode != 200 || body != "ok" {
+			t.Fatal("request failed:", resp.StatusCode, body)
+		}
+	}
+	if resolver.calls != 1 {
+		t.Error("expect 1 lookup,got:", resolver.calls)
+	}
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+)
+
+// requestBody the body send to the host,gzipped when gzip_body is set.
+// the bodies already encoded by the client are sent as they are
+func (h *Host) requestBody(body []byte, header http.Header, level int) (hostBody []byte, gzipped bool) {
+	if !h.GzipBody || len(body) == 0 || header.Get("Content-Encoding") != "" {
+		return body, false
+	}
+	var buf bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return body, false
+	}
+	gw.Write(body)
+	if err := gw.Close(); err != nil {
+		return body, false
+	}
+	return buf.Bytes(), true
+}
+
+// gzipLevel the api's gzip_level,the server's when not set
+func (api *apiStruct) gzipLevel() int {
+	return gzipLevel(api.GzipLevel, api.apiServer.ServerVhostConf.GzipLevel)
+}
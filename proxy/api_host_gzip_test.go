@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_HandlerHostGzipBody(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	type received struct {
+		encoding string
+		body     string
+	}
+	var mu sync.Mutex
+	got := make(map[string]received)
+	newBackend := func(name string) *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			r := received{encoding: req.Header.Get("Content-Encoding")}
+			if r.encoding == "gzip" {
+				if gr, err := gzip.NewReader(req.Body); err == nil {
+					bd, _ := ioutil.ReadAll(gr)
+					r.body = string(bd)
+				}
+			} else {
+				bd, _ := ioutil.ReadAll(req.Body)
+				r.body = string(bd)
+			}
+			mu.Lock()
+			got[name] = r
+			mu.Unlock()
+			rw.Write([]byte(name))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	gz := newBackend("gz")
+	plain := newBackend("plain")
+	testLoadAPI(t, apiServer, "gb", `{"path":"/gb/","enable":true,"default_master":"gz","hosts":{
+		"gz":{"url":"`+gz.URL+`/","enable":true,"gzip_body":true},
+		"plain":{"url":"`+plain.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	body := strings.Repeat(`{"k":"v"}`, 100)
+	resp, err := http.Post(ts.URL+"/gb/", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	//the shadow is called after the response
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if r := got["gz"]; r.encoding != "gzip" || r.body != body {
+		t.Error("expect gzip body decoded to the original:", r.encoding, len(r.body))
+	}
+	if r := got["plain"]; r.encoding != "" || r.body != body {
+		t.Error("expect plain body for the other host:", r.encoding, len(r.body))
+	}
+}
+
+func Test_HostRequestBodyGzipLevel(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("level")
+	if api.gzipLevel() != gzip.DefaultCompression {
+		t.Error("expect the default level,got:", api.gzipLevel())
+	}
+	apiServer.ServerVhostConf.GzipLevel = gzip.BestSpeed
+	if api.gzipLevel() != gzip.BestSpeed {
+		t.Error("expect the server's level,got:", api.gzipLevel())
+	}
+	api.GzipLevel = gzip.BestCompression
+	if api.gzipLevel() != gzip.BestCompression {
+		t.Error("expect the api's level,got:", api.gzipLevel())
+	}
+
+	host := newHost("h1", "http://127.0.0.1/", true)
+	host.GzipBody = true
+	var body []byte
+	for i := 0; i < 2000; i++ {
+		body = append(body, fmt.Sprintf(`{"id":%d,"name":"item_%d"}`, i, i%37)...)
+	}
+	sizes := make(map[int]int)
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestSpeed, gzip.BestCompression} {
+		hostBody, gzipped := host.requestBody(body, http.Header{}, level)
+		if !gzipped {
+			t.Fatal("level", level, "not gzipped")
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(hostBody))
+		if err != nil {
+			t.Fatal("level", level, "not gzip data:", err)
+		}
+		bd, err := ioutil.ReadAll(gr)
+		if err != nil || !bytes.Equal(bd, body) {
+			t.Fatal("level", level, "decode wrong:", err)
+		}
+		sizes[level] = len(hostBody)
+	}
+	if !(sizes[gzip.BestCompression] < sizes[gzip.BestSpeed] && sizes[gzip.BestSpeed] < sizes[gzip.HuffmanOnly]) {
+		t.Error("expect smaller output with the higher level:", sizes)
+	}
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// hostStats the recent health of a host,reset when the api reloads
+type hostStats struct {
+	mu      sync.Mutex
+	fails   int     //连续失败次数
+	latency float64 //平均耗时(ms),新的请求权重更高
+}
+
+const hostStatsLatencyWeight = 0.3
+
+// record the result of a request,a nil stats is ignored
+func (s *hostStats) record(used time.Duration, failed bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if failed {
+		s.fails++
+		return
+	}
+	s.fails = 0
+	ms := float64(used) / float64(time.Millisecond)
+	if s.latency == 0 {
+		s.latency = ms
+	} else {
+		s.latency = s.latency*(1-hostStatsLatencyWeight) + ms*hostStatsLatencyWeight
+	}
+}
+
+func (s *hostStats) get() (fails int, latency float64) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fails, s.latency
+}
+
+// reset forget the history,the host is unknown again
+func (s *hostStats) reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fails = 0
+	s.latency = 0
+}
+
+// sortHostsByHealth fewer failures first,then the faster ones.
+// the hosts without requests yet are the fastest,so they get requests
+func sortHostsByHealth(hs []*Host) {
+	type hostScore struct {
+		fails   int
+		latency float64
+	}
+	scores := make(map[*Host]hostScore, len(hs))
+	for _, h := range hs {
+		fails, latency := h.stats.get()
+		scores[h] = hostScore{fails, latency}
+	}
+	sort.SliceStable(hs, func(i, j int) bool {
+		a, b := scores[hs[i]], scores[hs[j]]
+		if a.fails != b.fails {
+			return a.fails < b.fails
+		}
+		return a.latency < b.latency
+	})
+}
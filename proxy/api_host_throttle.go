@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// rateLimitReader limit the read speed to rate bytes/sec
+type rateLimitReader struct {
+	r     io.Reader
+	rate  int64
+	start time.Time
+	n     int64
+}
+
+func newRateLimitReader(r io.Reader, rate int64) *rateLimitReader {
+	return &rateLimitReader{r: r, rate: rate}
+}
+
+func (rl *rateLimitReader) Read(p []byte) (int, error) {
+	if rl.start.IsZero() {
+		rl.start = time.Now()
+	}
+	//read small chunks,so the speed is smooth
+	chunk := rl.rate / 10
+	if chunk < 1 {
+		chunk = 1
+	}
+	if int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+	n, err := rl.r.Read(p)
+	rl.n += int64(n)
+	expect := time.Duration(rl.n * int64(time.Second) / rl.rate)
+	if wait := expect - time.Since(rl.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
+// bodyReader the request body send to the host,
+// the master is not throttled unless RateLimitMaster is set
+func (h *Host) bodyReader(body []byte, isMaster bool) io.Reader {
+	r := io.Reader(bytes.NewReader(body))
+	if h.BodyRateLimit < 1 || (isMaster && !h.RateLimitMaster) {
+		return r
+	}
+	return newRateLimitReader(r, h.BodyRateLimit)
+}
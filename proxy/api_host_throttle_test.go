@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HandlerShadowBodyThrottle(t *testing.T) {
+	bodyDone := make(map[string]chan time.Time)
+	newBackend := func(name string) *httptest.Server {
+		bodyDone[name] = make(chan time.Time, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			ioutil.ReadAll(req.Body)
+			bodyDone[name] <- time.Now()
+			rw.Write([]byte(name))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	master := newBackend("master")
+	shadow := newBackend("shadow")
+
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "tr", `{"path":"/tr/","enable":true,"default_master":"master","hosts":{
+		"master":{"url":"`+master.URL+`/","enable":true,"body_rate_limit":2000},
+		"shadow":{"url":"`+shadow.URL+`/","enable":true,"body_rate_limit":2000}
+	}}`)
+	ts := testServe(t, apiServer)
+
+	start := time.Now()
+	resp, err := http.Post(ts.URL+"/tr/x", "text/plain", strings.NewReader(strings.Repeat("a", 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Api-Front-Master") != "master" {
+		t.Fatal("wrong master:", resp.Header.Get("Api-Front-Master"))
+	}
+
+	//1000 bytes at 2000 bytes/sec
+	if used := (<-bodyDone["shadow"]).Sub(start); used < 400*time.Millisecond {
+		t.Error("shadow body should be throttled,used:", used)
+	}
+	if used := (<-bodyDone["master"]).Sub(start); used > 300*time.Millisecond {
+		t.Error("master should not be throttled,used:", used)
+	}
+}
+
+func Test_RateLimitReader(t *testing.T) {
+	start := time.Now()
+	r := newRateLimitReader(strings.NewReader(strings.Repeat("a", 300)), 1000)
+	bd, err := ioutil.ReadAll(r)
+	if err != nil || len(bd) != 300 {
+		t.Fatal("read failed:", len(bd), err)
+	}
+	if used := time.Since(start); used < 250*time.Millisecond {
+		t.Error("read too fast:", used)
+	}
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// dialer connect with connect_timeout_ms of the host,or the api's timeout
+func (h *Host) dialer(apiTimeout time.Duration) *net.Dialer {
+	timeout := apiTimeout
+	if h.ConnectTimeoutMs > 0 {
+		timeout = time.Duration(h.ConnectTimeoutMs) * time.Millisecond
+	}
+	return &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 0,
+	}
+}
+
+// requestTimeout the timeout_ms of the host replaces the api's
+func (h *Host) requestTimeout(apiTimeout time.Duration) time.Duration {
+	if h.TimeoutMs > 0 {
+		return time.Duration(h.TimeoutMs) * time.Millisecond
+	}
+	return apiTimeout
+}
+
+// withTotalTimeout the deadline covers reading the response body too,
+// call the returned func to release it when the request failed
+func (ar *apiHostRequest) withTotalTimeout(d time.Duration) (cancel func()) {
+	ctx, cancel := context.WithTimeout(ar.req.Context(), d)
+	ar.req = ar.req.WithContext(ctx)
+	if ar.fallbackReq != nil {
+		ar.fallbackReq = ar.fallbackReq.WithContext(ctx)
+	}
+	return cancel
+}
+
+// cancelOnClose release the deadline of the request when the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_HostTimeouts(t *testing.T) {
+	h := &Host{}
+	if d := h.dialer(time.Second).Timeout; d != time.Second {
+		t.Error("expect the api timeout to connect,got:", d)
+	}
+	if d := h.requestTimeout(time.Second); d != time.Second {
+		t.Error("expect the api timeout,got:", d)
+	}
+	h = &Host{ConnectTimeoutMs: 100, TimeoutMs: 3000}
+	if d := h.dialer(time.Second).Timeout; d != 100*time.Millisecond {
+		t.Error("expect connect_timeout_ms,got:", d)
+	}
+	if d := h.requestTimeout(time.Second); d != 3*time.Second {
+		t.Error("expect timeout_ms of host,got:", d)
+	}
+}
+
+func Test_HandlerHostTotalTimeout(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	//connects and sends the header at once,but the body is slow
+	slowBody := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("head"))
+		rw.(http.Flusher).Flush()
+		select {
+		case <-time.After(2 * time.Second):
+		case <-req.Context().Done():
+		}
+		rw.Write([]byte("tail"))
+	}))
+	defer slowBody.Close()
+	//the header is slower than the api's timeout,but in the host's
+	slowHeader := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		rw.Write([]byte("ok"))
+	}))
+	defer slowHeader.Close()
+
+	testLoadAPI(t, apiServer, "tb", `{"path":"/tb/","enable":true,"timeout_ms":5000,
+		"hosts":{"h1":{"url":"`+slowBody.URL+`/","enable":true,"timeout_ms":200}}}`)
+	testLoadAPI(t, apiServer, "th", `{"path":"/th/","enable":true,"timeout_ms":100,
+		"hosts":{"h1":{"url":"`+slowHeader.URL+`/","enable":true,"connect_timeout_ms":100,"timeout_ms":2000}}}`)
+	ts := testServe(t, apiServer)
+
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/tb/")
+	if err == nil {
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expect the slow body to be cut off")
+	}
+	if used := time.Since(start); used > time.Second {
+		t.Error("expect cut off by the host's timeout_ms,used:", used)
+	}
+
+	if resp, body := testGet(t, ts.URL+"/th/"); resp.StatusCode != 200 || body != "ok" {
+		t.Error("expect the host's timeout_ms to replace the api's,got:", resp.StatusCode, body)
+	}
+}
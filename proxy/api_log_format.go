@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// formats of the access log line
+const (
+	logFormatCommon   = "common"   //Common Log Format
+	logFormatCombined = "combined" //Combined Log Format,the common one with referer and user-agent
+)
+
+const logFormatTime = "02/Jan/2006:15:04:05 -0700"
+
+func checkLogFormat(format string) error {
+	switch format {
+	case "", logFormatCommon, logFormatCombined:
+		return nil
+	}
+	return fmt.Errorf("log_format wrong:%s", format)
+}
+
+// statusWriter record the status and the size of the response sent to the client
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Unwrap used by http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+var logFieldReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// logField quoted value,"-" when empty
+func logField(val string) string {
+	if val == "" {
+		return `"-"`
+	}
+	return `"` + logFieldReplacer.Replace(val) + `"`
+}
+
+// formatAccessLog the line of the common/combined log format:
+// remote - - [time] "METHOD uri proto" status bytes "referer" "user-agent"
+func formatAccessLog(format string, req *http.Request, w *statusWriter, start time.Time) string {
+	remote, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remote = req.RemoteAddr
+	}
+	uri := req.RequestURI
+	if uri == "" {
+		uri = req.URL.RequestURI()
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	size := "-"
+	if w.size > 0 {
+		size = fmt.Sprintf("%d", w.size)
+	}
+	line := fmt.Sprintf("%s - - [%s] %s %d %s", remote, start.Format(logFormatTime), logField(req.Method+" "+uri+" "+req.Proto), status, size)
+	if format == logFormatCombined {
+		line += " " + logField(req.Referer()) + " " + logField(req.UserAgent())
+	}
+	return line + "\n"
+}
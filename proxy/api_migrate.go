@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+)
+
+// apiSchemaVersion the version of the api conf format,
+// add a migration when the old confs need to be changed
+const apiSchemaVersion = 2
+
+// apiMigrations upgrade the conf from version n to n+1
+var apiMigrations = map[int]func(api *apiStruct){
+	1: migrateAPIV1,
+}
+
+// migrateAPIV1 the v1 confs rely on the defaults in code,write them out
+func migrateAPIV1(api *apiStruct) {
+	if api.Path == "" {
+		api.Path = fmt.Sprintf("/%s/", api.ID)
+	}
+	if api.TimeoutMs < 1 {
+		api.TimeoutMs = 5000
+	}
+	if api.TrailingSlash == "" {
+		api.TrailingSlash = trailingSlashStrict
+	}
+}
+
+// migrate upgrade the conf to the current version,return whether it is changed.
+// the confs without schema_version are v1
+func (api *apiStruct) migrate() (bool, error) {
+	from := api.SchemaVersion
+	if from < 1 {
+		from = 1
+	}
+	if from > apiSchemaVersion {
+		return false, fmt.Errorf("schema_version %d is newer than %d,upgrade api-front first", from, apiSchemaVersion)
+	}
+	for v := from; v < apiSchemaVersion; v++ {
+		if fn, has := apiMigrations[v]; has {
+			fn(api)
+		}
+	}
+	migrated := api.SchemaVersion != apiSchemaVersion
+	if migrated {
+		log.Println("[info]api [", api.ID, "] conf migrated from schema version", from, "to", apiSchemaVersion)
+	}
+	api.SchemaVersion = apiSchemaVersion
+	return migrated, nil
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+const testAPIConfV1 = `{"enable":true,"hosts":{"a":{"url":"http://127.0.0.1/","enable":true}}}`
+
+func Test_APIMigrateV1(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "old", testAPIConfV1)
+	if api.SchemaVersion != apiSchemaVersion {
+		t.Error("expect schema_version", apiSchemaVersion, "got:", api.SchemaVersion)
+	}
+	if api.Path != "/old/" || api.TimeoutMs != 5000 || api.TrailingSlash != trailingSlashStrict {
+		t.Error("defaults not migrated:", api.Path, api.TimeoutMs, api.TrailingSlash)
+	}
+	//not rewrite by default
+	data, _ := ioutil.ReadFile(api.ConfPath)
+	if string(data) != testAPIConfV1 {
+		t.Error("conf rewritten:", string(data))
+	}
+
+	apiServer.ServerVhostConf.RewriteMigrated = true
+	api = testLoadAPI(t, apiServer, "old", testAPIConfV1)
+	data, _ = ioutil.ReadFile(api.ConfPath)
+	var saved apiStruct
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.SchemaVersion != apiSchemaVersion || saved.Path != "/old/" || saved.TimeoutMs != 5000 {
+		t.Error("conf not rewritten:", string(data))
+	}
+	//already the current version,no more rewrite
+	saveVersion := api.Version
+	if err := apiServer.loadAPI("old"); err != nil {
+		t.Fatal(err)
+	}
+	if v := apiServer.getAPIByID("old").Version; v != saveVersion {
+		t.Error("expect version", saveVersion, "got:", v)
+	}
+}
+
+func Test_APIMigrateNewer(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := newAPI(apiServer, "new")
+	api.SchemaVersion = apiSchemaVersion + 1
+	if _, err := api.migrate(); err == nil {
+		t.Error("expect error for newer schema_version")
+	}
+}
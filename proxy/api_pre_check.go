@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// modes of the reachability pre-check when the api is loaded
+const (
+	preCheckWarn   = "warn"   //log a warning when no host is reachable
+	preCheckStrict = "strict" //do not enable the api when no host is reachable
+)
+
+const preCheckDefaultTimeoutMs = 1000
+
+func (api *apiStruct) initPreCheck() error {
+	switch api.PreCheck {
+	case "", preCheckWarn, preCheckStrict:
+	default:
+		return fmt.Errorf("pre_check wrong:%s", api.PreCheck)
+	}
+	if api.PreCheckTimeoutMs < 1 {
+		api.PreCheckTimeoutMs = preCheckDefaultTimeoutMs
+	}
+	return nil
+}
+
+// hostDialAddr host:port of the url,the default port of the scheme when not set
+func hostDialAddr(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("url has no host:%s", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// preCheck connect to the enabled hosts at the same time,
+// return nil when any of them is reachable
+func (api *apiStruct) preCheck() error {
+	var addrs []string
+	for _, apiHost := range api.Hosts {
+		if !apiHost.Enable {
+			continue
+		}
+		addr, err := hostDialAddr(api.hostURL(apiHost))
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no enabled hosts")
+	}
+	timeout := time.Duration(api.PreCheckTimeoutMs) * time.Millisecond
+	errs := make(chan error, len(addrs))
+	for _, addr := range addrs {
+		go (func(addr string) {
+			conn, err := net.DialTimeout("tcp", addr, timeout)
+			if err == nil {
+				conn.Close()
+			}
+			errs <- err
+		})(addr)
+	}
+	var lastErr error
+	for range addrs {
+		err := <-errs
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("no host reachable,last error:%s", lastErr)
+}
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"net"
+	"testing"
+)
+
+// testClosedAddr an address nothing listens on
+func testClosedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func Test_APIPreCheck(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	closedAddr := testClosedAddr(t)
+
+	//one of the hosts is reachable
+	testLoadAPI(t, apiServer, "up", `{"path":"/up/","enable":true,"pre_check":"strict","hosts":{
+		"h1":{"url":"http://`+closedAddr+`/","enable":true},
+		"h2":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "down", `{"path":"/down/","enable":true,"pre_check":"strict","pre_check_timeout_ms":200,"hosts":{
+		"h1":{"url":"http://`+closedAddr+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "warn", `{"path":"/warn/","enable":true,"pre_check":"warn","pre_check_timeout_ms":200,"hosts":{
+		"h1":{"url":"http://`+closedAddr+`/","enable":true}}}`)
+
+	cases := map[string]bool{
+		"/up/":   true,
+		"/down/": false,
+		"/warn/": true,
+	}
+	for urlPath, bound := range cases {
+		if item := apiServer.routers.getRouterByReqPath(urlPath); (item != nil) != bound {
+			t.Error(urlPath, "expect bound:", bound, "got:", item)
+		}
+	}
+	//the api is kept,so it can be fixed in the admin
+	if apiServer.getAPIByID("down") == nil {
+		t.Error("the api not reachable should be loaded")
+	}
+}
+
+func Test_APIPreCheckWrong(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.PreCheck = "always"
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong pre_check")
+	}
+}
+
+func Test_HostDialAddr(t *testing.T) {
+	cases := map[string]string{
+		"http://127.0.0.1/a":        "127.0.0.1:80",
+		"https://example.com/":      "example.com:443",
+		"http://example.com:8080/b": "example.com:8080",
+		"http://[::1]/":             "[::1]:80",
+	}
+	for rawURL, expect := range cases {
+		if addr, err := hostDialAddr(rawURL); err != nil || addr != expect {
+			t.Error(rawURL, "expect:", expect, "got:", addr, err)
+		}
+	}
+	if _, err := hostDialAddr("/no/host"); err == nil {
+		t.Error("expect error for url without host")
+	}
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errQueueFull    = errors.New("too many requests,queue is full")
+	errQueueTimeout = errors.New("too many requests,wait in queue timeout")
+	errQueueCancel  = errors.New("request canceled while waiting in queue")
+)
+
+// slotLimiter limit the concurrent requests of one api,
+// the key is the caller of the request
+type slotLimiter interface {
+	acquire(key string, cancel <-chan struct{}, onWait func(delta int64)) error
+	release()
+}
+
+// apiLimiter the others wait in a bounded queue
+type apiLimiter struct {
+	slots     chan struct{}
+	waiting   int32
+	queueSize int32
+	timeout   time.Duration
+}
+
+func newAPILimiter(maxConcurrent int, queueSize int, timeout time.Duration) *apiLimiter {
+	return &apiLimiter{
+		slots:     make(chan struct{}, maxConcurrent),
+		queueSize: int32(queueSize),
+		timeout:   timeout,
+	}
+}
+
+// acquire get a slot,wait in queue when all slots are in use
+func (l *apiLimiter) acquire(key string, cancel <-chan struct{}, onWait func(delta int64)) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if atomic.AddInt32(&l.waiting, 1) > l.queueSize {
+		atomic.AddInt32(&l.waiting, -1)
+		return errQueueFull
+	}
+	onWait(1)
+	defer func() {
+		atomic.AddInt32(&l.waiting, -1)
+		onWait(-1)
+	}()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errQueueTimeout
+	case <-cancel:
+		return errQueueCancel
+	}
+}
+
+func (l *apiLimiter) release() {
+	<-l.slots
+}
+
+func (api *apiStruct) initLimiter() {
+	if api.MaxConcurrent < 1 {
+		api.limiter = nil
+		return
+	}
+	if api.QueueSize < 0 {
+		api.QueueSize = 0
+	}
+	if api.QueueTimeoutMs < 1 {
+		api.QueueTimeoutMs = api.TimeoutMs
+	}
+	timeout := time.Duration(api.QueueTimeoutMs) * time.Millisecond
+	if api.FairQueue {
+		api.limiter = newFairLimiter(api.MaxConcurrent, api.QueueSize, timeout)
+		return
+	}
+	api.limiter = newAPILimiter(api.MaxConcurrent, api.QueueSize, timeout)
+}
+
+// acquireSlot must call releaseSlot after success
+func (api *apiStruct) acquireSlot(req *http.Request) error {
+	if api.limiter == nil {
+		return nil
+	}
+	return api.limiter.acquire(api.apiServer.callerIP(req), req.Context().Done(), api.expvarQueueDepthAdd)
+}
+
+func (api *apiStruct) releaseSlot() {
+	if api.limiter != nil {
+		api.limiter.release()
+	}
+}
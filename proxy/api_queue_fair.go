@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// fairLimiter the waiting requests get the released slots round-robin by caller,
+// so one caller can not take all of them during a spike
+type fairLimiter struct {
+	mu        sync.Mutex
+	max       int
+	inUse     int
+	queueSize int
+	waiting   int
+	timeout   time.Duration
+	queues    map[string][]*fairWaiter //the waiting requests of each caller,FIFO
+	keys      []string                 //the callers with waiting requests
+	next      int                      //the position in keys to get the next slot
+}
+
+type fairWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newFairLimiter(maxConcurrent int, queueSize int, timeout time.Duration) *fairLimiter {
+	return &fairLimiter{
+		max:       maxConcurrent,
+		queueSize: queueSize,
+		timeout:   timeout,
+		queues:    make(map[string][]*fairWaiter),
+	}
+}
+
+func (l *fairLimiter) acquire(key string, cancel <-chan struct{}, onWait func(delta int64)) error {
+	l.mu.Lock()
+	if l.inUse < l.max && l.waiting == 0 {
+		l.inUse++
+		l.mu.Unlock()
+		return nil
+	}
+	if l.waiting >= l.queueSize {
+		l.mu.Unlock()
+		return errQueueFull
+	}
+	w := &fairWaiter{ready: make(chan struct{})}
+	if len(l.queues[key]) == 0 {
+		l.keys = append(l.keys, key)
+	}
+	l.queues[key] = append(l.queues[key], w)
+	l.waiting++
+	l.mu.Unlock()
+
+	onWait(1)
+	defer onWait(-1)
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-cancel:
+		err = errQueueCancel
+	}
+	if !l.remove(key, w) {
+		//the slot is granted at the same time,pass it on
+		l.release()
+	}
+	return err
+}
+
+// remove the waiter gave up,false when it has been granted a slot
+func (l *fairLimiter) remove(key string, w *fairWaiter) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		return false
+	}
+	ws := l.queues[key]
+	for i, item := range ws {
+		if item == w {
+			l.queues[key] = append(ws[:i], ws[i+1:]...)
+			break
+		}
+	}
+	l.waiting--
+	if len(l.queues[key]) == 0 {
+		l.removeKey(key)
+	}
+	return true
+}
+
+func (l *fairLimiter) removeKey(key string) {
+	delete(l.queues, key)
+	for i, k := range l.keys {
+		if k == key {
+			l.keys = append(l.keys[:i], l.keys[i+1:]...)
+			if i < l.next {
+				l.next--
+			}
+			break
+		}
+	}
+	if l.next >= len(l.keys) {
+		l.next = 0
+	}
+}
+
+// release hand the slot to the next caller's first waiting request
+func (l *fairLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.keys) == 0 {
+		l.inUse--
+		return
+	}
+	key := l.keys[l.next]
+	w := l.queues[key][0]
+	l.queues[key] = l.queues[key][1:]
+	l.waiting--
+	w.granted = true
+	close(w.ready)
+	if len(l.queues[key]) == 0 {
+		l.removeKey(key)
+	} else {
+		l.next = (l.next + 1) % len(l.keys)
+	}
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBlockBackend the requests block until release is closed
+func testBlockBackend(t *testing.T) (ts *httptest.Server, entered chan bool, release chan bool) {
+	entered = make(chan bool, 10)
+	release = make(chan bool)
+	ts = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		entered <- true
+		<-release
+		rw.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, entered, release
+}
+
+func testWaitQueueDepth(t *testing.T, key string, depth int64) {
+	for i := 0; i < 100; i++ {
+		if testExpvarValue(expvarQueueDepth, key) == depth {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("queue depth of", key, "expect", depth, "got:", testExpvarValue(expvarQueueDepth, key))
+}
+
+func Test_HandlerQueue(t *testing.T) {
+	backend, entered, release := testBlockBackend(t)
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "q", `{"path":"/q/","enable":true,"max_concurrent":1,"queue_size":1,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	codes := make(chan int, 2)
+	get := func() {
+		resp, err := http.Get(ts.URL + "/q/a")
+		if err != nil {
+			codes <- 0
+			return
+		}
+		resp.Body.Close()
+		codes <- resp.StatusCode
+	}
+	go get()
+	<-entered
+	go get()
+	testWaitQueueDepth(t, "test/q", 1)
+
+	resp, body := testGet(t, ts.URL+"/q/a")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expect 503 when queue is full,got:", resp.StatusCode, body)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != 200 {
+			t.Error("expect 200,got:", code)
+		}
+	}
+	testWaitQueueDepth(t, "test/q", 0)
+}
+
+func Test_HandlerQueueTimeout(t *testing.T) {
+	backend, entered, release := testBlockBackend(t)
+	defer close(release)
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "qt", `{"path":"/qt/","enable":true,"max_concurrent":1,"queue_size":5,"queue_timeout_ms":100,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	go http.Get(ts.URL + "/qt/a")
+	<-entered
+
+	start := time.Now()
+	resp, body := testGet(t, ts.URL+"/qt/a")
+	used := time.Since(start)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expect 503 after wait timeout,got:", resp.StatusCode, body)
+	}
+	if used < 100*time.Millisecond || used > time.Second {
+		t.Error("wrong wait time:", used)
+	}
+}
+
+func Test_HandlerFairQueue(t *testing.T) {
+	var mu sync.Mutex
+	var callers []string
+	entered := make(chan bool, 10)
+	release := make(chan bool)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		callers = append(callers, req.Header.Get("X-Real-Ip"))
+		mu.Unlock()
+		entered <- true
+		<-release
+		rw.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "fq", `{"path":"/fq/","enable":true,"max_concurrent":1,"queue_size":10,"fair_queue":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	var wg sync.WaitGroup
+	get := func(ip string) {
+		wg.Add(1)
+		go (func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", ts.URL+"/fq/a", nil)
+			req.Header.Set("X-Real-Ip", ip)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Error("expect 200,got:", resp.StatusCode)
+			}
+		})()
+	}
+	//the noisy caller takes the slot and queues 4 more,then the other one comes
+	get("10.0.0.1")
+	<-entered
+	for i := 1; i <= 4; i++ {
+		get("10.0.0.1")
+		testWaitQueueDepth(t, "test/fq", int64(i))
+	}
+	for i := 5; i <= 6; i++ {
+		get("10.0.0.2")
+		testWaitQueueDepth(t, "test/fq", int64(i))
+	}
+	for i := 0; i < 6; i++ {
+		release <- true
+		<-entered
+	}
+	release <- true
+	wg.Wait()
+
+	expect := "10.0.0.1,10.0.0.1,10.0.0.2,10.0.0.1,10.0.0.2,10.0.0.1,10.0.0.1"
+	if got := strings.Join(callers, ","); got != expect {
+		t.Error("expect round-robin between callers:\n", expect, "\ngot:\n", got)
+	}
+	testWaitQueueDepth(t, "test/fq", 0)
+}
+
+func Test_FairLimiterGiveUp(t *testing.T) {
+	l := newFairLimiter(1, 2, 50*time.Millisecond)
+	onWait := func(int64) {}
+	if err := l.acquire("a", nil, onWait); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire("b", nil, onWait); err != errQueueTimeout {
+		t.Error("expect timeout,got:", err)
+	}
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := l.acquire("b", cancel, onWait); err != errQueueCancel {
+		t.Error("expect cancel,got:", err)
+	}
+	if l.waiting != 0 || len(l.keys) != 0 {
+		t.Error("the waiters gave up should be removed:", l.waiting, l.keys)
+	}
+	l.release()
+	if err := l.acquire("b", nil, onWait); err != nil {
+		t.Error("the slot should be free,got:", err)
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// quotaCounter the request+response bytes used today,reset daily.
+// saved to file,so it is kept across restarts
+type quotaCounter struct {
+	Day       string           `json:"day"`
+	Used      map[string]int64 `json:"used"`
+	rw        sync.RWMutex
+	filePath  string
+	lastMod   time.Time
+	lastWrite time.Time
+	now       func() time.Time
+}
+
+func newQuotaCounter(filePath string) *quotaCounter {
+	var qc *quotaCounter
+	if err := LoadJSONFile(filePath, &qc); err != nil || qc == nil {
+		qc = new(quotaCounter)
+	}
+	qc.filePath = filePath
+	qc.now = time.Now
+	if qc.Used == nil {
+		qc.Used = make(map[string]int64)
+	}
+	qc.lastMod = time.Now()
+	qc.lastWrite = qc.lastMod
+	return qc
+}
+
+// checkDay reset when it's a new day,must be called with the lock
+func (qc *quotaCounter) checkDay() {
+	day := qc.now().Format("20060102")
+	if qc.Day != day {
+		qc.Day = day
+		qc.Used = make(map[string]int64)
+		qc.lastMod = time.Now()
+	}
+}
+
+// remaining bytes of today
+func (qc *quotaCounter) remaining(key string, limit int64) int64 {
+	qc.rw.Lock()
+	defer qc.rw.Unlock()
+	qc.checkDay()
+	return limit - qc.Used[key]
+}
+
+func (qc *quotaCounter) add(key string, n int64) {
+	qc.rw.Lock()
+	defer qc.rw.Unlock()
+	qc.checkDay()
+	qc.Used[key] += n
+	qc.lastMod = time.Now()
+}
+
+// AutoSave auto save to file
+func (qc *quotaCounter) AutoSave(sec int64) {
+	t := time.NewTicker(time.Duration(sec) * time.Second)
+	for range t.C {
+		qc.rw.RLock()
+		changed := qc.lastWrite.Before(qc.lastMod)
+		qc.rw.RUnlock()
+		if changed {
+			qc.SaveFile()
+		}
+	}
+}
+
+// SaveFile save to file
+func (qc *quotaCounter) SaveFile() error {
+	qc.rw.Lock()
+	defer qc.rw.Unlock()
+	data, err := json.MarshalIndent(qc, "", "  ")
+	if err != nil {
+		return err
+	}
+	qc.lastWrite = time.Now()
+	if err = ioutil.WriteFile(qc.filePath, data, 0666); err != nil {
+		log.Println("[error]save quota file failed:", qc.filePath, err)
+	}
+	return err
+}
+
+// quotaKey the quota is for each caller ip when quota_per_caller,
+// not for the caller rule,which may be a cidr
+func (api *apiStruct) quotaKey(callerIP string) string {
+	if api.QuotaPerCaller {
+		return api.ID + "|" + callerIP
+	}
+	return api.ID
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HandlerDailyQuota(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	day := time.Date(2016, 5, 1, 23, 0, 0, 0, time.Local)
+	apiServer.quota.now = func() time.Time {
+		return day
+	}
+	backend := testBackend(t, "hello")
+	testLoadAPI(t, apiServer, "qa", `{"path":"/qa/","enable":true,"daily_byte_quota":10,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	cases := []struct {
+		status    int
+		remaining string
+	}{
+		{200, "10"},
+		{200, "5"},
+		{http.StatusTooManyRequests, "0"},
+	}
+	for i, c := range cases {
+		resp, _ := testGet(t, ts.URL+"/qa/a")
+		if resp.StatusCode != c.status || resp.Header.Get("Api-Front-Quota-Remaining") != c.remaining {
+			t.Fatal("request", i, "expect:", c.status, c.remaining, "got:", resp.StatusCode, resp.Header.Get("Api-Front-Quota-Remaining"))
+		}
+	}
+
+	//the counter is kept after restart
+	apiServer.quota.SaveFile()
+	qc := newQuotaCounter(apiServer.quota.filePath)
+	qc.now = apiServer.quota.now
+	if n := qc.remaining("qa", 10); n != 0 {
+		t.Error("expect 0 remaining after reload,got:", n)
+	}
+
+	//next day
+	day = day.Add(2 * time.Hour)
+	resp, _ := testGet(t, ts.URL+"/qa/a")
+	if resp.StatusCode != 200 || resp.Header.Get("Api-Front-Quota-Remaining") != "10" {
+		t.Error("quota should be reset,got:", resp.StatusCode, resp.Header.Get("Api-Front-Quota-Remaining"))
+	}
+}
+
+func Test_QuotaPerCaller(t *testing.T) {
+	qc := newQuotaCounter(filepath.Join(t.TempDir(), "_quota.json"))
+	api := &apiStruct{ID: "a", QuotaPerCaller: true}
+	k1 := api.quotaKey("10.0.0.1")
+	k2 := api.quotaKey("10.0.0.2")
+	qc.add(k1, 8)
+	if qc.remaining(k1, 10) != 2 || qc.remaining(k2, 10) != 10 {
+		t.Error("quota should be counted for each caller")
+	}
+}
+
+func Test_HandlerQuotaPerCallerIP(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "hello")
+	testLoadAPI(t, apiServer, "qc", `{"path":"/qc/","enable":true,"daily_byte_quota":5,"quota_per_caller":true,
+		"caller":[{"ip":"10.0.0.0/8","enable":true}],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(ip string) int {
+		req, _ := http.NewRequest("GET", ts.URL+"/qc/a", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get("10.0.0.1"); code != 200 {
+		t.Fatal("expect 200,got:", code)
+	}
+	if code := get("10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Error("expect 429 for the used up ip,got:", code)
+	}
+	//the same caller rule,but another ip
+	if code := get("10.0.0.2"); code != 200 {
+		t.Error("expect 200 for another ip of the rule,got:", code)
+	}
+}
+
+func Test_HandlerQuotaNotChargedRejected(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "qr", `{"path":"/qr/","enable":true,"daily_byte_quota":10,
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":false}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, err := http.Post(ts.URL+"/qr/a", "text/plain", strings.NewReader("12345"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatal("expect 502 for no backend hosts,got:", resp.StatusCode)
+	}
+	if n := apiServer.quota.remaining("qr", 10); n != 10 {
+		t.Error("the rejected request should not be charged,remaining:", n)
+	}
+}
+
+func Test_ManagerSaveQuotas(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	manager := apiServer.manager
+	manager.ps = &portServerManager{
+		PortServerMap: map[int]*portServer{
+			8080: {Port: 8080, APIServiers: map[string]*APIServer{"test": apiServer}},
+		},
+		manager: manager,
+	}
+	apiServer.quota.add("qs", 8)
+	manager.saveQuotas()
+	if n := newQuotaCounter(apiServer.quota.filePath).remaining("qs", 10); n != 2 {
+		t.Error("expect the quota saved,remaining:", n)
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReloadHookConf called after the api is loaded,eg warm a cache,notify the others
+type ReloadHookConf struct {
+	URL       string `json:"url"`        //POST reloadEvent(json) 到该地址,返回非2xx为失败
+	Command   string `json:"command"`    //执行该命令(按空格分割参数),stdin为reloadEvent(json),url和command只能设置一个
+	TimeoutMs int    `json:"timeout_ms"` //默认3000
+}
+
+func (c *ReloadHookConf) init() error {
+	if (c.URL == "") == (c.Command == "") {
+		return fmt.Errorf("one of url and command is required")
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("url wrong:%s", c.URL)
+		}
+	}
+	if c.Command != "" && len(strings.Fields(c.Command)) == 0 {
+		return fmt.Errorf("command wrong:%q", c.Command)
+	}
+	if c.TimeoutMs < 1 {
+		c.TimeoutMs = 3000
+	}
+	return nil
+}
+
+// reloadEvent the summary of the reload sent to the hook
+type reloadEvent struct {
+	Server     string   `json:"server"`
+	API        string   `json:"api"`
+	Version    int64    `json:"version"`
+	OldVersion int64    `json:"old_version"`
+	New        bool     `json:"new"`     //first loaded,no old conf
+	Changed    []string `json:"changed"` //the changed conf fields
+	Enable     bool     `json:"enable"`
+	Path       string   `json:"path"`
+}
+
+// newReloadEvent compare the conf of the loaded api with the old one
+func newReloadEvent(apiOld, api *apiStruct) *reloadEvent {
+	ev := &reloadEvent{
+		Server:  api.apiServer.GetServerID(),
+		API:     api.ID,
+		Version: api.Version,
+		New:     apiOld == nil,
+		Changed: []string{},
+		Enable:  api.Enable,
+		Path:    api.Path,
+	}
+	if apiOld == nil {
+		return ev
+	}
+	ev.OldVersion = apiOld.Version
+	oldConf, newConf := apiOld.confFields(), api.confFields()
+	for k, v := range newConf {
+		if k != "version" && !reflect.DeepEqual(oldConf[k], v) {
+			ev.Changed = append(ev.Changed, k)
+		}
+	}
+	for k := range oldConf {
+		if _, has := newConf[k]; !has {
+			ev.Changed = append(ev.Changed, k)
+		}
+	}
+	sort.Strings(ev.Changed)
+	return ev
+}
+
+// confFields the top level fields of the conf json
+func (api *apiStruct) confFields() map[string]interface{} {
+	api.rw.RLock()
+	data, _ := json.Marshal(api)
+	api.rw.RUnlock()
+	fields := make(map[string]interface{})
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// runReloadHook call the hook,the failures are logged only
+func (api *apiStruct) runReloadHook(ev *reloadEvent) {
+	hook := api.ReloadHook
+	data, _ := json.Marshal(ev)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(hook.TimeoutMs)*time.Millisecond)
+	defer cancel()
+	var err error
+	if hook.URL != "" {
+		err = hook.post(ctx, data)
+	} else {
+		err = hook.exec(ctx, ev, data)
+	}
+	if err != nil {
+		log.Println("[warning]api reload hook failed:", api.ID, err)
+		return
+	}
+	log.Println("api reload hook success:", api.ID, "changed:", ev.Changed)
+}
+
+func (c *ReloadHookConf) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequest("POST", c.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bd, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status:%d,body:%q", resp.StatusCode, bodySnippet(bd, 0))
+	}
+	return nil
+}
+
+func (c *ReloadHookConf) exec(ctx context.Context, ev *reloadEvent, data []byte) error {
+	args := strings.Fields(c.Command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"API_FRONT_API="+ev.API,
+		fmt.Sprintf("API_FRONT_VERSION=%d", ev.Version),
+		"API_FRONT_CHANGED="+strings.Join(ev.Changed, ","),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s,output:%q", err, bodySnippet(out, 0))
+	}
+	return nil
+}
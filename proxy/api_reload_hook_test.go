@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_APIReloadHookURL(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	events := make(chan *reloadEvent, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var ev *reloadEvent
+		json.NewDecoder(req.Body).Decode(&ev)
+		events <- ev
+	}))
+	defer hook.Close()
+	waitEvent := func() *reloadEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(3 * time.Second):
+			t.Fatal("reload hook not called")
+		}
+		return nil
+	}
+
+	conf := `{"path":"/rh/","enable":true,"note":"%s","reload_hook":{"url":"` + hook.URL + `/"},
+		"hosts":{"h1":{"url":"http://127.0.0.1/","enable":true}}}`
+	testLoadAPI(t, apiServer, "rh", fmt.Sprintf(conf, "v1"))
+	ev := waitEvent()
+	if !ev.New || ev.API != "rh" || ev.Path != "/rh/" || ev.Server != "test" {
+		t.Error("first load event wrong:", ev)
+	}
+
+	testLoadAPI(t, apiServer, "rh", fmt.Sprintf(conf, "v2"))
+	ev = waitEvent()
+	if ev.New || !reflect.DeepEqual(ev.Changed, []string{"note"}) {
+		t.Error("reload event wrong:", ev)
+	}
+}
+
+func Test_APIReloadHookCommand(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	out := filepath.Join(apiServer.getConfDir(), "hook_out.json")
+	testLoadAPI(t, apiServer, "rc", `{"path":"/rc/","enable":true,"reload_hook":{"command":"tee `+out+`"},
+		"hosts":{"h1":{"url":"http://127.0.0.1/","enable":true}}}`)
+	var data []byte
+	for i := 0; i < 100 && len(data) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ = ioutil.ReadFile(out)
+	}
+	var ev *reloadEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.API != "rc" {
+		t.Error("the event expected in stdin of the command,got:", string(data), err)
+	}
+}
+
+func Test_APIReloadHookFailed(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	//the failed hook does not fail the loading
+	api := testLoadAPI(t, apiServer, "rf", `{"path":"/rf/","enable":true,"reload_hook":{"url":"http://127.0.0.1:1/"},
+		"hosts":{"h1":{"url":"http://127.0.0.1/","enable":true}}}`)
+	if api == nil {
+		t.Fatal("api not loaded")
+	}
+
+	api = apiServer.newAPI("wrong")
+	api.ReloadHook = &ReloadHookConf{URL: "http://127.0.0.1/", Command: os.Args[0]}
+	if err := api.init(); err == nil {
+		t.Error("expect error with both url and command")
+	}
+}
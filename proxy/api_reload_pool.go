@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"sync"
+)
+
+// defaultMaxConcurrentReloads how many apis are loaded at the same time by default
+const defaultMaxConcurrentReloads = 4
+
+// reloadPool bound the parallel loading of the apis(eg a bulk import),
+// the loads of the same api run one by one,so the last change wins
+type reloadPool struct {
+	sem   chan struct{}
+	mu    sync.Mutex
+	names map[string]*sync.Mutex
+}
+
+func newReloadPool(size int) *reloadPool {
+	if size < 1 {
+		size = defaultMaxConcurrentReloads
+	}
+	return &reloadPool{
+		sem:   make(chan struct{}, size),
+		names: make(map[string]*sync.Mutex),
+	}
+}
+
+func (p *reloadPool) nameLock(apiName string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lock, has := p.names[apiName]
+	if !has {
+		lock = &sync.Mutex{}
+		p.names[apiName] = lock
+	}
+	return lock
+}
+
+// do run fn when a worker is free and no other load of the api is running
+func (p *reloadPool) do(apiName string, fn func()) {
+	lock := p.nameLock(apiName)
+	lock.Lock()
+	defer lock.Unlock()
+	p.sem <- struct{}{}
+	defer func() {
+		<-p.sem
+	}()
+	fn()
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowConfigSource count the concurrent loads
+type slowConfigSource struct {
+	ConfigSource
+	running int64
+	max     int64
+}
+
+func (s *slowConfigSource) Get(apiID string) ([]byte, error) {
+	n := atomic.AddInt64(&s.running, 1)
+	defer atomic.AddInt64(&s.running, -1)
+	for {
+		max := atomic.LoadInt64(&s.max)
+		if n <= max || atomic.CompareAndSwapInt64(&s.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return s.ConfigSource.Get(apiID)
+}
+
+func Test_APIReloadPool(t *testing.T) {
+	store := NewMemoryKVStore()
+	prefix := "/api-front/test/"
+	const total = 12
+	for i := 0; i < total; i++ {
+		store.Set(fmt.Sprintf("%sbulk_%d", prefix, i), []byte(fmt.Sprintf(`{"path":"/bulk_%d/","enable":true,"hosts":{}}`, i)))
+	}
+	source := &slowConfigSource{ConfigSource: NewKVConfigSource(store, prefix)}
+	manager := &APIServerManager{
+		ConfPath: filepath.Join(t.TempDir(), "server.json"),
+		mainConf: &mainConf{Users: users{"admin"}},
+		ConfigSourceFunc: func(serverID string, confDir string) ConfigSource {
+			return source
+		},
+	}
+	apiServer, err := newAPIServer(&serverVhost{Id: "test", Port: 8080, Enable: true, Users: NewUsers(), MaxConcurrentReloads: 3}, manager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apiServer.Apis) != total {
+		t.Fatal("expect all apis loaded,got:", len(apiServer.Apis))
+	}
+	if max := atomic.LoadInt64(&source.max); max > 3 || max < 2 {
+		t.Error("expect loads bounded by 3 and in parallel,max:", max)
+	}
+
+	//the changes of a bulk import
+	atomic.StoreInt64(&source.max, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go (func(i int) {
+			defer wg.Done()
+			apiServer.onConfChange(fmt.Sprintf("bulk_%d", i))
+		})(i)
+	}
+	wg.Wait()
+	if max := atomic.LoadInt64(&source.max); max > 3 {
+		t.Error("expect reloads bounded by 3,max:", max)
+	}
+	if len(apiServer.Apis) != total {
+		t.Error("expect all apis loaded,got:", len(apiServer.Apis))
+	}
+}
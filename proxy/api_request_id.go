@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requestIDMaxLen the inbound ids longer than it are replaced,they are written to the logs
+const requestIDMaxLen = 128
+
+// requestID the id under the request_id_header of the server,
+// the inbound one is kept,otherwise uniqID is set to the request,
+// name is empty when not configured
+func (apiServer *APIServer) requestID(req *http.Request, uniqID string) (name string, id string) {
+	name = apiServer.ServerVhostConf.RequestIDHeader
+	if name == "" {
+		return "", ""
+	}
+	id = strings.TrimSpace(req.Header.Get(name))
+	if id == "" || len(id) > requestIDMaxLen || strings.ContainsAny(id, " \t\r\n") {
+		id = uniqID
+	}
+	req.Header.Set(name, id)
+	return name, id
+}
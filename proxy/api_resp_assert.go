@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// respAssertMaxBody max bytes of body kept for assertion
+const respAssertMaxBody = 1 << 20
+
+// RespAssert assertion on the master's response body,eg for synthetic monitoring.
+// a failed assertion is logged and counted,the response to client is not changed
+type RespAssert struct {
+	Contains string `json:"contains"`
+	Regexp   string `json:"regexp"`
+	reg      *regexp.Regexp
+}
+
+func (ra *RespAssert) init() (err error) {
+	if ra.Regexp != "" {
+		ra.reg, err = regexp.Compile(ra.Regexp)
+	}
+	return err
+}
+
+// check return nil when pass
+func (ra *RespAssert) check(resp *http.Response, body *bytes.Buffer) error {
+	content := body.String()
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		content = gzipDocode(bytes.NewBuffer(body.Bytes()))
+	}
+	if ra.Contains != "" && !strings.Contains(content, ra.Contains) {
+		return fmt.Errorf("body not contains %q", ra.Contains)
+	}
+	if ra.reg != nil && !ra.reg.MatchString(content) {
+		return fmt.Errorf("body not match %q", ra.Regexp)
+	}
+	return nil
+}
+
+// limitBuffer buffer which drop the bytes after max
+type limitBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (lb *limitBuffer) Write(p []byte) (int, error) {
+	if n := lb.max - lb.Len(); n > 0 {
+		if len(p) > n {
+			lb.Buffer.Write(p[:n])
+		} else {
+			lb.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
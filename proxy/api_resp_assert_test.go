@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"testing"
+)
+
+func Test_HandlerRespAssert(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, `{"message":{"ack":{"status":"ACK"}}}`)
+	hosts := `"hosts":{"h1":{"url":"` + backend.URL + `/","enable":true}}`
+	testLoadAPI(t, apiServer, "as_ok", `{"path":"/as_ok/","enable":true,"resp_assert":{"contains":"ACK"},`+hosts+`}`)
+	testLoadAPI(t, apiServer, "as_fail", `{"path":"/as_fail/","enable":true,"resp_assert":{"regexp":"\"status\":\"NACK\""},`+hosts+`}`)
+	ts := testServe(t, apiServer)
+
+	okBefore := testExpvarValue(expvarAssertFailures, "test/as_ok")
+	failBefore := testExpvarValue(expvarAssertFailures, "test/as_fail")
+	_, body := testGet(t, ts.URL+"/as_ok/")
+	if n := testExpvarValue(expvarAssertFailures, "test/as_ok") - okBefore; n != 0 {
+		t.Error("assertion should pass,failures:", n)
+	}
+
+	resp, body2 := testGet(t, ts.URL+"/as_fail/")
+	if n := testExpvarValue(expvarAssertFailures, "test/as_fail") - failBefore; n != 1 {
+		t.Error("assertion should fail,failures:", n)
+	}
+	if resp.StatusCode != 200 || body2 != body {
+		t.Error("client response should not change:", resp.StatusCode, body2)
+	}
+}
+
+func Test_RespAssertWrongRegexp(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.RespAssert = &RespAssert{Regexp: "("}
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong regexp")
+	}
+}
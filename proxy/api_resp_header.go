@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"net/http"
+)
+
+func (api *apiStruct) initRespHeaders() {
+	if len(api.RespHeaders) == 0 {
+		return
+	}
+	hs := make(map[string]string, len(api.RespHeaders))
+	for k, v := range api.RespHeaders {
+		hs[http.CanonicalHeaderKey(k)] = v
+	}
+	api.RespHeaders = hs
+}
+
+// setDefaultRespHeaders set before calling backend,
+// so the error responses have them too
+func (api *apiStruct) setDefaultRespHeaders(h http.Header) {
+	for k, v := range api.RespHeaders {
+		h.Set(k, v)
+	}
+}
+
+// copyRespHeaders copy the backend response headers,
+// the default ones are replaced by backend's unless resp_headers_override
+func (api *apiStruct) copyRespHeaders(dst, src http.Header) {
+	for k, vs := range src {
+		if _, has := api.RespHeaders[k]; has {
+			if api.RespHeadersOverride {
+				continue
+			}
+			dst.Del(k)
+		}
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+}
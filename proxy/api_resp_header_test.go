@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HandlerDefaultRespHeaders(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Cache-Control", "max-age=60")
+		rw.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	hosts := `"hosts":{"h1":{"url":"` + backend.URL + `/","enable":true}}`
+	headers := `"resp_headers":{"cache-control":"no-cache","X-ONDC-Proxy":"api-front"}`
+	testLoadAPI(t, apiServer, "rh", `{"path":"/rh/","enable":true,`+headers+`,`+hosts+`}`)
+	testLoadAPI(t, apiServer, "rh_ov", `{"path":"/rh_ov/","enable":true,"resp_headers_override":true,`+headers+`,`+hosts+`}`)
+	testLoadAPI(t, apiServer, "rh_bad", `{"path":"/rh_bad/","enable":true,"timeout_ms":200,`+headers+`,
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, _ := testGet(t, ts.URL+"/rh/")
+	if resp.Header.Get("X-Ondc-Proxy") != "api-front" {
+		t.Error("default header missing")
+	}
+	if vs := resp.Header["Cache-Control"]; len(vs) != 1 || vs[0] != "max-age=60" {
+		t.Error("backend header should win:", vs)
+	}
+
+	resp, _ = testGet(t, ts.URL+"/rh_ov/")
+	if vs := resp.Header["Cache-Control"]; len(vs) != 1 || vs[0] != "no-cache" {
+		t.Error("default header should override backend:", vs)
+	}
+
+	resp, _ = testGet(t, ts.URL+"/rh_bad/")
+	if resp.StatusCode != http.StatusBadGateway || resp.Header.Get("X-Ondc-Proxy") != "api-front" {
+		t.Error("error response should have default header too:", resp.StatusCode, resp.Header)
+	}
+}
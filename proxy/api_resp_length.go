@@ -0,0 +1,13 @@
+package proxy
+
+import (
+	"net/http"
+)
+
+// respLengthMismatch whether the copied bytes differ from the Content-Length declared by the backend
+func respLengthMismatch(resp *http.Response, n int64) bool {
+	if resp.ContentLength < 0 || resp.Request == nil || resp.Request.Method == "HEAD" {
+		return false
+	}
+	return n != resp.ContentLength
+}
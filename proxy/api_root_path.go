@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// root_path options,for the request to the api path itself,
+// eg /a/ or /a(trailing_slash is match) for api path /a/
+const (
+	rootPathKeep  = "keep"  //the host url as it is,default
+	rootPathSlash = "slash" //the host url with the trailing slash
+	rootPathBare  = "bare"  //the host url without the trailing slash
+)
+
+func (api *apiStruct) initRootPath() error {
+	switch api.RootPath {
+	case "":
+		api.RootPath = rootPathKeep
+	case rootPathKeep, rootPathSlash, rootPathBare:
+	default:
+		return fmt.Errorf("root_path wrong:%s", api.RootPath)
+	}
+	return nil
+}
+
+// joinHostURL the url of the host with the path relative to the api path
+func (api *apiStruct) joinHostURL(hostURL string, relPath string) string {
+	if relPath != "" {
+		if strings.HasSuffix(hostURL, "/") {
+			return hostURL + strings.TrimLeft(relPath, "/")
+		}
+		return hostURL + relPath
+	}
+	switch api.RootPath {
+	case rootPathSlash:
+		if !strings.HasSuffix(hostURL, "/") {
+			return hostURL + "/"
+		}
+	case rootPathBare:
+		return strings.TrimRight(hostURL, "/")
+	}
+	return hostURL
+}
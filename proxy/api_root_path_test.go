@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_HandlerRootPath(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.RequestURI()))
+	}))
+	defer backend.Close()
+	ts := testServe(t, apiServer)
+
+	hostPaths := []string{"/", "", "/x/", "/x"}
+	cases := []struct {
+		rootPath string
+		expect   []string
+	}{
+		{"", []string{"/", "/", "/x/", "/x"}},
+		{"slash", []string{"/", "/", "/x/", "/x/"}},
+		{"bare", []string{"/", "/", "/x", "/x"}},
+	}
+	for _, c := range cases {
+		for i, hostPath := range hostPaths {
+			testLoadAPI(t, apiServer, "root", `{"path":"/root/","enable":true,"trailing_slash":"match","root_path":"`+c.rootPath+`",
+				"hosts":{"h1":{"url":"`+backend.URL+hostPath+`","enable":true}}}`)
+			for _, reqPath := range []string{"/root/", "/root"} {
+				if _, body := testGet(t, ts.URL+reqPath); body != c.expect[i] {
+					t.Error("root_path:", c.rootPath, "host path:", hostPath, "request:", reqPath, "expect:", c.expect[i], "got:", body)
+				}
+			}
+			if _, body := testGet(t, ts.URL+"/root/?k=1"); body != c.expect[i]+"?k=1" {
+				t.Error("root_path:", c.rootPath, "host path:", hostPath, "query lost,got:", body)
+			}
+		}
+	}
+
+	//not the root,no double slash
+	testLoadAPI(t, apiServer, "root", `{"path":"/root/","enable":true,"root_path":"bare",
+		"hosts":{"h1":{"url":"`+backend.URL+`/x/","enable":true}}}`)
+	if _, body := testGet(t, ts.URL+"/root/a/b"); body != "/x/a/b" {
+		t.Error("expect /x/a/b,got:", body)
+	}
+}
+
+func Test_APIRootPathWrong(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.RootPath = "none"
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong root_path")
+	}
+}
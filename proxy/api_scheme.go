@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+func checkScheme(scheme string) error {
+	switch scheme {
+	case "", "http", "https":
+		return nil
+	}
+	return fmt.Errorf("unknow scheme:%s", scheme)
+}
+
+func (api *apiStruct) initScheme() error {
+	if err := checkScheme(api.Scheme); err != nil {
+		return fmt.Errorf("scheme wrong:%s", err)
+	}
+	for name, apiHost := range api.Hosts {
+		if err := checkScheme(apiHost.Scheme); err != nil {
+			return fmt.Errorf("host [%s] scheme wrong:%s", name, err)
+		}
+	}
+	return nil
+}
+
+// hostURL the url of the host with the scheme replaced,
+// the host's scheme first,then the api's,the url's own when both are empty
+func (api *apiStruct) hostURL(apiHost *Host) string {
+	scheme := apiHost.Scheme
+	if scheme == "" {
+		scheme = api.Scheme
+	}
+	pos := strings.Index(apiHost.URLStr, "://")
+	if scheme == "" || pos < 0 {
+		return apiHost.URLStr
+	}
+	return scheme + apiHost.URLStr[pos:]
+}
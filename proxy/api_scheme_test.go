@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_APIHostURLScheme(t *testing.T) {
+	api := &apiStruct{}
+	h := &Host{URLStr: "http://a.test/x/"}
+	if u := api.hostURL(h); u != "http://a.test/x/" {
+		t.Error("expect url not changed,got:", u)
+	}
+	api.Scheme = "https"
+	if u := api.hostURL(h); u != "https://a.test/x/" {
+		t.Error("expect api scheme,got:", u)
+	}
+	h.Scheme = "http"
+	if u := api.hostURL(h); u != "http://a.test/x/" {
+		t.Error("expect host scheme first,got:", u)
+	}
+
+	api = &apiStruct{Scheme: "ftp", Hosts: newHosts()}
+	if err := api.initScheme(); err == nil {
+		t.Error("expect error for wrong api scheme")
+	}
+	api.Scheme = ""
+	api.Hosts["h1"] = &Host{URLStr: "http://a.test/", Scheme: "ws"}
+	if err := api.initScheme(); err == nil || !strings.Contains(err.Error(), "h1") {
+		t.Error("expect error for wrong host scheme,got:", err)
+	}
+}
+
+func Test_HandlerSchemeOverride(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "plain")
+	//the url is https but the backend serves http only
+	httpsURL := strings.Replace(backend.URL, "http://", "https://", 1) + "/"
+	testLoadAPI(t, apiServer, "sch", `{"path":"/sch/","enable":true,
+		"hosts":{"h1":{"url":"`+httpsURL+`","enable":true,"scheme":"http"}}}`)
+	testLoadAPI(t, apiServer, "sch_api", `{"path":"/sch_api/","enable":true,"scheme":"http",
+		"hosts":{"h1":{"url":"`+httpsURL+`","enable":true}}}`)
+	testLoadAPI(t, apiServer, "sch_none", `{"path":"/sch_none/","enable":true,
+		"hosts":{"h1":{"url":"`+httpsURL+`","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	for _, id := range []string{"sch", "sch_api"} {
+		resp, body := testGet(t, ts.URL+"/"+id+"/a")
+		if resp.StatusCode != 200 || body != "plain" {
+			t.Error(id, "expect the overridden scheme used,got:", resp.StatusCode, body)
+		}
+		if raw := resp.Header.Get("Api-Front-Raw-Url"); !strings.HasPrefix(raw, "http://") {
+			t.Error(id, "wrong raw url:", raw)
+		}
+	}
+	if resp, _ := testGet(t, ts.URL+"/sch_none/a"); resp.StatusCode == 200 {
+		t.Error("expect https used without override")
+	}
+}
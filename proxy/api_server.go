@@ -2,8 +2,12 @@ package proxy
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -22,58 +26,138 @@ type APIServer struct {
 	web             *webAdmin
 	ServerVhostConf *serverVhost
 	counter         *Counter //j接口计数器
+	confSource      ConfigSource
+	quota           *quotaCounter //每日流量配额
+	reloads         *reloadPool   //限制同时加载的接口数
+	trustedProxies  []*net.IPNet  //可信的前端代理,只有来自这些ip的请求使用X-Real-Ip
+
+	untrustedRealIPOnce sync.Once //不可信来源的X-Real-Ip只告警一次
 }
 
-func newAPIServer(conf *serverVhost, manager *APIServerManager) *APIServer {
+func newAPIServer(conf *serverVhost, manager *APIServerManager) (*APIServer, error) {
 	apiServer := &APIServer{ServerVhostConf: conf, manager: manager}
+	if err := checkGzipLevel(conf.GzipLevel); err != nil {
+		return nil, err
+	}
+	trustedProxies, err := parseIPNets(conf.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted_proxies wrong:%s", err)
+	}
+	apiServer.trustedProxies = trustedProxies
 
 	apiServer.ConfDir = filepath.Join(manager.rootConfDir(), fmt.Sprintf("api_%s", conf.Id))
+	if err := checkConfDir(apiServer.ConfDir); err != nil {
+		return nil, err
+	}
 	apiServer.ConfDir += string(filepath.Separator)
 
+	apiServer.confSource = manager.newConfigSource(conf.Id, apiServer.ConfDir)
+	apiServer.reloads = newReloadPool(conf.MaxConcurrentReloads)
+
 	apiServer.Apis = make(map[string]*apiStruct)
 	apiServer.routers = newRouters()
 	apiServer.web = newWebAdmin(apiServer)
 	apiServer.counter = newCounter(apiServer)
+	apiServer.quota = newQuotaCounter(apiServer.getConfDir() + "_quota.json")
+	apiServer.expvarEnvSet()
+	go apiServer.quota.AutoSave(10)
 	apiServer.loadAllApis()
-	return apiServer
+	if err := apiServer.confSource.Watch(apiServer.onConfChange); err != nil {
+		return nil, fmt.Errorf("watch api conf failed:%s", err)
+	}
+	return apiServer, nil
+}
+
+// checkConfDir create the conf dir if not exists and make sure it is writable
+func checkConfDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		log.Println("[info]conf dir not exists,create it:", dir)
+		if err = os.MkdirAll(dir, 0777); err != nil {
+			return fmt.Errorf("create conf dir [%s] failed:%s", dir, err)
+		}
+		info, err = os.Stat(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("conf dir [%s] wrong:%s", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("conf dir [%s] is not a directory", dir)
+	}
+	f, err := ioutil.TempFile(dir, "_write_check")
+	if err != nil {
+		return fmt.Errorf("conf dir [%s] is not writable:%s", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
 }
 
 func (apiServer *APIServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	normalizeRequest(req)
+	//router is from a snapshot of the routing table,a reload does not change it
 	router := apiServer.routers.getRouterByReqPath(req.URL.Path)
 	if router != nil {
 		router.Hander.ServeHTTP(rw, req)
 		return
 	}
-	if strings.HasPrefix(req.URL.Path, "/_") || req.URL.Path == "/" {
+	if strings.HasPrefix(req.URL.Path, "/_") || req.URL.Path == "/" || req.URL.Path == expvarPath || req.URL.Path == faviconPath {
 		apiServer.web.ServeHTTP(rw, req)
 	} else {
-		http.Error(rw, "Api Not Found (api-front)", http.StatusNotFound)
+		apiServer.writeErrorPage(rw, req, http.StatusNotFound, "Api Not Found (api-front)")
 	}
 }
 
-func (apiServer *APIServer) loadAllApis() {
-	fileNames, _ := filepath.Glob(apiServer.ConfDir + string(filepath.Separator) + "*.json")
-	for _, fileName := range fileNames {
-		log.Println("start load conf file:", fileName)
-		_, baseName := filepath.Split(fileName)
-
-		if baseName == "" {
-			log.Println("skip api conf:", fileName)
-			continue
+// normalizeRequest collapse the duplicate slashes and dot segments of path,
+// and upper the method.the original is still in req.RequestURI
+func normalizeRequest(req *http.Request) {
+	req.Method = strings.ToUpper(req.Method)
+	//"//a/b" is parsed as host a and path /b
+	if strings.HasPrefix(req.RequestURI, "//") {
+		if u, err := url.ParseRequestURI("/" + strings.TrimLeft(req.RequestURI, "/")); err == nil {
+			req.URL.Host = ""
+			req.URL.Path = u.Path
+			req.URL.RawPath = u.RawPath
 		}
+	}
+	cleanPath := URLPathClean(req.URL.Path)
+	if cleanPath != req.URL.Path {
+		log.Println("[info]path normalized", req.URL.Path, "->", cleanPath)
+		req.URL.Path = cleanPath
+		req.URL.RawPath = ""
+	}
+}
 
-		apiName := baseName[:len(baseName)-5]
+func (apiServer *APIServer) loadAllApis() {
+	apiNames, err := apiServer.confSource.List()
+	if err != nil {
+		log.Println("[error]list api conf failed:", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, apiName := range apiNames {
+		wg.Add(1)
+		go (func(apiName string) {
+			defer wg.Done()
+			apiServer.loadAPI(apiName)
+		})(apiName)
+	}
+	wg.Wait()
+}
 
-		if strings.HasPrefix(apiName, "_") {
-			log.Println("skip api", apiName)
-			continue
+// onConfChange reload the api when its conf changed in the conf source
+func (apiServer *APIServer) onConfChange(apiName string) {
+	log.Println("[info]api conf changed:", apiName)
+	apiServer.reloads.do(apiName, func() {
+		if _, err := apiServer.confSource.Get(apiName); os.IsNotExist(err) {
+			apiServer.removeAPI(apiName)
+			return
 		}
-
-		apiServer.loadAPI(apiName)
-	}
+		apiServer.doLoadAPI(apiName)
+	})
 }
 
-//api服务的唯一id
+// api服务的唯一id
 func (apiServer *APIServer) GetServerID() string {
 	return apiServer.ServerVhostConf.Id
 }
@@ -89,13 +173,29 @@ func (apiServer *APIServer) GetServerID() string {
 //	delete(apiServer.Apis, apiName)
 //}
 
+// removeAPI unregister the api and unbind its router
+func (apiServer *APIServer) removeAPI(apiName string) {
+	apiServer.Rw.Lock()
+	defer apiServer.Rw.Unlock()
+	api, has := apiServer.Apis[apiName]
+	if !has {
+		return
+	}
+	delete(apiServer.Apis, apiName)
+	apiServer.routers.deleteAPIRouter(api.Path, apiName)
+	log.Printf("api [%s] removed", apiName)
+}
+
 func (apiServer *APIServer) unRegisterAPI(apiName string) {
 	apiServer.Rw.Lock()
 	defer apiServer.Rw.Unlock()
-	_, has := apiServer.Apis[apiName]
+	api, has := apiServer.Apis[apiName]
 	if !has {
 		return
 	}
+	if api.transports != nil {
+		api.transports.closeIdleConns()
+	}
 	delete(apiServer.Apis, apiName)
 }
 
@@ -111,10 +211,17 @@ func (apiServer *APIServer) rootConfDir() string {
 	return apiServer.manager.rootConfDir()
 }
 
-func (apiServer *APIServer) loadAPI(apiName string) error {
-	apiServer.Rw.Lock()
-	defer apiServer.Rw.Unlock()
+// loadAPI load the api in the reload pool
+func (apiServer *APIServer) loadAPI(apiName string) (err error) {
+	apiServer.reloads.do(apiName, func() {
+		err = apiServer.doLoadAPI(apiName)
+	})
+	return err
+}
 
+// doLoadAPI the conf is parsed without the lock,
+// so the loading of the other apis and the readers are not blocked
+func (apiServer *APIServer) doLoadAPI(apiName string) error {
 	api, err := loadAPIByConf(apiServer, apiName)
 	if err != nil {
 		log.Printf("load api [%s] failed,err:%s", apiName, err)
@@ -123,18 +230,61 @@ func (apiServer *APIServer) loadAPI(apiName string) error {
 
 	log.Printf("load api [%s] success", apiName)
 
+	//check before holding the lock,it may take the timeout
+	enable := api.Enable
+	if enable && api.PreCheck != "" {
+		if err := api.preCheck(); err != nil {
+			log.Printf("[warning]api [%s] pre check failed,%s", apiName, err)
+			if api.PreCheck == preCheckStrict {
+				log.Printf("[error]api [%s] is not enable,no host reachable", apiName)
+				enable = false
+			}
+		}
+	}
+
+	apiServer.Rw.Lock()
+	defer apiServer.Rw.Unlock()
+
+	apiOld := apiServer.Apis[apiName]
+	if apiOld != nil && apiOld.transports != nil {
+		apiOld.transports.closeIdleConns()
+	}
 	apiServer.Apis[apiName] = api
-	if api.Enable {
-		router := newRouterItem(apiName, api.Path, apiServer.newHandler(api))
-		apiServer.routers.bindRouter(api.Path, router)
-	} else {
-		apiServer.routers.deleteRouterByPath(api.Path)
+	if api.ReloadHook != nil {
+		go api.runReloadHook(newReloadEvent(apiOld, api))
+	}
+	var router *routerItem
+	if enable {
+		router = newRouterItem(apiName, api.Path, apiServer.newHandler(api))
+		router.NoTrailingSlash = api.TrailingSlash != trailingSlashStrict
+	} else if !api.Enable {
 		log.Printf("api [%s] is not enable,skip", apiName)
 	}
+	apiServer.rebindAPIRouter(apiName, api.Path, router)
 
 	return nil
 }
 
+// rebindAPIRouter bind the router of the api(nil when disabled),
+// unbind its old path(the path is changed) and the paths of the unregistered apis(the id is changed),
+// all in one swap,so no request sees the old and the new binding at the same time.
+// the caller must hold apiServer.Rw
+func (apiServer *APIServer) rebindAPIRouter(apiName string, bindPath string, router *routerItem) {
+	apiServer.routers.update(func(bindMap map[string]*routerItem) {
+		for p, item := range bindMap {
+			_, registered := apiServer.Apis[item.APIName]
+			if !registered || (item.APIName == apiName && (p != bindPath || router == nil)) {
+				log.Println("unbind router,apiName=", item.APIName, "bindPath=", p)
+				delete(bindMap, p)
+			}
+		}
+		if router != nil {
+			bindMap[bindPath] = router
+			log.Println("bind router,apiName=", apiName, "bindPath=", bindPath)
+		}
+	})
+}
+
 func (apiServer *APIServer) uniqReqID(id uint64) string {
 	return fmt.Sprintf("%s_%d", time.Now().Format("20060102_150405"), id)
 }
@@ -151,6 +301,17 @@ func (apiServer *APIServer) domains() []string {
 	return apiServer.ServerVhostConf.Domains
 }
 
+// apiSnapshot the loaded apis,which can be used without holding the lock
+func (apiServer *APIServer) apiSnapshot() []*apiStruct {
+	apiServer.Rw.RLock()
+	defer apiServer.Rw.RUnlock()
+	apis := make([]*apiStruct, 0, len(apiServer.Apis))
+	for _, api := range apiServer.Apis {
+		apis = append(apis, api)
+	}
+	return apis
+}
+
 func (apiServer *APIServer) getAPIByID(id string) *apiStruct {
 	if id == "" {
 		return nil
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// jsonStringEscape the escaped string without the quotes,to be put in the json template
+func jsonStringEscape(s string) string {
+	bs, _ := json.Marshal(s)
+	return string(bs[1 : len(bs)-1])
+}
+
+// writeErrorPage write the json template of the server for 404/405,
+// ${status},${method},${path} in the template are replaced.
+// write the text when there is no template
+func (apiServer *APIServer) writeErrorPage(rw http.ResponseWriter, req *http.Request, code int, text string) {
+	var tpl string
+	switch code {
+	case http.StatusNotFound:
+		tpl = apiServer.ServerVhostConf.NotFoundJSON
+	case http.StatusMethodNotAllowed:
+		tpl = apiServer.ServerVhostConf.MethodNotAllowedJSON
+	}
+	if tpl == "" {
+		http.Error(rw, text, code)
+		return
+	}
+	r := strings.NewReplacer(
+		"${status}", jsonStringEscape(http.StatusText(code)),
+		"${method}", jsonStringEscape(req.Method),
+		"${path}", jsonStringEscape(req.URL.Path),
+	)
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(code)
+	rw.Write([]byte(r.Replace(tpl)))
+}
+
+func (api *apiStruct) initMethods() {
+	for i, m := range api.Methods {
+		api.Methods[i] = strings.ToUpper(strings.TrimSpace(m))
+	}
+}
+
+// methodAllowed all methods are allowed when Methods is empty
+func (api *apiStruct) methodAllowed(method string) bool {
+	if len(api.Methods) == 0 || InStringSlice(method, api.Methods) {
+		return true
+	}
+	//HEAD is allowed as GET
+	return api.headAsGet(method) && InStringSlice(http.MethodGet, api.Methods)
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_APIServerErrorJSON(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "m", `{"path":"/m/","enable":true,"methods":["get","post"],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	do := func(method string, path string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(bd)
+	}
+
+	//no template,plain text
+	if resp, body := do("GET", "/not_exists/"); resp.StatusCode != 404 || !strings.Contains(body, "Api Not Found") {
+		t.Error("wrong 404:", resp.StatusCode, body)
+	}
+	if resp, body := do("DELETE", "/m/a"); resp.StatusCode != 405 || resp.Header.Get("Allow") != "GET, POST" || !strings.Contains(body, "Method Not Allowed") {
+		t.Error("wrong 405:", resp.StatusCode, resp.Header, body)
+	}
+	if resp, body := do("POST", "/m/a"); resp.StatusCode != 200 || body != "ok" {
+		t.Error("POST should be allowed:", resp.StatusCode, body)
+	}
+
+	apiServer.ServerVhostConf.NotFoundJSON = `{"code":404,"msg":"${status}","path":"${path}"}`
+	apiServer.ServerVhostConf.MethodNotAllowedJSON = `{"code":405,"msg":"${method} not allowed"}`
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		expect map[string]interface{}
+	}{
+		{"GET", `/not_"exists/`, 404, map[string]interface{}{"code": 404.0, "msg": "Not Found", "path": `/not_"exists/`}},
+		{"PUT", "/m/a", 405, map[string]interface{}{"code": 405.0, "msg": "PUT not allowed"}},
+	}
+	for _, c := range cases {
+		resp, body := do(c.method, strings.Replace(c.path, `"`, "%22", -1))
+		if resp.StatusCode != c.code {
+			t.Error(c.path, "wrong status:", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Error(c.path, "wrong Content-Type:", ct)
+		}
+		ret := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(body), &ret); err != nil {
+			t.Fatal(c.path, "invalid json:", err, body)
+		}
+		for k, v := range c.expect {
+			if ret[k] != v {
+				t.Error(c.path, "wrong", k, ret[k])
+			}
+		}
+	}
+}
@@ -1,8 +1,8 @@
 package proxy
 
 import (
-	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -21,7 +21,29 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 	bindPath := api.Path
 	log.Println(apiServer.ServerVhostConf.Port, api.ID, "bind path [", bindPath, "]")
 	return func(rw http.ResponseWriter, req *http.Request) {
+		if api.redirectTrailingSlash(rw, req) {
+			return
+		}
+		if !api.methodAllowed(req.Method) {
+			rw.Header().Set("Allow", strings.Join(api.Methods, ", "))
+			apiServer.writeErrorPage(rw, req, http.StatusMethodNotAllowed, "Method Not Allowed (api-front)")
+			return
+		}
+		if api.urlTooLong(req) {
+			log.Println("[warning]url too long", api.ID, req.Method, len(req.URL.RequestURI()))
+			rw.WriteHeader(http.StatusRequestURITooLong)
+			rw.Write([]byte("request url too long"))
+			return
+		}
+		api.warnUntrustedRealIP(req)
+		if ip := apiServer.callerIP(req); !api.ipAllowed(ip) {
+			log.Println("[warning]caller not allowed", api.ID, ip, req.URL.String())
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte("caller not allowed"))
+			return
+		}
 		id := api.pvInc()
+		api.expvarReqInc()
 		uniqID := apiServer.uniqReqID(id)
 		var broadData *BroadCastData
 		needBroad := apiServer.needBroadcast(api)
@@ -40,21 +62,67 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 		}
 
 		rw.Header().Set("Api-Front-Version", APIFrontVersion)
+		api.setDefaultRespHeaders(rw.Header())
 		log.Println("[access]", req.URL.String())
 
-		relPath := req.URL.Path[len(bindPath):]
+		if err := api.acquireSlot(req); err != nil {
+			log.Println("[warning]", api.ID, req.URL.String(), err)
+			api.expvarErrInc()
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			rw.Write([]byte(err.Error()))
+			if needBroad {
+				broadData.setError(err.Error())
+			}
+			return
+		}
+		//when acked,the master is called in background,which releases the slot
+		var slotAsync bool
+		defer func() {
+			if !slotAsync {
+				api.releaseSlot()
+			}
+		}()
+
+		//empty when the path has no trailing slash
+		var relPath string
+		if len(req.URL.Path) > len(bindPath) {
+			relPath = req.URL.Path[len(bindPath):]
+		}
 		req.Header.Set("Connection", "close")
+		api.setClientCertHeaders(req)
 		//add this flag,so the real backend can catch it
 		req.Header.Add("Via", fmt.Sprintf("api-front/%s", APIFrontVersion))
 
 		logData := make(map[string]interface{})
 		var logRw sync.RWMutex
 
-		body, err := ioutil.ReadAll(req.Body)
+		if name, reqID := apiServer.requestID(req, uniqID); name != "" {
+			rw.Header().Set(name, reqID)
+			logData["request_id"] = reqID
+		}
+		if api.Enrich != nil {
+			key, hit := api.enrichRequest(req, relPath)
+			logData["enrich_key"] = key
+			logData["enrich_hit"] = hit
+		}
+
+		body, err := readRequestBody(req, api.bodyLimit(req.Method))
 
 		logData["body_len"] = len(body)
 
+		if err == errBodyTooLarge {
+			logData["body_limit"] = api.bodyLimit(req.Method)
+			log.Println("[warning]", api.ID, req.Method, req.URL.String(), err, "content_length:", req.ContentLength)
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+			rw.Write([]byte(err.Error()))
+			if needBroad {
+				broadData.setError(err.Error())
+			}
+			return
+		}
+
 		if err != nil {
+			api.expvarErrInc()
 			rw.WriteHeader(http.StatusBadGateway)
 			rw.Write([]byte("read body failed"))
 
@@ -63,9 +131,53 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 			}
 			return
 		}
+		if minBody := api.minifyBody(req, body); len(minBody) != len(body) {
+			logData["body_minify"] = len(minBody)
+			body = minBody
+		}
 		//get body must by before  parse callerPref
 
+		req, stickyKey := api.withStickyKey(req, body)
+		if stickyKey != "" {
+			logData["sticky_key"] = stickyKey
+		}
 		hosts, masterHost, cpf := api.getAPIHostsByReq(req)
+		caller := api.Caller.getCallerItemByIP(cpf.GetIP())
+		logData["caller"] = caller.logInfo(cpf)
+		if api.Deprecation != nil {
+			logData["deprecated"] = true
+			api.logDeprecatedUse(req)
+		}
+		caller.setRespHeaders(rw.Header())
+
+		//the host whose response is sent to the client
+		respHost := masterHost
+		if debugHost, found := debugHostName(req, caller, hosts); found {
+			respHost = debugHost
+			logData["debug_host"] = debugHost
+			rw.Header().Set("Api-Front-Debug-Host", debugHost)
+		} else if debugHost != "" {
+			logData["debug_host"] = debugHost + " not found"
+		}
+		if hs, skipped := api.masterOnlyHosts(hosts, body, masterHost, respHost); skipped {
+			hosts = hs
+			logData["skipped_large"] = true
+		}
+
+		quotaKey := api.quotaKey(cpf.GetIP())
+		if api.DailyByteQuota > 0 {
+			remaining := apiServer.quota.remaining(quotaKey, api.DailyByteQuota)
+			if remaining <= 0 {
+				log.Println("[warning]daily quota exceeded", api.ID, quotaKey, req.URL.String())
+				rw.Header().Set("Api-Front-Quota-Remaining", "0")
+				rw.WriteHeader(http.StatusTooManyRequests)
+				rw.Write([]byte("daily quota exceeded"))
+				if needBroad {
+					broadData.setError("daily quota exceeded")
+				}
+				return
+			}
+		}
 
 		if needBroad {
 			broadData.setData("master", masterHost)
@@ -77,13 +189,32 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 		if req.URL.RawQuery != "" {
 			_uri += "?" + req.URL.RawQuery
 		}
+		if req.RequestURI != "" && req.RequestURI != req.URL.RequestURI() {
+			logData["raw_uri"] = req.RequestURI
+		}
 		mainLogStr := fmt.Sprintf("uniqid=%s port=%d remote=%s method=%s uri=%s master=%s hostsTotal=%d refer=%s", uniqID, apiServer.ServerVhostConf.Port, req.RemoteAddr, req.Method, _uri, masterHost, len(hosts), req.Referer())
+		if env := apiServer.ServerVhostConf.Environment; env != "" {
+			mainLogStr += " env=" + env
+		}
+
+		var statusRw *statusWriter
+		if api.LogFormat != "" {
+			statusRw = &statusWriter{ResponseWriter: rw}
+			rw = statusRw
+		}
 
 		var printLog = func(logIndex int) {
+			if statusRw != nil {
+				//one line for the response sent to the client
+				if logIndex == 1 {
+					api.printAccessLog(formatAccessLog(api.LogFormat, req, statusRw, start))
+				}
+				return
+			}
 			logRw.RLock()
 			defer logRw.RUnlock()
 			totalUsed := fmt.Sprintf("%.3fms", float64(time.Now().Sub(start).Nanoseconds())/1e6)
-			log.Println(fmt.Sprintf("[access]logindex=%d/%d", logIndex, len(hosts)), mainLogStr, fmt.Sprintf("totalUsed=%s", totalUsed), logData)
+			api.printAccessLog(fmt.Sprintln(fmt.Sprintf("[access]logindex=%d/%d", logIndex, len(hosts)), mainLogStr, fmt.Sprintf("totalUsed=%s", totalUsed), logData))
 		}
 		defer (func() {
 			printLog(1)
@@ -93,8 +224,20 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 
 		addrInfo := strings.Split(req.RemoteAddr, ":")
 
+		if api.inCooldown() {
+			logData["cooldown"] = true
+			api.expvarErrInc()
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			rw.Write([]byte("all backend hosts failed,cooling down"))
+			if needBroad {
+				broadData.setError("all backend hosts failed,cooling down")
+			}
+			return
+		}
+
 		if len(hosts) == 0 {
 			logData["hostTotal"] = 0
+			api.expvarErrInc()
 			rw.WriteHeader(http.StatusBadGateway)
 			rw.Write([]byte("no backend hosts"))
 			if needBroad {
@@ -103,41 +246,46 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 			return
 		}
 
-		bodyLen := int64(len(body))
+		//charged after the rejections above,the response bytes are not known yet
+		if api.DailyByteQuota > 0 {
+			apiServer.quota.add(quotaKey, int64(len(body)))
+			remaining := apiServer.quota.remaining(quotaKey, api.DailyByteQuota)
+			if remaining < 0 {
+				remaining = 0
+			}
+			rw.Header().Set("Api-Front-Quota-Remaining", fmt.Sprintf("%d", remaining))
+		}
+
 		var reqs []*apiHostRequest
 
 		//build request
 		for _, apiHost := range hosts {
-			isMaster := apiHost.Name == masterHost
-			urlNew := ""
-
-			serverURL := apiHost.URLStr
+			isMaster := apiHost.Name == respHost
+			serverURL := api.hostURL(apiHost)
 			if api.HostAsProxy {
 				serverURL = "http://" + req.Host + api.Path
 			}
-			if strings.HasSuffix(urlNew, "/") {
-				urlNew += strings.TrimLeft(relPath, "/")
-			} else {
-				urlNew += relPath
-			}
+			var query string
 			if req.URL.RawQuery != "" {
-				urlNew += "?" + req.URL.RawQuery
+				query = "?" + req.URL.RawQuery
 			}
 
-			rawURL := apiHost.URLStr + urlNew
+			rawURL := api.joinHostURL(api.hostURL(apiHost), relPath) + query
 
 			if isMaster {
 				rw.Header().Set("Api-Front-Raw-Url", rawURL)
 			}
 
-			urlNew = serverURL + urlNew
+			urlNew := api.joinHostURL(serverURL, relPath) + query
 			if needBroad {
 				broadData.setData("raw_url", rawURL)
 			}
 
-			reqNew, err := http.NewRequest(req.Method, urlNew, ioutil.NopCloser(bytes.NewReader(body)))
+			hostBody, gzipped := apiHost.requestBody(body, req.Header, api.gzipLevel())
+			reqNew, err := http.NewRequest(api.backendMethod(req.Method), urlNew, ioutil.NopCloser(apiHost.bodyReader(hostBody, isMaster)))
 			if err != nil {
 				log.Println("[error]build req failed:", err)
+				api.expvarErrInc()
 				if isMaster {
 					rw.WriteHeader(http.StatusBadGateway)
 					rw.Write([]byte("error:" + err.Error() + "\nraw_url:" + rawURL))
@@ -149,6 +297,9 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 
 			}
 			copyHeaders(reqNew.Header, req.Header)
+			if api.ReuseConn {
+				reqNew.Header.Del("Connection")
+			}
 
 			//only accept gzip encode
 			acceptEncoding := reqNew.Header.Get("Accept-Encoding")
@@ -156,7 +307,14 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 				reqNew.Header.Set("Accept-Encoding", "gzip")
 			}
 
-			if bodyLen > 0 {
+			//the transform service gets the plain body
+			if api.Transform != nil && isMaster {
+				reqNew.Header.Del("Accept-Encoding")
+			}
+			if gzipped {
+				reqNew.Header.Set("Content-Encoding", "gzip")
+			}
+			if bodyLen := int64(len(hostBody)); bodyLen > 0 {
 				reqNew.ContentLength = bodyLen
 				reqNew.Header.Set("Content-Length", fmt.Sprintf("%d", bodyLen))
 			}
@@ -165,30 +323,10 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 				reqNew.Header.Set("HTTP_X_FORWARDED_FOR", addrInfo[0])
 			}
 
-			timeoutMs := time.Duration(api.TimeoutMs) * time.Millisecond
+			//caller > host > api
+			timeoutMs := caller.requestTimeout(apiHost.requestTimeout(api.requestTimeout()))
 
-			transport := &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				Dial: (&net.Dialer{
-					Timeout:   timeoutMs,
-					KeepAlive: 0,
-				}).Dial,
-				TLSHandshakeTimeout: timeoutMs,
-				DisableKeepAlives:   true,
-			}
-			if api.HostAsProxy {
-				transport.Proxy = (func(u string) func(*http.Request) (*url.URL, error) {
-					return func(req *http.Request) (*url.URL, error) {
-						return url.Parse(u)
-					}
-				})(apiHost.URLStr)
-			}
-
-			if api.proxyURL != nil {
-				transport.Proxy = func(req *http.Request) (*url.URL, error) {
-					return api.proxyURL, nil
-				}
-			}
+			transport := api.hostTransport(apiHost, timeoutMs)
 
 			apiReq := &apiHostRequest{
 				req:       reqNew,
@@ -200,12 +338,31 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 				urlRaw:    rawURL,
 				Timeout:   timeoutMs,
 			}
+			if apiHost.FallbackURL != "" && !api.HostAsProxy {
+				apiReq.fallbackReq = newFallbackRequest(reqNew, api.joinHostURL(apiHost.FallbackURL, relPath)+query, apiHost.bodyReader(hostBody, isMaster))
+			}
 			reqs = append(reqs, apiReq)
 		}
 
+		//the master response is broken after the header was sent
+		var abortConn bool
+		//the master response is a stream,not sent to the other hosts
+		var streamed bool
+		//the master response compared with the other hosts'
+		var shadowDiff *DiffResult
+		//the results of all the called hosts,for the all fail cooldown
+		results := &hostResults{}
+		//the results are reported after the other hosts are called async
+		var reportAsync bool
+
+		if api.Ack != nil {
+			logData["ack"] = api.Ack.Status
+			api.Ack.write(rw)
+		}
+
 		//call master at first sync
 		for index, apiReq := range reqs {
-			if !apiReq.isMaster {
+			if !apiReq.isMaster || api.Ack != nil {
 				continue
 			}
 			backLog := make(map[string]interface{})
@@ -220,27 +377,36 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 			backLog["isMaster"] = apiReq.isMaster
 			backLog["start"] = fmt.Sprintf("%.4f", float64(hostStart.UnixNano())/1e9)
 			backLog["status"] = 502 //as default
-
-			cc := rw.(http.CloseNotifier).CloseNotify()
-
-			go (func() {
-				select {
-				case <-cc:
-					if !apiReq.isDone {
-						apiReq.transport.CancelRequest(apiReq.req)
-						backLog["status"] = 499
-						if needBroad {
-							broadData.setData("resp_status", 499)
-						}
-					}
-				}
-			})()
+			api.expvarHostReqInc(apiReq.apiHost.Name)
+			//the request is aborted when the client is gone
+			apiReq.withClientContext(req.Context())
 			resp, err := apiReq.RoundTrip()
+			success := err == nil && api.statusSuccess(resp.StatusCode)
+			defer func() {
+				apiReq.recordStats(success)
+				if !reportAsync {
+					api.reportAllFail(!success)
+				}
+			}()
+			if apiReq.isFallback {
+				backLog["fallback_url"] = apiReq.urlNew
+			}
 
+			if err != nil && req.Context().Err() != nil {
+				backLog["status"] = 499
+				if needBroad {
+					broadData.setData("resp_status", 499)
+				}
+			}
 			if err != nil {
 				log.Println("[error]call_master_sync "+apiReq.urlNew, err)
-				rw.WriteHeader(http.StatusBadGateway)
-				rw.Write([]byte("fetch_error:" + err.Error() + "\nraw_url:" + apiReq.urlRaw + "\nnew_url:" + apiReq.urlNew))
+				api.expvarErrInc()
+				if caller.Trusted {
+					writeDiagnostic(rw, reqs, newDiagnosticHost(apiReq, resp, err, time.Since(hostStart)))
+				} else {
+					rw.WriteHeader(http.StatusBadGateway)
+					rw.Write([]byte("fetch_error:" + err.Error() + "\nraw_url:" + apiReq.urlRaw + "\nnew_url:" + apiReq.urlNew))
+				}
 				if needBroad {
 					broadData.setError(err.Error())
 				}
@@ -255,6 +421,7 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 			backLog["resp_mod_err"] = _mod_err
 			if _mod_err != nil {
 				log.Println("[error]call_resp_mod "+apiReq.urlNew, _mod_err)
+				api.expvarErrInc()
 				rw.WriteHeader(http.StatusBadGateway)
 				rw.Write([]byte("response modify error:" + _mod_err.Error()))
 
@@ -265,44 +432,143 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 			}
 			//--------------------------------------------------------------
 
+			if api.Transform != nil && !isStreamResp(resp) {
+				if tErr := api.Transform.transform(api.ID, req, resp); tErr != nil {
+					log.Println("[warning]transform failed", api.ID, apiReq.urlNew, tErr)
+					backLog["transform_err"] = tErr.Error()
+					if !api.Transform.FailOpen {
+						api.expvarErrInc()
+						rw.WriteHeader(http.StatusBadGateway)
+						rw.Write([]byte("transform error:" + tErr.Error()))
+						if needBroad {
+							broadData.setError(tErr.Error())
+						}
+						return
+					}
+				} else {
+					backLog["transform"] = true
+				}
+			}
+
 			if needBroad {
 				apiServer.addBroadCastDataResponse(broadData, resp)
 			}
 
-			for k, vs := range resp.Header {
-				for _, v := range vs {
-					rw.Header().Add(k, v)
-				}
+			api.rewriteSetCookies(resp.Header)
+			api.copyRespHeaders(rw.Header(), resp.Header)
+			caller.setRespHeaders(rw.Header())
+			if api.Deprecation != nil {
+				api.Deprecation.setRespHeaders(rw.Header())
 			}
 			rw.Header().Set("Connection", "close")
-			rw.WriteHeader(resp.StatusCode)
+			respWriter := api.respWriter(rw)
+			if isStreamResp(resp) {
+				streamed = true
+				backLog["stream"] = true
+				respWriter = streamWriter(respWriter, rw)
+			}
+			statusCode := api.remapStatus(resp.StatusCode)
+			if statusCode != resp.StatusCode {
+				backLog["status_remap"] = statusCode
+			}
+			rw.WriteHeader(statusCode)
 			backLog["status"] = resp.StatusCode
-			n, err := io.Copy(rw, resp.Body)
+			var respBody io.Reader = resp.Body
+			var assertBuf *limitBuffer
+			if api.RespAssert != nil || api.Golden != "" || api.SuccessCriteria.needBody() || api.ShadowDiff {
+				assertBuf = &limitBuffer{max: respAssertMaxBody}
+				respBody = io.TeeReader(resp.Body, assertBuf)
+			}
+			var n int64
+			if api.headAsGet(req.Method) {
+				//the client gets the headers only,the body of GET is not read
+				backLog["head_as_get"] = true
+			} else {
+				n, err = io.Copy(respWriter, respBody)
+			}
+			if api.DailyByteQuota > 0 {
+				apiServer.quota.add(quotaKey, n)
+			}
+			if respLengthMismatch(resp, n) && !api.headAsGet(req.Method) {
+				log.Println("[error]call_master_sync,content-length mismatch "+apiReq.urlNew, "declared:", resp.ContentLength, "copied:", n)
+				backLog["length_mismatch"] = fmt.Sprintf("%d/%d", n, resp.ContentLength)
+				abortConn = true
+			}
 			if err != nil {
-				log.Println(apiReq.urlNew, "io.copy:", n, err)
+				log.Println("[error]call_master_sync,copy body "+apiReq.urlNew, "io.copy:", n, err)
+				backLog["copy_err"] = err.Error()
+				backLog["copy_bytes"] = n
+				if req.Context().Err() != nil {
+					backLog["client_gone"] = true
+				}
+				abortConn = true
+				api.expvarErrInc()
+				if needBroad {
+					broadData.setError("copy body failed:" + err.Error())
+				}
+			}
+			if api.SuccessCriteria != nil && err == nil {
+				if success && api.SuccessCriteria.needBody() {
+					if failErr := api.bodySuccess(resp, &assertBuf.Buffer); failErr != nil {
+						success = false
+						backLog["success_fail"] = failErr.Error()
+					}
+				}
+				if !success {
+					api.expvarErrInc()
+				}
+			}
+			if api.RespAssert != nil {
+				if assertErr := api.RespAssert.check(resp, &assertBuf.Buffer); assertErr != nil {
+					backLog["assert_fail"] = assertErr.Error()
+					expvarAssertFailures.Add(api.expvarKey(), 1)
+					log.Println("[warning]resp_assert failed", api.ID, apiReq.urlNew, assertErr)
+				}
+			}
+			if api.ShadowDiff && err == nil && !streamed {
+				shadowDiff = newDiffResult(apiReq.apiHost.Name, newDiffResp(resp, assertBuf.Bytes(), n), api.shadowComparator())
+			}
+			if api.Golden != "" && err == nil {
+				tier, goldenErr := api.checkGolden(req, body, resp, &assertBuf.Buffer)
+				if tier != "" {
+					backLog["compare_tier"] = tier
+				}
+				if goldenErr != nil {
+					backLog["golden_"+api.Golden] = goldenErr.Error()
+					if api.Golden == goldenCompare {
+						expvarGoldenMismatch.Add(api.expvarKey(), 1)
+					}
+					log.Println("[warning]golden", api.Golden, "failed", api.ID, apiReq.urlNew, goldenErr)
+				}
 			}
 			hostEnd := time.Now()
 			used := hostEnd.Sub(hostStart)
 			backLog["end"] = fmt.Sprintf("%.4f", float64(hostEnd.UnixNano())/1e9)
 			backLog["used"] = fmt.Sprintf("%.3fms", float64(used.Nanoseconds())/1e6)
-
+			results.add(success)
 		}
 
-		if len(reqs) > 1 {
-			//call other hosts async
+		if streamed && len(reqs) > 1 {
+			logData["shadow_skip"] = "stream"
+		} else if len(reqs) > 1 || api.Ack != nil {
+			//call other hosts async,and the master too when acked
+			reportAsync = true
+			slotAsync = api.Ack != nil
 			go (func(reqs []*apiHostRequest) {
 				defer (func() {
 					printLog(len(reqs))
 				})()
 				var wgOther sync.WaitGroup
 				for index, apiReq := range reqs {
-					if apiReq.isMaster {
+					if apiReq.isMaster && api.Ack == nil {
 						continue
 					}
 					wgOther.Add(1)
+					fanoutAdd(1)
 					go (func(index int, apiReq *apiHostRequest) {
 						backLog := make(map[string]interface{})
 						defer (func() {
+							fanoutAdd(-1)
 							logRw.Lock()
 							logData[fmt.Sprintf("host_%s_%d", apiReq.apiHost.Name, index)] = backLog
 							logRw.Unlock()
@@ -312,13 +578,65 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 						hostStart := time.Now()
 						backLog["isMaster"] = apiReq.isMaster
 						backLog["start"] = fmt.Sprintf("%.4f", float64(hostStart.UnixNano())/1e9)
-						backLog["isMaster"] = apiReq.isMaster
+						api.expvarHostReqInc(apiReq.apiHost.Name)
+						lifetime := shadowMaxLifetime
+						if apiReq.isMaster {
+							//the acked master,limited by the timeout and counted like a sync one
+							defer api.releaseSlot()
+							lifetime = apiReq.Timeout
+						}
+						lifetimeDone := apiReq.withLifetime(lifetime)
 						resp, err := apiReq.RoundTrip()
+						var diffBody, successBody *limitBuffer
+						var diffSize int64
+						if err == nil && shadowDiff != nil && !apiReq.isMaster {
+							diffBody = &limitBuffer{max: shadowDiffMaxBody}
+							diffSize, _ = io.Copy(diffBody, resp.Body)
+							if api.SuccessCriteria.needBody() {
+								successBody = diffBody
+							}
+						} else if err == nil && api.SuccessCriteria.needBody() {
+							successBody = &limitBuffer{max: respAssertMaxBody}
+							_, err = io.Copy(successBody, resp.Body)
+						} else if err == nil && api.ReuseConn {
+							//the connection can be reused only when the body is read to the end,
+							//before the lifetime is released
+							io.Copy(ioutil.Discard, resp.Body)
+						}
+						if lifetimeDone() && !apiReq.isMaster {
+							backLog["reaped"] = true
+							api.shadowReaped(apiReq)
+						}
+						if apiReq.isFallback {
+							backLog["fallback_url"] = apiReq.urlNew
+						}
 						if err != nil {
 							log.Println("[error]call_other_async,fetch "+apiReq.urlNew, err)
+							apiReq.recordStats(false)
+							if apiReq.isMaster {
+								api.expvarErrInc()
+							}
+							if shadowDiff != nil && !apiReq.isMaster {
+								shadowDiff.addError(apiReq.apiHost.Name, err)
+							}
 							return
 						}
 						backLog["status"] = resp.StatusCode
+						success := api.statusSuccess(resp.StatusCode)
+						if success && successBody != nil {
+							if failErr := api.bodySuccess(resp, &successBody.Buffer); failErr != nil {
+								success = false
+								backLog["success_fail"] = failErr.Error()
+							}
+						}
+						apiReq.recordStats(success)
+						if apiReq.isMaster && api.SuccessCriteria != nil && !success {
+							api.expvarErrInc()
+						}
+						results.add(success)
+						if diffBody != nil {
+							shadowDiff.add(apiReq.apiHost.Name, newDiffResp(resp, diffBody.Bytes(), diffSize))
+						}
 						defer resp.Body.Close()
 
 						hostEnd := time.Now()
@@ -328,41 +646,105 @@ func (apiServer *APIServer) newHandler(api *apiStruct) func(http.ResponseWriter,
 					})(index, apiReq)
 				}
 				wgOther.Wait()
+				api.reportAllFail(results.allFail())
+				if shadowDiff != nil {
+					if shadowDiff.hasDiff() {
+						expvarShadowDiffs.Add(api.expvarKey(), 1)
+					}
+					logRw.Lock()
+					logData["shadow_diff"] = shadowDiff
+					logRw.Unlock()
+				}
 
 			})(reqs)
 		}
+
+		if abortConn {
+			//status and header have been sent,abort the connection
+			//so the client gets an error rather than a truncated body
+			panic(http.ErrAbortHandler)
+		}
 	}
 }
 
 type apiHostRequest struct {
-	req       *http.Request //修改后的请求
-	reqRaw    *http.Request //原始的请求
-	urlRaw    string
-	urlNew    string
-	transport *http.Transport
-	apiHost   *Host
-	isMaster  bool
-	Timeout   time.Duration
-	isDone    bool
+	req         *http.Request //修改后的请求
+	reqRaw      *http.Request //原始的请求
+	urlRaw      string
+	urlNew      string
+	transport   *http.Transport
+	apiHost     *Host
+	isMaster    bool
+	Timeout     time.Duration
+	fallbackReq *http.Request //连接失败时使用的请求
+	isFallback  bool
+	used        time.Duration //RoundTrip的耗时,记入后端的健康统计
+}
+
+// recordStats record the health of the host,
+// call it after the success criteria are checked
+func (ar *apiHostRequest) recordStats(success bool) {
+	ar.apiHost.stats.record(ar.used, !success)
 }
 
+// newFallbackRequest same as req but to the fallback url
+func newFallbackRequest(req *http.Request, urlStr string, body io.Reader) *http.Request {
+	reqFallback, err := http.NewRequest(req.Method, urlStr, ioutil.NopCloser(body))
+	if err != nil {
+		log.Println("[error]build fallback req failed:", err)
+		return nil
+	}
+	reqFallback.Header = req.Header.Clone()
+	reqFallback.ContentLength = req.ContentLength
+	return reqFallback
+}
+
+// RoundTrip call the host,retry with the fallback url when failed to connect
 func (ar *apiHostRequest) RoundTrip() (resp *http.Response, err error) {
-	isTimeout := false
-	time.AfterFunc(ar.Timeout, func() {
-		if ar.isDone {
-			return
-		}
-		ar.transport.CancelRequest(ar.req)
-		isTimeout = true
+	start := time.Now()
+	defer func() {
+		ar.used = time.Since(start)
+	}()
+	if ar.apiHost.TimeoutMs > 0 {
+		cancel := ar.withTotalTimeout(ar.Timeout)
+		defer func() {
+			if err != nil {
+				cancel()
+			} else {
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			}
+		}()
+	}
+	resp, err = ar.roundTrip()
+	if err == nil || ar.fallbackReq == nil || !isDialError(err) {
+		return resp, err
+	}
+	log.Println("[warning]host", ar.apiHost.Name, "connect", ar.urlNew, "failed:", err, ",try fallback:", ar.fallbackReq.URL.String())
+	ar.req = ar.fallbackReq
+	ar.urlNew = ar.fallbackReq.URL.String()
+	ar.isFallback = true
+	return ar.roundTrip()
+}
+
+func (ar *apiHostRequest) roundTrip() (resp *http.Response, err error) {
+	req := ar.req
+	timer := time.AfterFunc(ar.Timeout, func() {
+		ar.transport.CancelRequest(req)
 	})
-	resp, err = ar.transport.RoundTrip(ar.req)
-	ar.isDone = true
-	if isTimeout {
+	resp, err = ar.transport.RoundTrip(req)
+	//the timer has fired when it can not be stopped
+	if !timer.Stop() && err != nil {
 		err = fmt.Errorf("reuest timeout after:%s ", ar.Timeout)
 	}
 	return
 }
 
+// isDialError failed to connect
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
 var reqCookieDumpLine = regexp.MustCompile(`Cookie: .+\r\n`)
 
 func (apiServer *APIServer) initBroadCastData(req *http.Request) *BroadCastData {
@@ -445,7 +827,7 @@ func (apiServer *APIServer) broadcastAPIReq(api *apiStruct, data *BroadCastData)
 	}
 }
 
-//判断是否需要将数据广播出去：有用户打开了页面在进行查看才广播
+// 判断是否需要将数据广播出去：有用户打开了页面在进行查看才广播
 func (apiServer *APIServer) needBroadcast(api *apiStruct) bool {
 	if apiServer.needStore() {
 		return true
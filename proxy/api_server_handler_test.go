@@ -0,0 +1,350 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBackendStatus(t *testing.T, code int) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(code)
+		rw.Write([]byte(http.StatusText(code)))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test_HandlerStatusRemap(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	b422 := testBackendStatus(t, 422)
+	b500 := testBackendStatus(t, 500)
+	remap := `"status_remap":{"422":400}`
+	testLoadAPI(t, apiServer, "s422", `{"path":"/s422/","enable":true,`+remap+`,"hosts":{"h1":{"url":"`+b422.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "s500", `{"path":"/s500/","enable":true,`+remap+`,"hosts":{"h1":{"url":"`+b500.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, body := testGet(t, ts.URL+"/s422/")
+	if resp.StatusCode != 400 {
+		t.Error("expect remapped 400,got:", resp.StatusCode)
+	}
+	if body != http.StatusText(422) {
+		t.Error("body should not change:", body)
+	}
+
+	resp, _ = testGet(t, ts.URL+"/s500/")
+	if resp.StatusCode != 500 {
+		t.Error("unmapped status should not change,got:", resp.StatusCode)
+	}
+}
+
+func Test_APIStatusRemapWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("wrong")
+	api.StatusRemap = map[int]int{422: 40}
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong status code")
+	}
+}
+
+func Test_HandlerBodyLimit(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bd, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(rw, "%d", len(bd))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "bl", `{"path":"/bl/","enable":true,"body_limit":{"get":10,"post":0},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	big := strings.Repeat("a", 1000)
+	do := func(method string, body string) (int, string) {
+		req, _ := http.NewRequest(method, ts.URL+"/bl/", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(bd)
+	}
+
+	if code, _ := do("GET", big); code != http.StatusRequestEntityTooLarge {
+		t.Error("large GET should be rejected,got:", code)
+	}
+	if code, body := do("GET", "small"); code != 200 || body != "5" {
+		t.Error("small GET should pass,got:", code, body)
+	}
+	if code, body := do("POST", big); code != 200 || body != "1000" {
+		t.Error("large POST should pass,got:", code, body)
+	}
+}
+
+func Test_HandlerMaxURLLength(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	api := testLoadAPI(t, apiServer, "ul", `{"path":"/ul/","enable":true,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	if api.MaxURLLength != defaultMaxURLLength {
+		t.Error("expect default max_url_length,got:", api.MaxURLLength)
+	}
+	testLoadAPI(t, apiServer, "ul_short", `{"path":"/ul_short/","enable":true,"max_url_length":20,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	if resp, body := testGet(t, ts.URL+"/ul/a?q="+strings.Repeat("a", 100)); resp.StatusCode != 200 || body != "ok" {
+		t.Error("normal url should pass,got:", resp.StatusCode, body)
+	}
+	if resp, _ := testGet(t, ts.URL+"/ul/a?q="+strings.Repeat("a", defaultMaxURLLength)); resp.StatusCode != http.StatusRequestURITooLong {
+		t.Error("expect 414 for long url,got:", resp.StatusCode)
+	}
+	if resp, _ := testGet(t, ts.URL+"/ul_short/?q=1"); resp.StatusCode != 200 {
+		t.Error("expect 200,got:", resp.StatusCode)
+	}
+	if resp, _ := testGet(t, ts.URL+"/ul_short/?q=123456789"); resp.StatusCode != http.StatusRequestURITooLong {
+		t.Error("expect 414,got:", resp.StatusCode)
+	}
+}
+
+func Test_HandlerBackendResetMidBody(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("partial body"))
+		rw.(http.Flusher).Flush()
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "reset", `{"path":"/reset/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	//the client fails either on the response or on the body,
+	//the proxy must not end the response as a complete one
+	resp, err := http.Get(ts.URL + "/reset/")
+	if err == nil {
+		defer resp.Body.Close()
+		var bd []byte
+		bd, err = ioutil.ReadAll(resp.Body)
+		if err == nil {
+			t.Fatal("client should see an error,got complete body:", resp.StatusCode, string(bd))
+		}
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		t.Error("expect unexpected EOF,got:", err)
+	}
+}
+
+func Test_HandlerHostFallback(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	fallback := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bd, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(rw, "fallback:%s:%s", req.URL.Path, bd)
+	}))
+	defer fallback.Close()
+	//a closed server,connect will be refused
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primary.Close()
+
+	testLoadAPI(t, apiServer, "fb", `{"path":"/fb/","enable":true,"hosts":{"h1":{"url":"`+primary.URL+`/","enable":true,
+		"fallback_url":"`+fallback.URL+`/"}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, err := http.Post(ts.URL+"/fb/a", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	bd, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(bd) != "fallback:/a:hello" {
+		t.Error("expect response from fallback,got:", resp.StatusCode, string(bd))
+	}
+
+	testLoadAPI(t, apiServer, "nofb", `{"path":"/nofb/","enable":true,"hosts":{"h1":{"url":"`+primary.URL+`/","enable":true}}}`)
+	if resp, _ := testGet(t, ts.URL+"/nofb/a"); resp.StatusCode != http.StatusBadGateway {
+		t.Error("expect 502 without fallback,got:", resp.StatusCode)
+	}
+}
+
+func Test_HandlerContentLengthMismatch(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	//declares more bytes than it sends,then closes the connection
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		conn, buf, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\nContent-Type: text/plain\r\n\r\nshort")
+		buf.Flush()
+		conn.Close()
+	}))
+	defer backend.Close()
+	api := testLoadAPI(t, apiServer, "cl_lie", `{"path":"/cl_lie/","enable":true,"access_log":"only",
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, err := http.Get(ts.URL + "/cl_lie/")
+	if err == nil {
+		bd, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			t.Error("client should see an error,got complete body:", resp.StatusCode, string(bd))
+		}
+	}
+
+	var line string
+	for i := 0; i < 100 && line == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ := ioutil.ReadFile(api.accessLogPath())
+		for _, l := range strings.Split(string(data), "\n") {
+			if strings.Contains(l, "logindex=1/") {
+				line = l
+			}
+		}
+	}
+	if !strings.Contains(line, "length_mismatch:5/100") {
+		t.Error("expect length_mismatch in log,got:", line)
+	}
+}
+
+func Test_HandlerShadowMaxBody(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	hits := make(chan string, 10)
+	newBackend := func(name string) *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			ioutil.ReadAll(req.Body)
+			hits <- name
+			rw.Write([]byte(name))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	b1 := newBackend("h1")
+	b2 := newBackend("h2")
+	api := testLoadAPI(t, apiServer, "smb", `{"path":"/smb/","enable":true,"shadow_max_body":10,"default_master":"h1","access_log":"only",
+		"hosts":{"h1":{"url":"`+b1.URL+`/","enable":true},"h2":{"url":"`+b2.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	post := func(body string) []string {
+		resp, err := http.Post(ts.URL+"/smb/", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		var got []string
+		timeout := time.After(300 * time.Millisecond)
+		for {
+			select {
+			case name := <-hits:
+				got = append(got, name)
+			case <-timeout:
+				return got
+			}
+		}
+	}
+	if got := post("small"); len(got) != 2 {
+		t.Error("small request should be sent to all hosts,got:", got)
+	}
+	if got := post(strings.Repeat("a", 11)); len(got) != 1 || got[0] != "h1" {
+		t.Error("large request should be sent to the master only,got:", got)
+	}
+
+	data, _ := ioutil.ReadFile(api.accessLogPath())
+	var lines []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if strings.Contains(l, "logindex=1/") {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) != 2 || strings.Contains(lines[0], "skipped_large") || !strings.Contains(lines[1], "skipped_large:true") {
+		t.Error("expect skipped_large in the log of the large request,got:", lines)
+	}
+}
+
+func Test_HandlerRequestIDHeader(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.ServerVhostConf.RequestIDHeader = "X-Correlation-Id"
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.Header.Get("X-Correlation-Id") + "|" + req.Header.Get("X-Request-Id")))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "rid", `{"path":"/rid/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(id string) (string, string) {
+		req, _ := http.NewRequest("GET", ts.URL+"/rid/", nil)
+		if id != "" {
+			req.Header.Set("X-Correlation-Id", id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp.Header.Get("X-Correlation-Id"), string(bd)
+	}
+	if respID, body := get("abc-123"); respID != "abc-123" || body != "abc-123|" {
+		t.Error("the inbound id should be kept,got:", respID, body)
+	}
+	respID, body := get("")
+	if respID == "" || body != respID+"|" {
+		t.Error("expect generated id forwarded and returned,got:", respID, body)
+	}
+
+	apiServer.ServerVhostConf.RequestIDHeader = ""
+	if respID, body := get("abc-123"); respID != "" || body != "abc-123|" {
+		t.Error("expect not changed when not configured,got:", respID, body)
+	}
+}
+
+func Test_HandlerClientCancelMidCopy(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	started := make(chan bool, 1)
+	aborted := make(chan bool, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("partial body"))
+		rw.(http.Flusher).Flush()
+		started <- true
+		select {
+		case <-req.Context().Done():
+			aborted <- true
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "cancel", `{"path":"/cancel/","enable":true,"timeout_ms":20000,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/cancel/", nil)
+	go (func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+	})()
+
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("backend not called")
+	}
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(3 * time.Second):
+		t.Error("the backend request should be aborted when the client is gone")
+	}
+}
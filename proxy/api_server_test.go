@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestAPIServer(t *testing.T) *APIServer {
+	dir := t.TempDir()
+	manager := &APIServerManager{
+		ConfPath: filepath.Join(dir, "server.json"),
+		mainConf: &mainConf{Users: users{"admin"}},
+	}
+	//the tests pretend to be the callers behind a front proxy on 127.0.0.1 by X-Real-Ip
+	vhost := &serverVhost{Id: "test", Port: 8080, Enable: true, Users: NewUsers(), TrustedProxies: []string{"127.0.0.1"}}
+	apiServer, err := newAPIServer(vhost, manager)
+	if err != nil {
+		t.Fatal("new api server failed:", err)
+	}
+	return apiServer
+}
+
+// testLoadAPI write the api conf file and load it
+func testLoadAPI(t *testing.T, apiServer *APIServer, apiID string, conf string) *apiStruct {
+	confPath := filepath.Join(apiServer.getConfDir(), apiID+".json")
+	DirCheck(confPath)
+	if err := ioutil.WriteFile(confPath, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiServer.loadAPI(apiID); err != nil {
+		t.Fatal("load api failed:", err)
+	}
+	return apiServer.getAPIByID(apiID)
+}
+
+func testBackend(t *testing.T, body string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testServe(t *testing.T, apiServer *APIServer) *httptest.Server {
+	ts := httptest.NewServer(apiServer)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testGet(t *testing.T, urlStr string) (*http.Response, string) {
+	resp, err := http.Get(urlStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	bd, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(bd)
+}
+
+func Test_APIServerServe(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "hello")
+	testLoadAPI(t, apiServer, "a", `{"path":"/a/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, body := testGet(t, ts.URL+"/a/b")
+	if resp.StatusCode != 200 || body != "hello" {
+		t.Error("wrong response:", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Api-Front-Master") != "h1" {
+		t.Error("wrong master:", resp.Header.Get("Api-Front-Master"))
+	}
+
+	resp, _ = testGet(t, ts.URL+"/not_exists/")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error("expect 404,got:", resp.StatusCode)
+	}
+}
+
+func Test_CheckConfDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "api_test")
+	if err := checkConfDir(dir); err != nil {
+		t.Fatal("create missing dir failed:", err)
+	}
+	if !FileExists(dir) {
+		t.Error("conf dir not created")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "api_file")
+	ioutil.WriteFile(filePath, []byte("x"), 0644)
+	if err := checkConfDir(filePath); err == nil {
+		t.Error("expect error when conf dir is a file")
+	}
+
+	if os.Geteuid() == 0 {
+		t.Log("skip unwritable dir check for root")
+		return
+	}
+	roDir := filepath.Join(t.TempDir(), "ro")
+	os.Mkdir(roDir, 0555)
+	if err := checkConfDir(roDir); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Error("expect not writable error,got:", err)
+	}
+}
+
+func Test_APIServerReloadWhileServing(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "r", `{"path":"/r/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "r2", `{"path":"/r2/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+
+	ts := testServe(t, apiServer)
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			apiServer.loadAPI("r")
+			apiServer.loadAPI("r2")
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var failed int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, p := range []string{"/r/a", "/r2/a"} {
+					resp, err := http.Get(ts.URL + p)
+					if err != nil || resp.StatusCode != 200 {
+						atomic.AddInt32(&failed, 1)
+					}
+					if err == nil {
+						resp.Body.Close()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if failed > 0 {
+		t.Error("requests failed while reloading:", failed)
+	}
+}
+
+func Test_APIServerNormalizePath(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.Method + " " + req.URL.Path))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "ns", `{"path":"/ns/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	cases := []struct {
+		method string
+		path   string
+		expect string
+	}{
+		{"GET", "//ns//a", "GET /a"},
+		{"GET", "/ns//a//b/", "GET /a/b/"},
+		{"GET", "/x/../ns/./a", "GET /a"},
+		{"get", "/ns/a", "GET /a"},
+	}
+	for _, c := range cases {
+		//send the request line as is
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(conn, "%s %s HTTP/1.0\r\nHost: 127.0.0.1\r\n\r\n", c.method, c.path)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bd, _ := ioutil.ReadAll(resp.Body)
+		conn.Close()
+		if resp.StatusCode != 200 || string(bd) != c.expect {
+			t.Error(c.method, c.path, "expect:", c.expect, "got:", resp.StatusCode, string(bd))
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// shadowDiffMaxBody the larger bodies are compared by status and length only
+const shadowDiffMaxBody = respAssertMaxBody
+
+// shadowDiffMaxPaths how many different json paths are in the excerpt
+const shadowDiffMaxPaths = 5
+
+// diffResp the response compared,body is decoded when gzipped
+type diffResp struct {
+	status int
+	isJSON bool
+	body   []byte //nil when larger than shadowDiffMaxBody
+	size   int64
+}
+
+func newDiffResp(resp *http.Response, body []byte, size int64) *diffResp {
+	dr := &diffResp{
+		status: resp.StatusCode,
+		size:   size,
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	dr.isJSON = mediaType == "application/json"
+	if size > shadowDiffMaxBody {
+		return dr
+	}
+	dr.body = body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		if gr, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			dr.body, _ = ioutil.ReadAll(gr)
+			gr.Close()
+		}
+	}
+	dr.size = int64(len(dr.body))
+	return dr
+}
+
+// DiffResult how the responses of the shadow hosts differ from the master's,
+// logged as shadow_diff
+type DiffResult struct {
+	Master   string               `json:"master"`
+	Differed []string             `json:"differed"` //响应和master不同的host
+	Hosts    map[string]*HostDiff `json:"hosts"`
+
+	mu     sync.Mutex
+	master *diffResp
+	cmp    BodyComparator //the comparator of the api,nil when not set
+}
+
+// HostDiff the difference of one shadow host
+type HostDiff struct {
+	Status      int    `json:"status"`
+	StatusDiff  bool   `json:"status_diff,omitempty"`
+	LengthDelta int64  `json:"length_delta"`        //body长度之差,shadow-master
+	BodyDiff    string `json:"body_diff,omitempty"` //不同之处的摘要
+	Error       string `json:"error,omitempty"`
+}
+
+func newDiffResult(masterName string, master *diffResp, cmp BodyComparator) *DiffResult {
+	return &DiffResult{
+		Master:   masterName,
+		Differed: []string{},
+		Hosts:    make(map[string]*HostDiff),
+		master:   master,
+		cmp:      cmp,
+	}
+}
+
+// shadowComparator the comparator set by the api,
+// nil when not set,then the json is compared by keys and the others as bytes
+func (api *apiStruct) shadowComparator() BodyComparator {
+	if api.Comparator == nil {
+		return nil
+	}
+	return api.bodyComparator
+}
+
+// add compare the response of the shadow host with the master's
+func (dr *DiffResult) add(hostName string, shadow *diffResp) {
+	hd := &HostDiff{
+		Status:      shadow.status,
+		StatusDiff:  shadow.status != dr.master.status,
+		LengthDelta: shadow.size - dr.master.size,
+	}
+	switch {
+	case dr.master.body == nil || shadow.body == nil:
+		if hd.LengthDelta != 0 {
+			hd.BodyDiff = "body too large,length differs"
+		}
+	case dr.cmp != nil:
+		hd.BodyDiff = dr.compareBody(shadow)
+	case dr.master.isJSON && shadow.isJSON:
+		hd.BodyDiff = diffJSONBody(dr.master.body, shadow.body, nil)
+	default:
+		if err := (bytesComparator{}).Compare(dr.master.body, shadow.body); err != nil {
+			hd.BodyDiff = err.Error()
+		}
+	}
+	dr.set(hostName, hd)
+}
+
+// compareBody compare by the comparator of the api,
+// the summary has the json paths when the json comparator is used
+func (dr *DiffResult) compareBody(shadow *diffResp) string {
+	err := dr.cmp.Compare(dr.master.body, shadow.body)
+	if err == nil {
+		return ""
+	}
+	cmp := dr.cmp
+	if tc, ok := cmp.(tieredComparator); ok && tc.tier(dr.master.body, shadow.body) == compareTierFull {
+		cmp = tc.full
+	}
+	if n, ok := cmp.(jsonNormalizer); ok && dr.master.isJSON && shadow.isJSON {
+		if excerpt := diffJSONBody(dr.master.body, shadow.body, n.normalize); excerpt != "" {
+			return excerpt
+		}
+	}
+	return err.Error()
+}
+
+// addError the shadow host failed
+func (dr *DiffResult) addError(hostName string, err error) {
+	dr.set(hostName, &HostDiff{Error: err.Error()})
+}
+
+func (dr *DiffResult) set(hostName string, hd *HostDiff) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.Hosts[hostName] = hd
+	if hd.StatusDiff || hd.BodyDiff != "" || hd.Error != "" {
+		dr.Differed = append(dr.Differed, hostName)
+		sort.Strings(dr.Differed)
+	}
+}
+
+// hasDiff any shadow host differs
+func (dr *DiffResult) hasDiff() bool {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	return len(dr.Differed) > 0
+}
+
+func (dr *DiffResult) String() string {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	bs, _ := json.Marshal(dr)
+	return string(bs)
+}
+
+// diffJSONBody compare by keys,the key order and spaces are ignored,
+// the values are normalized before compared when normalize is not nil,
+// compare as bytes when not json
+func diffJSONBody(expect, actual []byte, normalize func(v interface{}) interface{}) string {
+	expectVal, errExpect := decodeJSONValue(expect)
+	actualVal, errActual := decodeJSONValue(actual)
+	if errExpect != nil || errActual != nil {
+		if err := (bytesComparator{}).Compare(expect, actual); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	if normalize != nil {
+		expectVal = normalize(expectVal)
+		actualVal = normalize(actualVal)
+	}
+	paths := jsonDiffPaths(expectVal, actualVal, "", nil)
+	if len(paths) == 0 {
+		return ""
+	}
+	more := ""
+	if len(paths) > shadowDiffMaxPaths {
+		more = fmt.Sprintf(" and %d more", len(paths)-shadowDiffMaxPaths)
+		paths = paths[:shadowDiffMaxPaths]
+	}
+	return "json differs at " + strings.Join(paths, ",") + more
+}
+
+// jsonDiffPaths the paths where the values differ,eg "data.list.0.id",
+// "." is the root
+func jsonDiffPaths(expect, actual interface{}, prefix string, paths []string) []string {
+	subPath := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch ev := expect.(type) {
+	case map[string]interface{}:
+		av, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(ev)+len(av))
+		for k := range ev {
+			keys = append(keys, k)
+		}
+		for k := range av {
+			if _, has := ev[k]; !has {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			paths = jsonDiffPaths(ev[k], av[k], subPath(k), paths)
+		}
+		return paths
+	case []interface{}:
+		av, ok := actual.([]interface{})
+		if !ok || len(av) != len(ev) {
+			break
+		}
+		for i := range ev {
+			paths = jsonDiffPaths(ev[i], av[i], subPath(strconv.Itoa(i)), paths)
+		}
+		return paths
+	}
+	if reflect.DeepEqual(expect, actual) {
+		return paths
+	}
+	if prefix == "" {
+		prefix = "."
+	}
+	return append(paths, prefix)
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testJSONBackend(t *testing.T, status int, body string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+		rw.WriteHeader(status)
+		rw.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test_HandlerShadowDiff(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	master := testJSONBackend(t, 200, `{"a":1,"b":[1,2]}`)
+	same := testJSONBackend(t, 200, `{"b":[1, 2], "a":1}`)
+	candidate := testJSONBackend(t, 500, `{"a":1,"b":[1,3],"c":true}`)
+	api := testLoadAPI(t, apiServer, "sd", `{"path":"/sd/","enable":true,"access_log":"only",
+		"shadow_diff":true,"default_master":"h1","hosts":{
+		"h1":{"url":"`+master.URL+`/","enable":true},
+		"h2":{"url":"`+same.URL+`/","enable":true},
+		"h3":{"url":"`+candidate.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	before := testExpvarValue(expvarShadowDiffs, "test/sd")
+	resp, bd := testGet(t, ts.URL+"/sd/a")
+	if resp.StatusCode != 200 || bd != `{"a":1,"b":[1,2]}` {
+		t.Fatal("the client should get the master's response", resp.StatusCode, bd)
+	}
+
+	//the shadows are logged after the response is sent
+	var line string
+	for i := 0; i < 100 && line == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ := ioutil.ReadFile(api.accessLogPath())
+		for _, l := range strings.Split(string(data), "\n") {
+			if strings.Contains(l, "shadow_diff:") {
+				line = l
+			}
+		}
+	}
+	expects := []string{
+		`"master":"h1"`,
+		`"differed":["h3"]`,
+		`"h2":{"status":200,"length_delta":2}`,
+		`"h3":{"status":500,"status_diff":true,"length_delta":9,"body_diff":"json differs at b.1,c"}`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(line, expect) {
+			t.Error("expect", expect, "in shadow_diff,got:", line)
+		}
+	}
+	if got := testExpvarValue(expvarShadowDiffs, "test/sd"); got != before+1 {
+		t.Error("shadow diffs not counted", before, got)
+	}
+}
+
+func Test_HandlerShadowDiffComparator(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	master := testJSONBackend(t, 200, `{"a":1,"b":2,"ts":100}`)
+	same := testJSONBackend(t, 200, `{"a":1.0,"b":2,"ts":200}`)
+	candidate := testJSONBackend(t, 200, `{"a":1,"b":3,"ts":300}`)
+	api := testLoadAPI(t, apiServer, "sdc", `{"path":"/sdc/","enable":true,"access_log":"only",
+		"comparator":{"type":"ignore_fields","ignore_fields":["ts"],"normalize_numbers":true},
+		"shadow_diff":true,"default_master":"h1","hosts":{
+		"h1":{"url":"`+master.URL+`/","enable":true},
+		"h2":{"url":"`+same.URL+`/","enable":true},
+		"h3":{"url":"`+candidate.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	testGet(t, ts.URL+"/sdc/a")
+	var line string
+	for i := 0; i < 100 && line == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ := ioutil.ReadFile(api.accessLogPath())
+		for _, l := range strings.Split(string(data), "\n") {
+			if strings.Contains(l, "shadow_diff:") {
+				line = l
+			}
+		}
+	}
+	//the ignored field and 1 vs 1.0 are not differences
+	expects := []string{
+		`"differed":["h3"]`,
+		`"h2":{"status":200,"length_delta":2}`,
+		`"body_diff":"json differs at b"`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(line, expect) {
+			t.Error("expect", expect, "in shadow_diff,got:", line)
+		}
+	}
+}
+
+func Test_DiffJSONBody(t *testing.T) {
+	cases := []struct {
+		expect string
+		actual string
+		diff   string
+	}{
+		{`{"a":1,"b":{"c":2}}`, `{"b":{"c":2},"a":1}`, ""},
+		{`{"a":1,"b":{"c":2}}`, `{"a":1,"b":{"c":3,"d":4}}`, "json differs at b.c,b.d"},
+		{`[1,2]`, `[1,2,3]`, "json differs at ."},
+		{`{"a":[1,2,3,4,5,6]}`, `{"a":[0,0,0,0,0,0]}`, "json differs at a.0,a.1,a.2,a.3,a.4 and 1 more"},
+		{`{"a":1}`, `not json`, `body mismatch at byte 0,expect:"{\"a\":1}" actual:"not json"`},
+	}
+	for _, c := range cases {
+		if got := diffJSONBody([]byte(c.expect), []byte(c.actual), nil); got != c.diff {
+			t.Error(c.expect, c.actual, "expect:", c.diff, "got:", got)
+		}
+	}
+}
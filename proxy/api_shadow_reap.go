@@ -0,0 +1,32 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// shadowMaxLifetime the hard limit of a shadow request,
+// whatever timeout_ms is,so the fan-out goroutines can not pile up
+var shadowMaxLifetime = 5 * time.Minute
+
+// withLifetime bound the requests by a deadline,
+// call the returned func when done,it reports whether the request is reaped
+func (ar *apiHostRequest) withLifetime(d time.Duration) (done func() bool) {
+	parent := ar.req.Context()
+	ctx, cancel := context.WithTimeout(parent, d)
+	ar.req = ar.req.WithContext(ctx)
+	if ar.fallbackReq != nil {
+		ar.fallbackReq = ar.fallbackReq.WithContext(ctx)
+	}
+	return func() bool {
+		reaped := ctx.Err() == context.DeadlineExceeded && parent.Err() == nil
+		cancel()
+		return reaped
+	}
+}
+
+func (api *apiStruct) shadowReaped(ar *apiHostRequest) {
+	log.Println("[warning]shadow request reaped", api.ID, ar.apiHost.Name, ar.urlNew)
+	expvarShadowReaped.Add(api.expvarKey(), 1)
+}
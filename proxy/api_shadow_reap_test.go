@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_HandlerShadowReaped(t *testing.T) {
+	old := shadowMaxLifetime
+	shadowMaxLifetime = 200 * time.Millisecond
+	defer func() { shadowMaxLifetime = old }()
+
+	released := make(chan bool, 1)
+	stop := make(chan bool)
+	hang := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+			released <- true
+		case <-stop:
+		}
+	}))
+	defer hang.Close()
+	defer close(stop)
+
+	apiServer := newTestAPIServer(t)
+	master := testBackend(t, "ok")
+	//the timeout is too long
+	testLoadAPI(t, apiServer, "reap", `{"path":"/reap/","enable":true,"timeout_ms":3600000,"default_master":"m",
+		"hosts":{"m":{"url":"`+master.URL+`/","enable":true},"hang":{"url":"`+hang.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	reapedBefore := testExpvarValue(expvarShadowReaped, "test/reap")
+	if _, body := testGet(t, ts.URL+"/reap/"); body != "ok" {
+		t.Fatal("wrong body:", body)
+	}
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shadow request is not reaped")
+	}
+	for i := 0; i < 100 && testExpvarValue(expvarShadowReaped, "test/reap") == reapedBefore; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := testExpvarValue(expvarShadowReaped, "test/reap") - reapedBefore; n != 1 {
+		t.Error("expect 1 reaped,got:", n)
+	}
+}
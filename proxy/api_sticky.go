@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+type stickyKeyCtxKey struct{}
+
+// withStickyKey get the sticky key from the json body by StickyJSONPath,
+// the key is carried by the context of the returned request
+func (api *apiStruct) withStickyKey(req *http.Request, body []byte) (*http.Request, string) {
+	if api.StickyJSONPath == "" || len(body) == 0 {
+		return req, ""
+	}
+	key, ok := jsonScalarAt(body, api.StickyJSONPath)
+	if !ok {
+		return req, ""
+	}
+	return req.WithContext(context.WithValue(req.Context(), stickyKeyCtxKey{}, key)), key
+}
+
+// jsonScalarAt the string/number/bool value at the path(eg context.transaction_id) of the json
+func jsonScalarAt(body []byte, path string) (string, bool) {
+	v, err := decodeJSONValue(body)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if v, ok = m[name]; !ok {
+			return "", false
+		}
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+func stickyKeyOf(req *http.Request) string {
+	key, _ := req.Context().Value(stickyKeyCtxKey{}).(string)
+	return key
+}
+
+// stickyHostName the same key always gets the same host when the hosts are not changed,
+// and only the keys of the removed host move when one is removed (rendezvous hashing)
+func stickyHostName(key string, names []string) string {
+	var best string
+	var bestScore uint64
+	for _, name := range names {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		if score := h.Sum64(); best == "" || score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
+// stickyMaxFails the host with so many consecutive failures is skipped by the sticky keys
+const stickyMaxFails = 3
+
+// stickyHealthyNames the hosts without stickyMaxFails consecutive failures,
+// all of them when none is healthy
+func (api *apiStruct) stickyHealthyNames(names []string) []string {
+	var healthy []string
+	for _, name := range names {
+		if fails, _ := api.Hosts[name].stats.get(); fails < stickyMaxFails {
+			healthy = append(healthy, name)
+		}
+	}
+	if len(healthy) == 0 {
+		return names
+	}
+	return healthy
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_APIStickyMaster(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "sticky", `{"path":"/sticky/","enable":true,"sticky_json_path":"context.transaction_id","hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true},
+		"h3":{"url":"http://127.0.0.1:3/","enable":true}
+	}}`)
+
+	master := func(body string) string {
+		req, _ := http.NewRequest("POST", "http://127.0.0.1/sticky/", strings.NewReader(body))
+		req, _ = api.withStickyKey(req, []byte(body))
+		_, name, _ := api.getAPIHostsByReq(req)
+		return name
+	}
+	used := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		body := fmt.Sprintf(`{"context":{"transaction_id":"tx_%d","action":"search"}}`, i)
+		name := master(body)
+		used[name] = true
+		for j := 0; j < 5; j++ {
+			if m := master(strings.Replace(body, "search", "on_search", 1)); m != name {
+				t.Fatal("same transaction_id should have the same master,", name, m)
+			}
+		}
+	}
+	if len(used) < 2 {
+		t.Error("the keys should be spread over the hosts:", used)
+	}
+
+	req, _ := http.NewRequest("POST", "http://127.0.0.1/sticky/", nil)
+	if _, key := api.withStickyKey(req, []byte(`{"context":{}}`)); key != "" {
+		t.Error("expect no key,got:", key)
+	}
+	if _, key := api.withStickyKey(req, []byte(`{"context":{"transaction_id":123}}`)); key != "123" {
+		t.Error("number key wrong:", key)
+	}
+}
+
+func Test_APIStickyMasterFailed(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "sticky_f", `{"path":"/sticky_f/","enable":true,"sticky_json_path":"id","hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true},
+		"h3":{"url":"http://127.0.0.1:3/","enable":true}
+	}}`)
+	master := func() string {
+		body := `{"id":"tx_1"}`
+		req, _ := http.NewRequest("POST", "http://127.0.0.1/sticky_f/", strings.NewReader(body))
+		req, _ = api.withStickyKey(req, []byte(body))
+		_, name, _ := api.getAPIHostsByReq(req)
+		return name
+	}
+	owner := master()
+	for i := 0; i < stickyMaxFails; i++ {
+		api.Hosts[owner].stats.record(time.Millisecond, true)
+	}
+	moved := master()
+	if moved == owner {
+		t.Error("the key should move from the failed host:", owner)
+	}
+
+	//all failed,the owner is used again
+	for name, host := range api.Hosts {
+		if name != owner {
+			for i := 0; i < stickyMaxFails; i++ {
+				host.stats.record(time.Millisecond, true)
+			}
+		}
+	}
+	if name := master(); name != owner {
+		t.Error("expect the owner when all hosts failed,got:", name)
+	}
+
+	//recovered
+	api.Hosts[owner].stats.record(time.Millisecond, false)
+	if name := master(); name != owner {
+		t.Error("expect the owner after recovered,got:", name)
+	}
+}
+
+func Test_StickyHostName(t *testing.T) {
+	names := []string{"h1", "h2", "h3"}
+	moved := 0
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		name := stickyHostName(key, names)
+		var left []string
+		for _, n := range names {
+			if n != "h3" {
+				left = append(left, n)
+			}
+		}
+		//only the keys on the removed host move
+		if nameNow := stickyHostName(key, left); nameNow != name {
+			if name != "h3" {
+				t.Fatal("key moved:", key, name, nameNow)
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("some keys should be on h3")
+	}
+}
@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"io"
+	"mime"
+	"net/http"
+)
+
+// isStreamResp the response is a stream (server-sent events),
+// which must be sent to the client as soon as it arrives
+func isStreamResp(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// flushWriter flush the response after each write
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, fw.rc.Flush()
+}
+
+// streamWriter wrap the writer of the master's response body to flush each chunk,
+// the proxies in front (nginx) are told not to buffer it either
+func streamWriter(w io.Writer, rw http.ResponseWriter) io.Writer {
+	rw.Header().Set("X-Accel-Buffering", "no")
+	return &flushWriter{w: w, rc: http.NewResponseController(rw)}
+}
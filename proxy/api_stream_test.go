@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_HandlerStreamFlush(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	next := make(chan struct{}, 2)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		for i := 1; i <= 2; i++ {
+			fmt.Fprintf(rw, "data: event_%d\n\n", i)
+			rw.(http.Flusher).Flush()
+			select {
+			case <-next:
+			case <-time.After(5 * time.Second):
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+	var shadowCalled int32
+	shadow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&shadowCalled, 1)
+	}))
+	defer shadow.Close()
+	testLoadAPI(t, apiServer, "sse", `{"path":"/sse/","enable":true,"default_master":"h1",
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true},"h2":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, err := http.Get(ts.URL + "/sse/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Error("expect X-Accel-Buffering:no,got:", resp.Header.Get("X-Accel-Buffering"))
+	}
+	br := bufio.NewReader(resp.Body)
+	for i := 1; i <= 2; i++ {
+		//the backend waits for the client before the next event,
+		//so the event must be received before the response ends
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal("read event failed:", err)
+		}
+		if want := fmt.Sprintf("data: event_%d", i); strings.TrimSpace(line) != want {
+			t.Fatal("expect", want, "got:", line)
+		}
+		br.ReadString('\n')
+		next <- struct{}{}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&shadowCalled); n != 0 {
+		t.Error("expect no shadow call for stream,got:", n)
+	}
+}
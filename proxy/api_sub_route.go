@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SubRoute route the sub path under api path to part of the hosts
+type SubRoute struct {
+	Path  string   `json:"path"`  //相对api绑定路径的前缀,如 /v1/ ,也可写作 /v1/*
+	Hosts []string `json:"hosts"` //只转发到这些host
+}
+
+func (sr *SubRoute) init(api *apiStruct) error {
+	sr.Path = "/" + strings.TrimLeft(strings.TrimSuffix(sr.Path, "*"), "/")
+	if len(sr.Hosts) == 0 {
+		return fmt.Errorf("sub_route [%s] has no hosts", sr.Path)
+	}
+	for _, name := range sr.Hosts {
+		if _, has := api.Hosts[name]; !has {
+			return fmt.Errorf("sub_route [%s] host [%s] not exists", sr.Path, name)
+		}
+	}
+	return nil
+}
+
+func (api *apiStruct) initSubRoutes() error {
+	for _, sr := range api.SubRoutes {
+		if err := sr.init(api); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subRouteHosts get the hosts of the first matched sub route,
+// nil when no sub route matched (all hosts)
+func (api *apiStruct) subRouteHosts(urlPath string) []string {
+	if len(api.SubRoutes) == 0 || !strings.HasPrefix(urlPath, api.Path) {
+		return nil
+	}
+	relPath := "/" + strings.TrimLeft(urlPath[len(api.Path):], "/")
+	for _, sr := range api.SubRoutes {
+		//match by path segment,/v1 is not the prefix of /v10
+		if relPath == sr.Path || strings.HasPrefix(relPath, strings.TrimSuffix(sr.Path, "/")+"/") {
+			return sr.Hosts
+		}
+	}
+	return nil
+}
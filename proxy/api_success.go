@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SuccessCriteria when the master's response counts as success,for the error metrics
+// and the all-fail cooldown. the default is a status code less than 500
+type SuccessCriteria struct {
+	Status    []string `json:"status"`     //成功的状态码,如["2xx","304"],x匹配任意数字,为空则小于500都是成功
+	JSONPath  string   `json:"json_path"`  //json body中该路径(如 message.ack.status)的值等于json_value时才是成功
+	JSONValue string   `json:"json_value"` //
+}
+
+func (sc *SuccessCriteria) init() error {
+	for _, pattern := range sc.Status {
+		if len(pattern) != 3 {
+			return fmt.Errorf("status wrong:%s", pattern)
+		}
+		for _, c := range pattern {
+			if c != 'x' && (c < '0' || c > '9') {
+				return fmt.Errorf("status wrong:%s", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func (sc *SuccessCriteria) statusSuccess(code int) bool {
+	if len(sc.Status) == 0 {
+		return code < 500
+	}
+	codeStr := strconv.Itoa(code)
+	for _, pattern := range sc.Status {
+		if statusPatternMatch(pattern, codeStr) {
+			return true
+		}
+	}
+	return false
+}
+
+func statusPatternMatch(pattern string, code string) bool {
+	if len(pattern) != len(code) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != 'x' && pattern[i] != code[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// needBody the body must be kept to check
+func (sc *SuccessCriteria) needBody() bool {
+	return sc != nil && sc.JSONPath != ""
+}
+
+// statusSuccess check the status only,the body is checked after it is sent
+func (api *apiStruct) statusSuccess(code int) bool {
+	if api.SuccessCriteria == nil {
+		return code < 500
+	}
+	return api.SuccessCriteria.statusSuccess(code)
+}
+
+// bodySuccess check the body by json_path,nil when success.
+// must be checked with needBody at first
+func (api *apiStruct) bodySuccess(resp *http.Response, body *bytes.Buffer) error {
+	bd := body.Bytes()
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		bd = []byte(gzipDocode(bytes.NewBuffer(bd)))
+	}
+	v, _ := jsonScalarAt(bd, api.SuccessCriteria.JSONPath)
+	if v != api.SuccessCriteria.JSONValue {
+		return fmt.Errorf("%s is %q,not %q", api.SuccessCriteria.JSONPath, v, api.SuccessCriteria.JSONValue)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_SuccessCriteriaStatus(t *testing.T) {
+	sc := &SuccessCriteria{Status: []string{"2xx", "304"}}
+	if err := sc.init(); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[int]bool{200: true, 204: true, 304: true, 301: false, 404: false, 500: false}
+	for code, want := range cases {
+		if got := sc.statusSuccess(code); got != want {
+			t.Errorf("status %d,expect %v,got %v", code, want, got)
+		}
+	}
+	if (&SuccessCriteria{}).statusSuccess(404) != true {
+		t.Error("expect 404 success by default")
+	}
+	if err := (&SuccessCriteria{Status: []string{"2x"}}).init(); err == nil {
+		t.Error("expect error for wrong status")
+	}
+}
+
+func Test_HandlerSuccessCriteriaBody(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"message":{"ack":{"status":"NACK"}}}`))
+	}))
+	defer backend.Close()
+
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "sc", `{"path":"/sc/","enable":true,"all_fail_threshold":2,"all_fail_cooldown_ms":60000,
+		"success":{"status":["2xx"],"json_path":"message.ack.status","json_value":"ACK"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "sc_ok", `{"path":"/sc_ok/","enable":true,"all_fail_threshold":2,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	errsBefore := testExpvarValue(expvarErrors, "test/sc")
+	for i := 0; i < 2; i++ {
+		resp, body := testGet(t, ts.URL+"/sc/a")
+		if resp.StatusCode != 200 || body != `{"message":{"ack":{"status":"NACK"}}}` {
+			t.Fatal("the response should not be changed:", resp.StatusCode, body)
+		}
+	}
+	if n := testExpvarValue(expvarErrors, "test/sc") - errsBefore; n != 2 {
+		t.Error("expect 2 errors,got:", n)
+	}
+	//the 200 with NACK are failures,so in cooldown now
+	if resp, _ := testGet(t, ts.URL+"/sc/a"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expect 503 in cooldown,got:", resp.StatusCode)
+	}
+	//without criteria,200 is success
+	for i := 0; i < 3; i++ {
+		if resp, _ := testGet(t, ts.URL+"/sc_ok/a"); resp.StatusCode != 200 {
+			t.Fatal("expect 200 without criteria,got:", resp.StatusCode)
+		}
+	}
+}
+
+func Test_HandlerSuccessCriteriaHostStats(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"message":{"ack":{"status":"NACK"}}}`))
+	}))
+	defer backend.Close()
+
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "scs", `{"path":"/scs/","enable":true,"default_master":"h1",
+		"success":{"status":["2xx"],"json_path":"message.ack.status","json_value":"ACK"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true},"h2":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	if resp, _ := testGet(t, ts.URL+"/scs/a"); resp.StatusCode != 200 {
+		t.Fatal("wrong status:", resp.StatusCode)
+	}
+	testWaitFanout(t)
+	//the 200 with NACK are failures of both the master and the shadow
+	for _, name := range []string{"h1", "h2"} {
+		if fails, _ := api.Hosts[name].stats.get(); fails != 1 {
+			t.Error("expect 1 failure of", name, ",got:", fails)
+		}
+	}
+}
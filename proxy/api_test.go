@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_APIMasterByHeader(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "canary", `{"path":"/canary/","enable":true,"hosts":{
+		"stable":{"url":"http://127.0.0.1:1/","enable":true},
+		"canary":{"url":"http://127.0.0.1:2/","enable":true,"match_header":{"name":"X-Canary","value":"true"}},
+		"beta":{"url":"http://127.0.0.1:3/","enable":true,"match_header":{"name":"X-Beta"}}
+	}}`)
+
+	cases := []struct {
+		header map[string]string
+		master string
+	}{
+		{nil, "stable"},
+		{map[string]string{"X-Canary": "true"}, "canary"},
+		{map[string]string{"X-Canary": "false"}, "stable"},
+		{map[string]string{"X-Beta": "any"}, "beta"},
+	}
+	for _, c := range cases {
+		//master selection is random among candidates,try more times
+		for i := 0; i < 10; i++ {
+			req, _ := http.NewRequest("GET", "http://127.0.0.1/canary/", nil)
+			for k, v := range c.header {
+				req.Header.Set(k, v)
+			}
+			hosts, master, _ := api.getAPIHostsByReq(req)
+			if master != c.master {
+				t.Fatal("header:", c.header, "expect master:", c.master, "got:", master)
+			}
+			if len(hosts) != 3 || hosts[0].Name != master {
+				t.Fatal("all hosts should be called,master first:", hosts)
+			}
+		}
+	}
+}
+
+func Test_APISubRoutes(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "sub", `{"path":"/sub/","enable":true,
+		"sub_routes":[{"path":"/v1/*","hosts":["old"]},{"path":"/v2/","hosts":["new1","new2"]},{"path":"/v4","hosts":["new1"]}],
+		"hosts":{
+		"old":{"url":"http://127.0.0.1:1/","enable":true},
+		"new1":{"url":"http://127.0.0.1:2/","enable":true},
+		"new2":{"url":"http://127.0.0.1:3/","enable":true}
+	}}`)
+
+	hostNames := func(urlPath string) (names []string, master string) {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1"+urlPath, nil)
+		hosts, master, _ := api.getAPIHostsByReq(req)
+		for _, h := range hosts {
+			names = append(names, h.Name)
+		}
+		return names, master
+	}
+
+	names, master := hostNames("/sub/v1/a/b")
+	if len(names) != 1 || master != "old" {
+		t.Error("v1 should route to old:", names, master)
+	}
+	names, master = hostNames("/sub/v2/a")
+	if len(names) != 2 || !InStringSlice("new1", names) || !InStringSlice("new2", names) || !InStringSlice(master, names) {
+		t.Error("v2 should route to new hosts:", names, master)
+	}
+	names, _ = hostNames("/sub/v3/a")
+	if len(names) != 3 {
+		t.Error("no sub route matched,should use all hosts:", names)
+	}
+	for _, p := range []string{"/sub/v4", "/sub/v4/a"} {
+		if names, _ = hostNames(p); len(names) != 1 || names[0] != "new1" {
+			t.Error(p, "should route to new1:", names)
+		}
+	}
+	if names, _ = hostNames("/sub/v40/a"); len(names) != 3 {
+		t.Error("/v4 should not match /v40,got:", names)
+	}
+}
+
+func Test_APISubRoutesWrongHost(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.SubRoutes = []*SubRoute{{Path: "/v1/", Hosts: []string{"not_exists"}}}
+	if err := api.init(); err == nil {
+		t.Error("expect error for not exists host")
+	}
+}
+
+func Test_APIDefaultMaster(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "dm", `{"path":"/dm/","enable":true,"default_master":"h2","hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true},
+		"h3":{"url":"http://127.0.0.1:3/","enable":true}
+	}}`)
+
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/dm/", nil)
+		if _, master, _ := api.getAPIHostsByReq(req); master != "h2" {
+			t.Fatal("expect default master h2,got:", master)
+		}
+	}
+
+	//the pref of request is first
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/dm/?"+apiPrefParamName+"=h3", nil)
+	if _, master, _ := api.getAPIHostsByReq(req); master != "h3" {
+		t.Error("expect pref master h3,got:", master)
+	}
+
+	//disabled default master is skipped
+	api.Hosts["h2"].Enable = false
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/dm/", nil)
+	if _, master, _ := api.getAPIHostsByReq(req); master == "h2" || master == "" {
+		t.Error("disabled default master should not be used,got:", master)
+	}
+}
+
+func Test_APIHostPriority(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "pri", `{"path":"/pri/","enable":true,"hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true,"priority":1},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true,"priority":5},
+		"h3":{"url":"http://127.0.0.1:3/","enable":true,"priority":5},
+		"h4":{"url":"http://127.0.0.1:4/","enable":true}
+	}}`)
+
+	master := func() string {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/pri/", nil)
+		_, name, _ := api.getAPIHostsByReq(req)
+		return name
+	}
+	//h2 and h3 tie,the smaller name wins every time
+	for i := 0; i < 20; i++ {
+		if name := master(); name != "h2" {
+			t.Fatal("expect the higher priority h2,got:", name)
+		}
+	}
+
+	api.Hosts["h2"].Enable = false
+	if name := master(); name != "h3" {
+		t.Error("expect h3 when h2 disabled,got:", name)
+	}
+
+	//default master is first
+	api.DefaultMaster = "h1"
+	if name := master(); name != "h1" {
+		t.Error("expect default master h1,got:", name)
+	}
+
+	//the pref of request is first
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/pri/?"+apiPrefParamName+"=h4", nil)
+	if _, name, _ := api.getAPIHostsByReq(req); name != "h4" {
+		t.Error("expect pref master h4,got:", name)
+	}
+}
+
+func Test_HostsPriorityHostName(t *testing.T) {
+	hosts := newHosts()
+	for _, name := range []string{"a", "b", "c"} {
+		hosts.addNewHost(newHost(name, "http://127.0.0.1/", true))
+	}
+	if name := hosts.priorityHostName([]string{"a", "b", "c"}); name != "" {
+		t.Error("expect empty without priority,got:", name)
+	}
+	hosts["c"].Priority = -1
+	if name := hosts.priorityHostName([]string{"c", "b", "a"}); name != "a" {
+		t.Error("expect a,got:", name)
+	}
+	hosts["b"].Priority = 2
+	if name := hosts.priorityHostName([]string{"a", "c"}); name != "a" {
+		t.Error("not allowed host should be skipped,got:", name)
+	}
+}
+
+func Test_APIReadOnlyHost(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "ro", `{"path":"/ro/","enable":true,"hosts":{
+		"rw":{"url":"http://127.0.0.1:1/","enable":true},
+		"ro":{"url":"http://127.0.0.1:2/","enable":true,"read_only":true}
+	}}`)
+
+	cases := []struct {
+		method string
+		names  []string
+	}{
+		{"GET", []string{"ro", "rw"}},
+		{"HEAD", []string{"ro", "rw"}},
+		{"POST", []string{"rw"}},
+		{"DELETE", []string{"rw"}},
+	}
+	for _, c := range cases {
+		for i := 0; i < 10; i++ {
+			req, _ := http.NewRequest(c.method, "http://127.0.0.1/ro/", nil)
+			hosts, master, _ := api.getAPIHostsByReq(req)
+			var names []string
+			for _, h := range hosts {
+				names = append(names, h.Name)
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(c.names, ",") {
+				t.Fatal(c.method, "expect hosts:", c.names, "got:", names)
+			}
+			if !InStringSlice(master, c.names) {
+				t.Fatal(c.method, "wrong master:", master)
+			}
+		}
+	}
+}
+
+func Test_APIMaxShadowHosts(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "shadow", `{"path":"/shadow/","enable":true,"default_master":"m","max_shadow_hosts":2,"hosts":{
+		"m":{"url":"http://127.0.0.1:1/","enable":true},
+		"fail":{"url":"http://127.0.0.1:2/","enable":true},
+		"slow":{"url":"http://127.0.0.1:3/","enable":true},
+		"fast":{"url":"http://127.0.0.1:4/","enable":true}
+	}}`)
+	api.Hosts["fail"].stats.record(time.Millisecond, true)
+	api.Hosts["slow"].stats.record(100*time.Millisecond, false)
+	api.Hosts["fast"].stats.record(10*time.Millisecond, false)
+
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/shadow/", nil)
+		hosts, master, _ := api.getAPIHostsByReq(req)
+		var names []string
+		for _, h := range hosts {
+			names = append(names, h.Name)
+		}
+		if master != "m" || strings.Join(names, ",") != "m,fast,slow" {
+			t.Fatal("wrong hosts:", master, names)
+		}
+	}
+
+	//recovered
+	api.Hosts["fail"].stats.record(time.Millisecond, false)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/shadow/", nil)
+	hosts, _, _ := api.getAPIHostsByReq(req)
+	if len(hosts) != 3 || hosts[1].Name != "fail" || hosts[2].Name != "fast" {
+		t.Error("the recovered fast host should be first")
+	}
+}
+
+func Test_APIDuplicateHostName(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	confPath := filepath.Join(apiServer.getConfDir(), "dup.json")
+	DirCheck(confPath)
+	ioutil.WriteFile(confPath, []byte(`{"path":"/dup/","enable":true,"hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true},
+		"h1":{"url":"http://127.0.0.1:3/","enable":true}
+	}}`), 0644)
+	err := apiServer.loadAPI("dup")
+	if err == nil || !strings.Contains(err.Error(), "duplicate host name:h1") {
+		t.Error("expect duplicate error,got:", err)
+	}
+	if apiServer.getAPIByID("dup") != nil {
+		t.Error("the api should not be loaded")
+	}
+
+	if name := duplicateHostName([]byte(`{"hosts":{"h1":{"url":"a"},"h2":{"hosts":{"h1":1}}}}`)); name != "" {
+		t.Error("expect no duplicate,got:", name)
+	}
+	if name := duplicateHostName([]byte(`{"hosts":null}`)); name != "" {
+		t.Error("expect no duplicate,got:", name)
+	}
+}
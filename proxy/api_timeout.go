@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand rand which can be used by goroutines
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+// Seed reset the seed,eg for test
+func (lr *lockedRand) Seed(seed int64) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.r = rand.New(rand.NewSource(seed))
+}
+
+// Intn [0,n)
+func (lr *lockedRand) Intn(n int) int {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	return lr.r.Intn(n)
+}
+
+var jitterRand = newLockedRand(time.Now().UnixNano())
+
+// requestTimeout timeout for one backend request,
+// with a random jitter of [0,TimeoutJitterMs] added
+func (api *apiStruct) requestTimeout() time.Duration {
+	ms := api.TimeoutMs
+	if api.TimeoutJitterMs > 0 {
+		ms += jitterRand.Intn(api.TimeoutJitterMs + 1)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_APIRequestTimeoutJitter(t *testing.T) {
+	api := &apiStruct{TimeoutMs: 1000}
+	if api.requestTimeout() != time.Second {
+		t.Error("no jitter expected:", api.requestTimeout())
+	}
+
+	api.TimeoutJitterMs = 200
+	jitterRand.Seed(1)
+	var first []time.Duration
+	hasJitter := false
+	for i := 0; i < 100; i++ {
+		d := api.requestTimeout()
+		if d < time.Second || d > 1200*time.Millisecond {
+			t.Fatal("timeout out of jitter range:", d)
+		}
+		if d != time.Second {
+			hasJitter = true
+		}
+		first = append(first, d)
+	}
+	if !hasJitter {
+		t.Error("jitter not applied")
+	}
+
+	//same seed,same values
+	jitterRand.Seed(1)
+	for i := 0; i < 100; i++ {
+		if d := api.requestTimeout(); d != first[i] {
+			t.Fatal("seeded jitter not reproducible,index:", i, d, first[i])
+		}
+	}
+}
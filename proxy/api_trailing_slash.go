@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// trailing_slash options,for request path without the trailing slash of api path,
+// eg /a for api path /a/
+const (
+	trailingSlashStrict   = "strict"   //not match,default
+	trailingSlashMatch    = "match"    //treat as the same
+	trailingSlashRedirect = "redirect" //redirect to the api path
+)
+
+func (api *apiStruct) initTrailingSlash() error {
+	switch api.TrailingSlash {
+	case "":
+		api.TrailingSlash = trailingSlashStrict
+	case trailingSlashStrict, trailingSlashMatch, trailingSlashRedirect:
+	default:
+		return fmt.Errorf("trailing_slash wrong:%s", api.TrailingSlash)
+	}
+	return nil
+}
+
+// redirectTrailingSlash redirect /a to /a/,return true when redirected
+func (api *apiStruct) redirectTrailingSlash(rw http.ResponseWriter, req *http.Request) bool {
+	if api.TrailingSlash != trailingSlashRedirect || len(req.URL.Path) >= len(api.Path) {
+		return false
+	}
+	target := api.Path
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	code := http.StatusMovedPermanently
+	if req.Method != "GET" && req.Method != "HEAD" {
+		//keep the method and body
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(rw, req, target, code)
+	return true
+}
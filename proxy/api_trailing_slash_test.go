@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_APITrailingSlash(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	for _, id := range []string{"strict", "match", "redirect"} {
+		conf := `{"path":"/` + id + `/","enable":true,"hosts":{"h1":{"url":"` + backend.URL + `/","enable":true}}`
+		if id != "strict" {
+			conf += `,"trailing_slash":"` + id + `"`
+		}
+		testLoadAPI(t, apiServer, id, conf+"}")
+	}
+	if apiServer.getAPIByID("strict").TrailingSlash != trailingSlashStrict {
+		t.Error("strict should be the default")
+	}
+	ts := testServe(t, apiServer)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	cases := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{"GET", "/strict/", 200, ""},
+		{"GET", "/strict", 404, ""},
+		{"GET", "/match/", 200, ""},
+		{"GET", "/match", 200, ""},
+		{"GET", "/matchx", 404, ""},
+		{"GET", "/redirect/a", 200, ""},
+		{"GET", "/redirect?a=1", 301, "/redirect/?a=1"},
+		{"POST", "/redirect", 308, "/redirect/"},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, ts.URL+c.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status || resp.Header.Get("Location") != c.location {
+			t.Error(c.method, c.path, "expect:", c.status, c.location, "got:", resp.StatusCode, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func Test_APITrailingSlashWrong(t *testing.T) {
+	api := &apiStruct{TrailingSlash: "abc"}
+	if err := api.initTrailingSlash(); err == nil {
+		t.Error("expect error for wrong trailing_slash")
+	}
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// transformMaxBody the larger bodies are not transformed
+const transformMaxBody = 10 << 20
+
+// TransformConf post the master's response body to a service,
+// the body it returns is sent to the client
+type TransformConf struct {
+	URL       string `json:"url"`
+	TimeoutMs int    `json:"timeout_ms"` //默认1000
+	FailOpen  bool   `json:"fail_open"`  //转换失败(超时、非2xx等)时返回原来的body,否则返回502
+
+	client *http.Client
+}
+
+func (c *TransformConf) init() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("url wrong:%s", c.URL)
+	}
+	if c.TimeoutMs < 1 {
+		c.TimeoutMs = 1000
+	}
+	c.client = &http.Client{Timeout: time.Duration(c.TimeoutMs) * time.Millisecond}
+	return nil
+}
+
+// bodyWithCloser read the replaced body,close the original one
+type bodyWithCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// transform replace the body of resp with the transformed one,
+// the body is not changed when failed
+func (c *TransformConf) transform(apiID string, req *http.Request, resp *http.Response) error {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, transformMaxBody+1))
+	resp.Body = &bodyWithCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+	if err != nil {
+		return fmt.Errorf("read body failed:%s", err)
+	}
+	if len(body) > transformMaxBody {
+		return fmt.Errorf("body too large,more than %d bytes", transformMaxBody)
+	}
+
+	treq, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	treq.Header.Set("Content-Type", resp.Header.Get("Content-Type"))
+	treq.Header.Set("X-Api-Front-Api", apiID)
+	treq.Header.Set("X-Api-Front-Status", fmt.Sprintf("%d", resp.StatusCode))
+	treq.Header.Set("X-Api-Front-Uri", req.URL.RequestURI())
+	tresp, err := c.client.Do(treq)
+	if err != nil {
+		return err
+	}
+	defer tresp.Body.Close()
+	if tresp.StatusCode < 200 || tresp.StatusCode > 299 {
+		return fmt.Errorf("transform status:%d", tresp.StatusCode)
+	}
+	tbody, err := ioutil.ReadAll(io.LimitReader(tresp.Body, transformMaxBody+1))
+	if err != nil {
+		return fmt.Errorf("read transformed body failed:%s", err)
+	}
+	if len(tbody) > transformMaxBody {
+		return fmt.Errorf("transformed body too large,more than %d bytes", transformMaxBody)
+	}
+
+	resp.Body = &bodyWithCloser{Reader: bytes.NewReader(tbody), Closer: resp.Body.(*bodyWithCloser).Closer}
+	resp.ContentLength = int64(len(tbody))
+	resp.Header.Set("Content-Length", fmt.Sprintf("%d", len(tbody)))
+	if ct := tresp.Header.Get("Content-Type"); ct != "" {
+		resp.Header.Set("Content-Type", ct)
+	}
+	return nil
+}
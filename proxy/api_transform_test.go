@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HandlerTransform(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "hello")
+	transformer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bd, _ := ioutil.ReadAll(req.Body)
+		rw.Header().Set("Content-Type", "text/x-upper")
+		rw.Write([]byte(strings.ToUpper(string(bd)) + "|" + req.Header.Get("X-Api-Front-Status")))
+	}))
+	defer transformer.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		rw.Write([]byte("late"))
+	}))
+	defer slow.Close()
+
+	testLoadAPI(t, apiServer, "tf", `{"path":"/tf/","enable":true,
+		"transform":{"url":"`+transformer.URL+`/"},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "tf_open", `{"path":"/tf_open/","enable":true,
+		"transform":{"url":"`+slow.URL+`/","timeout_ms":50,"fail_open":true},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "tf_close", `{"path":"/tf_close/","enable":true,
+		"transform":{"url":"`+slow.URL+`/","timeout_ms":50},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	resp, bd := testGet(t, ts.URL+"/tf/a")
+	if resp.StatusCode != 200 || bd != "HELLO|200" {
+		t.Error("transformed", resp.StatusCode, bd)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/x-upper" {
+		t.Error("content-type of the transform service expected,got:", ct)
+	}
+
+	start := time.Now()
+	resp, bd = testGet(t, ts.URL+"/tf_open/a")
+	if resp.StatusCode != 200 || bd != "hello" {
+		t.Error("original body expected when fail open", resp.StatusCode, bd)
+	}
+	if used := time.Since(start); used > 400*time.Millisecond {
+		t.Error("transform timeout not used,cost:", used)
+	}
+
+	resp, bd = testGet(t, ts.URL+"/tf_close/a")
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Error("502 expected when not fail open", resp.StatusCode, bd)
+	}
+}
+
+func Test_APITransformWrong(t *testing.T) {
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.Transform = &TransformConf{URL: "ftp://127.0.0.1/"}
+	if err := api.init(); err == nil {
+		t.Error("expect error with the url")
+	}
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// transportIdleTimeout close the idle connections of the shared transports,
+// the default of reuse_conn_idle_ms
+const transportIdleTimeout = 90 * time.Second
+
+// transportPool the keep-alive transports of the api when reuse_conn is on,
+// the hosts with the same scheme://host and transport settings share one,
+// so do the master and the shadow requests
+type transportPool struct {
+	mu sync.Mutex
+	m  map[string]*pooledTransport
+}
+
+// pooledTransport the shared transport and when it is created,
+// it is replaced after reuse_conn_max_life_ms
+type pooledTransport struct {
+	transport *http.Transport
+	created   time.Time
+}
+
+func newTransportPool() *transportPool {
+	return &transportPool{m: make(map[string]*pooledTransport)}
+}
+
+// idleTimeout the idle connections are closed after it
+func (api *apiStruct) idleTimeout() time.Duration {
+	if api.ReuseConnIdleMs > 0 {
+		return time.Duration(api.ReuseConnIdleMs) * time.Millisecond
+	}
+	return transportIdleTimeout
+}
+
+// transportExpired the connections of the transport are too old to be reused
+func (api *apiStruct) transportExpired(pt *pooledTransport) bool {
+	return api.ReuseConnMaxLifeMs > 0 && time.Since(pt.created) > time.Duration(api.ReuseConnMaxLifeMs)*time.Millisecond
+}
+
+// transportKey the hosts with the same key can use the same connections
+func (h *Host) transportKey() string {
+	addr := h.URLStr
+	if u, err := url.Parse(h.URLStr); err == nil {
+		addr = u.Scheme + "://" + u.Host
+	}
+	return fmt.Sprintf("%s|connect=%d|dns=%d", addr, h.ConnectTimeoutMs, h.DNSCacheSec)
+}
+
+// hostTransport a new transport for each request(no keep-alive),
+// or the shared one when reuse_conn is on
+func (api *apiStruct) hostTransport(apiHost *Host, timeout time.Duration) *http.Transport {
+	if !api.ReuseConn {
+		return api.newTransport(apiHost, apiHost.dialer(api.requestTimeout()), timeout, false)
+	}
+	key := apiHost.transportKey()
+	api.transports.mu.Lock()
+	defer api.transports.mu.Unlock()
+	pt, has := api.transports.m[key]
+	if has && !api.transportExpired(pt) {
+		return pt.transport
+	}
+	if has {
+		//the requests in flight are not broken,their connections are closed when they end
+		old := pt.transport
+		old.CloseIdleConnections()
+		time.AfterFunc(api.requestTimeout(), old.CloseIdleConnections)
+	}
+	//the jitter is not used,the settings are same for all requests
+	dialer := apiHost.dialer(time.Duration(api.TimeoutMs) * time.Millisecond)
+	transport := api.newTransport(apiHost, dialer, dialer.Timeout, true)
+	api.transports.m[key] = &pooledTransport{transport: transport, created: time.Now()}
+	return transport
+}
+
+func (api *apiStruct) newTransport(apiHost *Host, dialer *net.Dialer, tlsTimeout time.Duration, keepAlive bool) *http.Transport {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		Dial:                dialer.Dial,
+		TLSHandshakeTimeout: tlsTimeout,
+		DisableKeepAlives:   !keepAlive,
+	}
+	if keepAlive {
+		transport.IdleConnTimeout = api.idleTimeout()
+	}
+	if apiHost.dns != nil {
+		transport.DialContext = apiHost.dns.dialContext(dialer)
+	}
+	if api.HostAsProxy {
+		transport.Proxy = (func(u string) func(*http.Request) (*url.URL, error) {
+			return func(req *http.Request) (*url.URL, error) {
+				return url.Parse(u)
+			}
+		})(apiHost.URLStr)
+	}
+
+	if api.proxyURL != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return api.proxyURL, nil
+		}
+	}
+	return transport
+}
+
+// closeIdleConns release the connections of the shared transports,eg when the api is reloaded
+func (p *transportPool) closeIdleConns() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pt := range p.m {
+		pt.transport.CloseIdleConnections()
+	}
+}
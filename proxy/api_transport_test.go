@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testBackendConns backend counting the new connections
+func testBackendConns(t *testing.T) (*httptest.Server, *int64) {
+	var conns int64
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("ok"))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts, &conns
+}
+
+func Test_APIReuseConn(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	ts := testServe(t, apiServer)
+	const total = 5
+
+	//h1 and h2 are the same backend,every request goes to both
+	count := func(id string, reuse bool) int64 {
+		backend, conns := testBackendConns(t)
+		reuseStr := "false"
+		if reuse {
+			reuseStr = "true"
+		}
+		testLoadAPI(t, apiServer, id, `{"path":"/`+id+`/","enable":true,"reuse_conn":`+reuseStr+`,
+			"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true},"h2":{"url":"`+backend.URL+`/","enable":true}}}`)
+		for i := 0; i < total; i++ {
+			testGet(t, ts.URL+"/"+id+"/")
+			//wait for the shadow request
+			time.Sleep(30 * time.Millisecond)
+		}
+		return atomic.LoadInt64(conns)
+	}
+	if n := count("no_reuse", false); n != total*2 {
+		t.Error("expect a new connection for each request without reuse,got:", n)
+	}
+	if n := count("reuse", true); n > 2 {
+		t.Error("expect connections reused,got:", n)
+	}
+}
+
+func Test_APIReuseConnPools(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	b1 := testBackend(t, "b1")
+	b2 := testBackend(t, "b2")
+	api := testLoadAPI(t, apiServer, "pools", `{"path":"/pools/","enable":true,"reuse_conn":true,
+		"hosts":{
+			"h1":{"url":"`+b1.URL+`/a/","enable":true},
+			"h1_dup":{"url":"`+b1.URL+`/b/","enable":true},
+			"h1_slow":{"url":"`+b1.URL+`/","enable":true,"connect_timeout_ms":100},
+			"h2":{"url":"`+b2.URL+`/","enable":true}
+		}}`)
+	hosts := api.Hosts
+	transport := func(name string) *http.Transport {
+		return api.hostTransport(hosts[name], time.Second)
+	}
+	if transport("h1") != transport("h1_dup") {
+		t.Error("the hosts with same address should share the transport")
+	}
+	if transport("h1") == transport("h2") {
+		t.Error("distinct hosts should have independent transports")
+	}
+	if transport("h1") == transport("h1_slow") {
+		t.Error("the hosts with different settings should have independent transports")
+	}
+	if len(api.transports.m) != 3 {
+		t.Error("expect 3 transports,got:", len(api.transports.m))
+	}
+	if transport("h1").DisableKeepAlives {
+		t.Error("expect keep-alive when reuse_conn")
+	}
+
+	api.ReuseConn = false
+	if transport("h1") == transport("h1") || !transport("h1").DisableKeepAlives {
+		t.Error("expect new transport without keep-alive for each request")
+	}
+}
+
+func Test_APIReuseConnIdleAndMaxLife(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	ts := testServe(t, apiServer)
+
+	count := func(id string, conf string) int64 {
+		backend, conns := testBackendConns(t)
+		testLoadAPI(t, apiServer, id, `{"path":"/`+id+`/","enable":true,"reuse_conn":true,`+conf+`
+			"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+		for i := 0; i < 3; i++ {
+			if _, body := testGet(t, ts.URL+"/"+id+"/"); body != "ok" {
+				t.Fatal("wrong body:", body)
+			}
+			time.Sleep(150 * time.Millisecond)
+		}
+		return atomic.LoadInt64(conns)
+	}
+	if n := count("lc_default", ""); n != 1 {
+		t.Error("expect the connection reused,got:", n)
+	}
+	if n := count("lc_idle", `"reuse_conn_idle_ms":50,`); n != 3 {
+		t.Error("expect the idle connections not reused,got:", n)
+	}
+	if n := count("lc_life", `"reuse_conn_max_life_ms":100,`); n != 3 {
+		t.Error("expect the old connections not reused,got:", n)
+	}
+}
+
+func Test_APIReuseConnBackendRestart(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	ts := testServe(t, apiServer)
+
+	start := func(addr string, body string) *http.Server {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(body))
+		})}
+		go srv.Serve(ln)
+		t.Cleanup(func() { srv.Close() })
+		return srv
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	srv := start(addr, "before")
+	testLoadAPI(t, apiServer, "restart", `{"path":"/restart/","enable":true,"reuse_conn":true,
+		"hosts":{"h1":{"url":"http://`+addr+`/","enable":true}}}`)
+	if _, body := testGet(t, ts.URL+"/restart/"); body != "before" {
+		t.Fatal("wrong body:", body)
+	}
+	//the pooled connection is closed by the backend
+	srv.Close()
+	start(addr, "after")
+	if resp, body := testGet(t, ts.URL+"/restart/"); resp.StatusCode != 200 || body != "after" {
+		t.Error("expect recovered after the backend restarted,got:", resp.StatusCode, body)
+	}
+}
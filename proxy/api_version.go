@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// apiVersionsKeep how many versions of conf are kept for each api
+const apiVersionsKeep = 10
+
+// versionDir confDir/versions/{apiID}/
+func (api *apiStruct) versionDir() string {
+	return filepath.Join(api.apiServer.getConfDir(), "versions", api.ID)
+}
+
+func (api *apiStruct) versionPath(version int64) string {
+	return filepath.Join(api.versionDir(), fmt.Sprintf("%d.json", version))
+}
+
+// saveVersion keep a copy of the saved conf,remove the old ones
+func (api *apiStruct) saveVersion(data []byte) error {
+	versionPath := api.versionPath(api.Version)
+	DirCheck(versionPath)
+	if err := ioutil.WriteFile(versionPath, data, 0644); err != nil {
+		return err
+	}
+	versions, err := api.versions()
+	if err != nil {
+		return err
+	}
+	for len(versions) > apiVersionsKeep {
+		os.Remove(api.versionPath(versions[0]))
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// versions the kept versions,in ascending order
+func (api *apiStruct) versions() ([]int64, error) {
+	fileNames, err := filepath.Glob(filepath.Join(api.versionDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var versions []int64
+	for _, fileName := range fileNames {
+		v, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(fileName), ".json"), 10, 64)
+		if err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// loadVersion the api with the conf of the version
+func (api *apiStruct) loadVersion(version int64) (*apiStruct, error) {
+	data, err := ioutil.ReadFile(api.versionPath(version))
+	if err != nil {
+		return nil, err
+	}
+	apiOld := newAPI(api.apiServer, api.ID)
+	if err := json.Unmarshal(data, &apiOld); err != nil {
+		return nil, err
+	}
+	if err := apiOld.init(); err != nil {
+		return nil, err
+	}
+	apiOld.Hosts.init()
+	return apiOld, nil
+}
+
+// apiRollback restore the conf of the version,the restored one is saved as a new version
+func (wr *webReq) apiRollback() {
+	req := wr.req
+	apiID := strings.TrimSpace(req.FormValue("name"))
+	version, err := strconv.ParseInt(req.FormValue("version"), 10, 64)
+	if err != nil {
+		wr.json(400, "version wrong", nil)
+		return
+	}
+	api := wr.web.apiServer.getAPIByID(apiID)
+	if api == nil {
+		wr.json(404, "Api Not Exists", nil)
+		return
+	}
+	if !api.userCanEdit(wr.user) {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	apiOld, err := api.loadVersion(version)
+	if err != nil {
+		if os.IsNotExist(err) {
+			wr.json(404, "version not found", nil)
+			return
+		}
+		wr.json(500, "load version failed:"+err.Error(), nil)
+		return
+	}
+	if apiByPath := wr.web.apiServer.getAPIByPath(apiOld.Path); apiByPath != nil && apiByPath.ID != apiID {
+		wr.json(409, fmt.Sprintf("same location (%s) as api(%s)", apiOld.Path, apiByPath.ID), nil)
+		return
+	}
+	api.rw.RLock()
+	apiOld.Version = api.Version
+	api.rw.RUnlock()
+	oldData, _ := ioutil.ReadFile(api.ConfPath)
+	if err := apiOld.save(); err != nil {
+		wr.json(500, "Save failed:"+err.Error(), nil)
+		return
+	}
+	if err := wr.reloadSaved(apiID, api.ConfPath, oldData); err != nil {
+		wr.json(500, "Rollback failed,can not reload:"+err.Error()+",reverted", nil)
+		return
+	}
+	log.Println("api [", apiID, "] rollback to version", version, "as", apiOld.Version)
+	wr.json(0, "Success", apiOld.Version)
+}
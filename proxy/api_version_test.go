@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_APIVersionRollback(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("ver")
+	api.Path = "/ver/"
+	api.Enable = true
+	for i := 1; i <= 3; i++ {
+		api.Note = fmt.Sprintf("note_%d", i)
+		if err := api.save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//not changed,no new version
+	api.save()
+	if api.Version != 3 {
+		t.Fatal("expect version 3,got:", api.Version)
+	}
+	if err := apiServer.loadAPI("ver"); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := func(query string, user *User) (code int, data int64) {
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/rollback?"+query, nil), user)
+		wr.execute()
+		var ret struct {
+			Code int   `json:"code"`
+			Data int64 `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &ret)
+		return ret.Code, ret.Data
+	}
+	admin := &User{ID: "admin"}
+	if code, _ := rollback("name=ver&version=1", nil); code != 403 {
+		t.Error("expect 403 without login,got:", code)
+	}
+	if code, _ := rollback("name=ver&version=9", admin); code != 404 {
+		t.Error("expect 404 for not exists version,got:", code)
+	}
+	code, version := rollback("name=ver&version=1", admin)
+	if code != 0 || version != 4 {
+		t.Fatal("rollback failed:", code, version)
+	}
+	apiNow := apiServer.getAPIByID("ver")
+	if apiNow.Note != "note_1" || apiNow.Version != 4 {
+		t.Error("not rollback,note:", apiNow.Note, "version:", apiNow.Version)
+	}
+	versions, _ := apiNow.versions()
+	if fmt.Sprint(versions) != "[1 2 3 4]" {
+		t.Error("wrong versions:", versions)
+	}
+}
+
+func Test_APIVersionRollbackLoadFail(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("ver_bad")
+	api.Path = "/ver_bad/"
+	api.Enable = true
+	api.Note = "good"
+	if err := api.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiServer.loadAPI("ver_bad"); err != nil {
+		t.Fatal(err)
+	}
+	//the path can not be loaded
+	if err := ioutil.WriteFile(api.versionPath(9), []byte(`{"path":"/bad path/","enable":true,"note":"bad"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/rollback?name=ver_bad&version=9", nil), &User{ID: "admin"})
+	wr.execute()
+	var ret struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &ret)
+	if ret.Code == 0 || !strings.Contains(ret.Msg, "reverted") {
+		t.Error("expect rollback failed,got:", rec.Body.String())
+	}
+	if apiNow := apiServer.getAPIByID("ver_bad"); apiNow == nil || apiNow.Note != "good" {
+		t.Error("the api should not be changed:", apiNow)
+	}
+	if err := apiServer.loadAPI("ver_bad"); err != nil {
+		t.Error("the conf should be reverted:", err)
+	}
+}
+
+func Test_APIVersionKeep(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("keep")
+	for i := 0; i < apiVersionsKeep+5; i++ {
+		api.Note = fmt.Sprint(i)
+		api.save()
+	}
+	versions, _ := api.versions()
+	if len(versions) != apiVersionsKeep || versions[0] != 6 {
+		t.Error("wrong versions kept:", versions)
+	}
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// deadlineWriter extend the write deadline of the conn before each write,
+// so a client which stops reading is dropped after the timeout
+type deadlineWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (dw *deadlineWriter) Write(p []byte) (int, error) {
+	if err := dw.rc.SetWriteDeadline(time.Now().Add(dw.timeout)); err != nil {
+		return 0, err
+	}
+	return dw.w.Write(p)
+}
+
+// respWriter the writer to copy the master's response body to
+func (api *apiStruct) respWriter(rw http.ResponseWriter) io.Writer {
+	if api.WriteTimeoutMs < 1 {
+		return rw
+	}
+	timeout := time.Duration(api.WriteTimeoutMs) * time.Millisecond
+	rc := http.NewResponseController(rw)
+	if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		log.Println("[warning]set write deadline failed,", api.ID, err)
+		return rw
+	}
+	return &deadlineWriter{w: rw, rc: rc, timeout: timeout}
+}
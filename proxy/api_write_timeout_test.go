@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HandlerWriteTimeout(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backendDone := make(chan error, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		chunk := []byte(strings.Repeat("a", 32*1024))
+		for {
+			if _, err := rw.Write(chunk); err != nil {
+				backendDone <- err
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "wt", `{"path":"/wt/","enable":true,"write_timeout_ms":200,
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	//a client which never reads the response
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /wt/ HTTP/1.1\r\nHost: %s\r\n\r\n", ts.Listener.Addr())
+
+	select {
+	case err := <-backendDone:
+		t.Log("backend released:", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("backend still hold by the stalled client")
+	}
+}
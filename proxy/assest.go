@@ -352,8 +352,8 @@ var Assest = &AssestStruct{
 
 		_assestBase64Decode("L3Jlcy90cGwvYXBpL2Zvcm1fYmFzZS5odG1s"): &AssestFile{
 			Name:    _assestBase64Decode("L3Jlcy90cGwvYXBpL2Zvcm1fYmFzZS5odG1s"),
-			Mtime:   1476448534,
-			Content: _assestGzipBase64decode("H4sIAAAJbogA/6xYb3MTxxl/jT7FdiEZu7F0EF40Y5+udQtTPNO0npS86ADVrO5W1o1Pu5e7lW1V0YwBA6YxFlOcgI1LysQuHigWKY1RLLv+MLm9k17lK3T2/ukkC2wamGGsu93n3+/57fM8e3KBWiVQwqxItSw0qc0gQCrTKclCKSchU4dANZBtZ6HYmS5SS/8LJQwZEDBkTWGWhXrByolFCFCZUZWWTAMznIW0UIBKStaJWWaAVUychUVd0zCBgKASzkKNQjCDjDLOwjyysdhc0LGh2ZgpKdnAU5hoyvjkBPg1sjGYIAWaSoFqVS+ADDL1zPk53WZ2rZaSNX0m8jKQyhHKhL5xTbOwbf+4tyYjULRwIQurVSGcK1tGrdaNIZc3EJmGSnJVlpCSep/kbXNMtk1EIhNTRsUs6iolIP6VJng2PasTjc5CRZbEbiUlS5o+o6SqVUy0Wi2V6lGS8BOYZcNIW/pUkUGx/XB8ke8iIwQZFVu3f6lr2cDZzMS5Wg0q4+GC8DpwOrZszoyCZBy6loUiSnMm16MifPgtZpMzInw/jFBXN6gwL6ke2H1yTFm0bEIlBQAAsoHy2IiWVWqk7VL6Q6BSwixqpINVC39W1i2sQWXi3Kgs+S9D8YTuUPhsqFn8GwBStPQGvomYdS3mXBz750VWEmxQjqOkRLsayqaGfJ7FcsJtpV9xvBz6Xbax9RtEzms6Sy4K+XyZMUpCw8FDfADzjIA8I2lDJ9MQaIihNKNTU0bglDiRwbuQ0idLVNMLlXFTnzj3cWJ9togYnsHWIACcg2135XtZCiwrSccDKsWOBtxOLBs2Pl4WkgASPJtAL5JKciokDAxVMTzHYMybLIwZBEzEGLZIFl66PJu+8sFxcw5MA6m4SA1NINJZn3dbj9s7L0Gg5ce9JX5ji387z1caTnMeKiA1CJEEGkniFrFhpvMGVae7QQaK3H/c4gf3fe1X29tN/vy+27jD/7bkfvmCP7+f1Bn+eaeH7XdURaLEH//IHTcxAeYmYsV+xCcRK/pwR2mSMj9/HfhiSTkK2C6k7vImr3/z6ScT3tqC93Cb73/Zub7FF28KeP95jS9/HWyQ80qRMXNUklBGpSVJLlDCgEoNamVh3ihjqASdT5bEiiLppEAzZtGUpbwyEudvcdV9vsE3FvjjZ/zmKhigRQrUSKGeEXel4S5d5Rtr3u6TwL8f5q/JeUuKI/C+bTl7e87uMpnSyZy3tjBIrRFmDfTpd5duOa2NH+avDaDNT2YNVH5PGT6SKB/9f0Txu3QfUYS93oL8rk8CVC7qJUzL7Fj8P9G/5kcYGz1xdO0CIuSwvw8AgQXO9OMQ+vix7eNwoqfDHPYkjTSNEqiU7OSROfQzwvBdgHieoLxxNDXOArWI1ek8nUv2yYRMArwQnVggRAj7lmKAzsBk//fXajVfBmvZ8G9YnbsW/oTtRPNKujy4xHQZ0IdG7JvNKqL1ijOYnsUiu6OAUKuEjDGo9PS/royYvPynXJ7O5ZA2A5VxbQYRFWuRU4PYLsRElnJiTPbFIuuabpsGqgjLJBpE3pjb4+d3XCvpBHxqY8vuTfJRNSCxRXm7elDWNbv/HPgOxAWhb+4Y3Bj4xprTfOrsH3grW96zr7zdg0uDCurnYQEFV9oHj9zlzZH2F5t86Qa/++xSOC0flhlFpBKKBXuu8MVVp9VyF+reypa7+Kpzfd9dv+3+/Xpn9e7P+rwdfDTfZc4mkYUJAxcuXpwEkxadqxwrc2d/euZMYexQzxcvu4NW1Pn/7Lfhy9LlwyNA2KDLo+avznz4i8zpzOnMmdGzZ09/BAHTmSC8aKC37/D606CrCgGn9Y139+ZIkG9nd8nbfRK84our/O6y96zhrt/hf33sNO+0r++7DxrBqrv1mO/VD1Hq9UVTlrq30+5NNTic4UOuSG1mw+7tNWl/4PgvI0CJaujqdAhiTkysQk0OadrQ8JiFWdkioIAMG4/B8CJ4EiofiIteNIRGl7TX3DBFfkMChTAGfvH9e87+egiH7yNf3Xr/5JnTY97WF3y33m60+OILb21BECp34Q9/vMhf3HQfNKrVjIU/872s1fz9fP+e97Lltb72Vlud1ZvtVy/b+//ie9d4s+ne3+H1hnv7Ht+bd59vdOZvdx5/HzPuzZU/wMHO9dKrpwFcoDYbt32qHdUFknlPJbpAiB44fMcFshnB6TfxUpmJAdr97mrnVt3ZW+Mbd/jiDl984TbqTvNpMtsj3sIOv7vcWau767ud+du8/hW/seM0nzvNeaf59BNsm5TYuH2wwh8+EgOhZAp7UbnX9JmYTNWqhcgUBqcEKCOnxOvRbBx8cPmVY9b60OgkhywLVQIZcCqjUjqt40kLF6JbWrIQ5KfSdllVsW2DUiVtIoKN+MS1G5shW17922vV29v/9fa3neZuoNFbW+i0HrS3N5y9B/zGYrDT2RXDt/fwP+7ypvtdvf1kka9utbcPOve3g21B1O3GptNsueu7Xuue+2gdRv4nrprV90qVnE5Uo6xhIC5zUrcP+uSw6GxGVBj4Xu1tok+c8q69vg823fOf+Cplq5ZuMiV1amg4Y2GkVYYKZeLP40PDVV/fqSF4srfHD2fUokjgoa3R9t7ePpwJrvVDw2P+rtrwWEr8l6XI+ABY7HK+pLMYCtnHqW9aju7h0fcfH0BmGj7wg0nmE+yiGX3EeMt0REjGY6efHUS05LebQd9DXmMngFHXcv73jthQZOZ/AAAA//8BAAD//7nCURzNFAAA"),
+			Mtime:   1792141421,
+			Content: _assestGzipBase64decode("H4sIAAAAAAAA/7RYb1Mbx/1/HL2K/a2dDPyCdHb8oBk4XUvrTMNM3TKp86Bju5rV3QrdcNo9360AVdEMtrENDSBPTYzB1CkTqBm7BidusIygvBjr7qRHfgudvds7nYRscBozYqS93f3++Xz/3lfOUasACpjlqZaGJrUZBEhlOiVpKGUkZOoQqAay7TTkJ5N5aul/oYQhAwKGrDHM0lDPWRm+CQEqMqrSgmlghtOQ5nJQScg6MYsMsJKJ0zCvaxomEBBUwGmoUQgmkFHEaZhFNuaHczo2NBszJSEbeAwTTRkeHQG/RjYGIyRHEwlQLus5kEKmnvpsSreZXakkZE2fCKUMbmUIZZzesKZZ2LZf76/KCOQtnEvDcplfzhQto1Jp65DJGoiMQyW+K0tISXxEsrY5JNsmIiGLMaNk5nWVEhD9ShI8mZzUiUYnoSJL/LSSkCVNn1AS5TImWqWSSHQQickJzKJhJC19LM+gkuilXyg7twhBRsnW7V/qWjoQNjVyvlKByrDY4FIHQkeczYlBENdD19KQY2BOZDpIiMVvMRud4OpzeUNabaWEXRIdsHP7J8csWjShkgAAANlAWWyE2yo1knYh+QlQKWEWNZLBroWvFnULa1AZOT8oS/5DcT1GW1w+JyjzTw+Qwq23+BvXWdcin4t0/yrPCtwblJMQKdA2haKpIYbj97jYSjfhBOiUu2hj6zeIfKbpLL7J72eLjFEiGAeLKACzjIAsI0lDJ+MQaIihJKNjY0YgFI/I4Jlw6VMFqum50rCpj5y/ENufzCOGJ7DVC4DG4ba79FKWAs5tvUDoSuFa+Ha4LJexYeNK5V0BJHgyhl54S+jr+5RwGChIMTzFYOQ3aRh5EDARY9giaXjp8mTyyscntTkwDaTiPDU0jkhrbdqtrzd3n4OAyuv9eefmlvP9tLO006hNQwUkeiESQyMeFHlsmMmsQdXxtpIBIfcft53DZZ/6teZ2zXm67O4sOH+bd7955jxdjtMUXz9rsP2Oqoin+JOH3EkNE2BuIpbvRnwUsXylEjOTlPr/N4HPt5TjgA1FA8Bd3HSq3335xYi3OuM92HYOvmnd2HJmb3F4/3ndWfw2OCBnlTxj5qAkoZRKC5Kco4QBlRrUSsOsUcRQCSqfLPEdRdJJjqbMvClLWWUgst/sivt0w9mYcdafOLdWQA8qUkBGEnQG3KUdd/6as7Hq7T0K5Hs1fV3OWlKkgfd9vbG/39hbJGM6mfJWZ3qRNYTVQBd9d/52o77xavp6D7f5n70GKr+nDB/rKJ/+NEfxq3SXo3B+UT56L5EAlYt6AdMiO5H/f9C954dCxPQDYcK3aA64yqK+9wCBBcJ0B4yQ8YLN83KMTW9JkkjTKIFKwRYwxYB7PxiexzlUNBi4gGyGrRNBGelgYwOrb/AS0RoG1DMFn3rsLv/I1OQBFgIGldZq1V3bk6XgeefhctlCZAyD05zwwOk8tdlg2ve0z2ln59CDdLnsX4uKhV+/8dWAGDjtkxFABDhUKoFuWEuHP0ShUDppvUnY7jIbUDlqz2PKjPvDurs2p1I6ruNX09fyGGnYcmfvNZ/d8Ja23HsvG/v3nZuzrZsL3sG2u7z7en++cXDoLW01dzadO4vekx1vdeYLbJuU2Lh5uOQ8ePieYvEzgrLG8RnmHFDzWB3P0qm2koJDbB3EoAiy6IIINOxziuLsrDCnb8RAikrFv4O1tPgObRdx+BO229w6RO5tm1B6qHShEclmsxLv4HgqT05iniQGAaFWARlDUBFJpVsf3sD7q0yWTmWQNgGVYW0CERVroVC9DMWvcStl+NuWfy3krum2aaAS50wwPKrGEdue3L7DWkEn4EsbW3ankY8rJeKPHwlx6JkwjmbUoq7Z3enUFyCM4xCdbj4hA964QcXZWG3UHgdh4T255+0dXupVl78SdRhcaR4+dBc3B5pfbzrzN507Ty6Jl66jdwYRKYlrwZkrzuxKo153Z6o8PGdftG4cuGtz7t9vtFbu/F+XtLFl7OfPabNRZGHCwOcXL46CUYtOlU5kuXiK/4mWMzmzbtP5EoSmazeQf/a7ucvS5aOdpOjzioPmr85+8ovUmdSZ1NnBc+fOfAoB0xkPN96HzS041cdBc8YvNOrfeXduDQT2buzNe3uPgkfO7EqQEt21Beev643aQvPGgXt/J9h1t9ad/SpUjjWS+ErIUnvI0R54BMEpFhlepWzYHoLE+fd8i5QRoEQ1dHVcgJjhLz6cTAZpWl//kIVZ0SIghwwbD0ExTzgFlY/5vCAsO+G7/hsGFdy+woEEjIFczsHdxsGagMPHyFnZ+ujU2TND3tbXzl61uVN3Zp95qzPcoTKf/+GPF51nt9z7O+VyysJXfWUrFf+8c3DXe1736t96K/XWyq3mi+fNg385+9edWs1d3nWqO+7cXWd/2n260Zqea62/jDzu7Zmfc8ggO9PpXh0FgDcDw7bvasdVAaGob/dErAoI9MDRUQmQzRBOnqeShSLjQw/3x2ut29XG/qqzseDM7jqzz9ydaqP2OG7tAW9m17mzGHQ4rek5p3rPubnbqD1t1KYbtcedJZq/V0gm5xeme02fiJzpJJ2QHHmt72Q6ySDLQqWo4Qk6ilEL58IuJZ4IsmNJu6iq2LZBoZQ0EcFGFHFRY+G8+MGrV5vb//EOthu1vYCitzrTqt9vbm8EfUmAQGOPv8N5D/7tLm66P1abj2adla3m9mFreTs4Fmjd3Nls1Oru2p5Xv+s+XBM+Efo0lxGUPyyUMjpRjaKGAZ99Se06yOHJWHQyxTMM/LDyLtqLmO7kJx5GD8Q6HveyrVq6yZTE6b7+lIWRVurLFYn/WtfXX/ZJn+6DpzprfH9KzSMyho8cDY931vb+VDAd6usf8k9V+ocS/F+WQuY9YLGL2YLOIih4jbIKXY1eOM4Jx4g+gMw0fOB7O5nvYBfNcBb2juYIkRRAJoIZKSJafATYa6z2Bj4BjLqWKfCxWMSoXMZEq1QS/x0AVKcyCRQXAAA="),
 		},
 
 		_assestBase64Decode("L3Jlcy90cGwvYXBpL2Zvcm1fYmFzZV9ob3N0X3Jvdy5odG1s"): &AssestFile{
@@ -430,8 +430,8 @@ var Assest = &AssestStruct{
 
 		_assestBase64Decode("L3Jlcy90cGwvbGF5b3V0Lmh0bWw="): &AssestFile{
 			Name:    _assestBase64Decode("L3Jlcy90cGwvbGF5b3V0Lmh0bWw="),
-			Mtime:   1476448534,
-			Content: _assestGzipBase64decode("H4sIAAAJbogA/6RWTY4jNRTe5xQeS5ANVZ5uEIy6q4xaNItII2iJ2bCKnKpXqZd27MJ+VemoJks2SHMDNlyAG3AcQNwC2fnp6kwCzZCN/f4+v3o/n5K9uP32qzff333NalpqOcr2B6hSjrIlkGJFrZwHynlLVfKK79VGLSHnHcKqsY44K6whMJTzFZZU5yV0WEAShU8YGiRUOvGF0pBfpC+PYFRLtXUDkLJdAR45eVcMPGqixl8JMUeq21la2KWosWyFajCpnDUUoglJg+z79E24bDbsLVMNsmhnb1nfpx04j9ZEU9+nhTVV+o1awmaTiW30KNNo7pkDnXNPaw2+BiDOagdVzsVUOPBiZi15cqoRhR9I6RJNWnj/5bTLh4/x56EGrGg8h+ELhw0xWjeQc4IHEgvVqa2WM++KA9TCC2+Le6AUbXKRfpp+kS48l5nYOv9XrMUPLbh1cplepJ/Fj/z/YGll3fLDcR47sDhuwOJE7T40V9Xg8/Bkp1wYtmkctunONR8Pw8bXgzCxW7qZLddylJXYMSxzblTHB+ISTMvliG1/WaujVkNFU9/Onlqjh0ZWaOV9zmfWleCmFTpPXGbqcdbQlPDA5c3dhL1GT5lQMhMaj4GexKgGubwpS3ZzN3lWgAcXKMFz+d3udjrsRZKwf8Lpahvy/+Pnd7//9MtfP77787dfTwKxJDmfz7+zByPl5oH2pjOtzD2XNzPbvl+aTLT6UQhd2lW7abVOQl84iyucj5fKzdFE3dXly4+udzLZ5upV8zCWB/qxFOmnxO4U8tjhvKbx8NuyQQ7x1/dYsRT9aztHs9k8sW1nInJbzie3V32fth5cOrkNcxwks+M/jZK9H/qkGdrObUtcbs/TfWB9D9rDqTSOsdBEKDSnkfoeTDnEidVnQ/lM0XihQbmwptHh6Djvt1+7sJT84GMNKTTgkkq3WMaFC55yV8pJWKdBB/e2AHJQP/9tbefTEruDYZRV1hK4fdjKqSZQRO2EHGUFGAInPy5ss76+fHnxOTs741mzZRhY7elp6rUN3CAamYkdUjaLuGL7aLjtGErEPwt/AwAA//8BAAD//+AkYQFDCAAA"),
+			Mtime:   1792142095,
+			Content: _assestGzipBase64decode("H4sIAAAAAAAA/6RVwW7jNhC9+yu4BFpfKjHJLtrAkVgETQ8GFm2A7qUngZZG1jgUyZKUbMP1sZcC+we99Af6B/2ctuhfFJRlR3HsbrqVD9JwZh6HnDfPyau7b7969/3916TyteSjZP8CUfBRUoMXJK+EdeBT2vgyuqb7ZSVqSGmLsDTaekpyrTwon9IlFr5KC2gxh6gzPiOo0KOQkcuFhPQyvjiCEY2vtB2AFM0S8CjI2XwQUXlv3ISxOfqqmcW5rlmFRcOEwai0WvmQ7dFL4JtN/C58bLfkR7LZxDMrVJH54VIL1qFWezPXqoy/ETVstwnrAvkokageiAWZUsy1oqSyUKaUlaINdoy5pk+inF9LcBWAP8RmzIJjM62981YYlruBFdeo4ty5L7M2HZb0QtSA1TnPYbjcovHErw2k1MPKs4VoxW6VEmfzA9TCMafzB/Ax6ugyfh1/ES8c5QnbBf9XrMUPDdh1dBVfxm+6Q/5/sLjUtv74oh47sDhuwOLE3X1srcLgy/B4KywRBrOOuVkfmo6HaeObQRrrJ3SmizUfJQW2BIuUKtHSgVmDaigfkd2z2WBJevJLPdfb7d6TiEcqoSpgRUkuhXMpNY2UkYTSU55gPd8d8DBCOxRKhPQpPR4sSjo2prQCnFd+8vrCrG5qYeeoJm/MilybVWCBGNQHqhgU1cjuTGH3zDWzp4cJv0Tivs6ZtgXYrETrQqnPzsNv76fkLTofNkyYxGOgJznCIOW3RUFu76cvSnBgg9w5yr/rv06nvYoi8m84baVD/X/+8v6Pn3/9+6f3f/3+20kgEkX8LM6HlZF4YedB0rOZFOqB8tuZbp5fTcIa+WgEUj1jRd/j8a6vHVMmVxef9H2OvDaTa7Ma84Omat9paoHtKeSxDVQZH1zBO6hhQGN0b/Uc1SNd+idwohPslE7vJptN3Diw8fQuSGCwVC/qEvmTTpxoRmB34ynfvU/3IZBWOthuP4yFqoNCdRrpiP397Q9qPHtpNJcgbJilLuDodT5urxJBQw4DH/5fBSqwUSkbLLqBC3vx/iqnYZwGHdz7Ashh+eV7Sz3PCmwPjlFSau3B7tOWVpigaJVlfJTkoDxY/mmuzfrm6uLyc3KW44npxEPBcq+mmZM6aAMzPGE9UjLrcNlu0/DVCyqrfC35PwMArVSfuR8JAAA="),
 		},
 
 		_assestBase64Decode("L3Jlcy90cGwvbGlzdC5odG1s"): &AssestFile{
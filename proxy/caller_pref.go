@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"log"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -51,16 +53,64 @@ func (cpf *CallerPrefConf) GetIP() string {
 
 var ipReg = regexp.MustCompile(`^(\d+\.){3}\d+$`)
 
+// remoteIP the ip of the peer,ipv6 too
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// callerIP X-Real-Ip set by the front proxy(nginx),or the remote ip.
+// X-Real-Ip is used only when the peer is one of trusted_proxies,
+// otherwise any client can pretend to be another caller
+func (apiServer *APIServer) callerIP(req *http.Request) string {
+	peer := remoteIP(req)
+	if apiServer == nil || !ipInNets(peer, apiServer.trustedProxies) {
+		return peer
+	}
+	xRealIP := strings.TrimSpace(req.Header.Get("X-Real-Ip"))
+	if net.ParseIP(xRealIP) != nil {
+		return xRealIP
+	}
+	return peer
+}
+
+// warnUntrustedRealIP X-Real-Ip from a peer not in trusted_proxies is ignored,
+// log it once when the api has caller rules,they match the peer ip now(nginx's ip when trusted_proxies is not set)
+func (api *apiStruct) warnUntrustedRealIP(req *http.Request) {
+	apiServer := api.apiServer
+	if apiServer == nil || req.Header.Get("X-Real-Ip") == "" || !api.hasCallerRules() {
+		return
+	}
+	peer := remoteIP(req)
+	if ipInNets(peer, apiServer.trustedProxies) {
+		return
+	}
+	apiServer.untrustedRealIPOnce.Do(func() {
+		log.Println("[warning]X-Real-Ip from untrusted peer ignored", api.ID, "peer:", peer, "set trusted_proxies for the front proxy")
+	})
+}
+
+// hasCallerRules caller items other than the default all,or allow_only
+func (api *apiStruct) hasCallerRules() bool {
+	if len(api.AllowOnly) > 0 {
+		return true
+	}
+	for _, citem := range api.Caller {
+		if citem.IP != ipAll {
+			return true
+		}
+	}
+	return false
+}
+
 func newCallerPrefConfByHTTPRequest(req *http.Request, api *apiStruct) *CallerPrefConf {
 	prefConf := &CallerPrefConf{}
 	prefConf.prefHostName = make(map[string][]string)
 
-	info := strings.SplitN(req.RemoteAddr, ":", 2)
-	prefConf.ip = info[0]
-	xRealIP := strings.TrimSpace(req.Header.Get("X-Real-Ip"))
-	if xRealIP != "" && ipReg.MatchString(xRealIP) {
-		prefConf.ip = xRealIP
-	}
+	prefConf.ip = api.apiServer.callerIP(req)
 
 	//get from form data
 	prefConf.AddNewPrefHostRaw(apiPrefTypeReq, req.FormValue(apiPrefParamName), ",")
@@ -1,38 +1,510 @@
 package proxy
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_CpfIP(t *testing.T) {
 	req, _ := http.NewRequest("GET", "http://127.0.0.1/", nil)
+	req.RemoteAddr = "127.0.0.1:5555"
 
 	ip0 := "192.168.8.11"
 	req.Header.Set("X-Real-Ip", ip0)
 
-	api := &Api{
-		Name: "test",
+	api := &apiStruct{
+		ID:        "test",
+		apiServer: newTestAPIServer(t),
 	}
 
-	cpf := NewCallerPrefConfByHttpRequest(req, api)
-	if ip0 != cpf.GetIp() {
+	cpf := newCallerPrefConfByHTTPRequest(req, api)
+	if ip0 != cpf.GetIP() {
 		t.Error("ip wrong")
 	}
 
-	caller := NewCaller()
+	//X-Real-Ip from an untrusted peer is ignored
+	req.RemoteAddr = "10.0.0.9:5555"
+	if ip := newCallerPrefConfByHTTPRequest(req, api).GetIP(); ip != "10.0.0.9" {
+		t.Error("forged X-Real-Ip should be ignored,got:", ip)
+	}
+	req.RemoteAddr = "[::1]:5555"
+	if ip := newCallerPrefConfByHTTPRequest(req, &apiStruct{ID: "test"}).GetIP(); ip != "::1" {
+		t.Error("ipv6 peer wrong,got:", ip)
+	}
+
+	caller := newCaller()
 
-	caller.AddNewCallerItem(NewCallerItemMust(IP_ALL))
-	item := caller.getCallerItemByIp(ip0)
+	caller.addNewCallerItem(newCallerItemMust(ipAll))
+	item := caller.getCallerItemByIP(ip0)
 
-	if item.Ip != IP_ALL {
+	if item.IP != ipAll {
 		t.Error("get ip failed")
 	}
-	caller.AddNewCallerItem(NewCallerItemMust(ip0))
+	caller.addNewCallerItem(newCallerItemMust(ip0))
+
+	item = caller.getCallerItemByIP(ip0)
+
+	if item.IP != ip0 {
+		t.Error("get ip wrong,cur_ip:", ip0, "get_ip:", item.IP)
+	}
+}
+
+func Test_HandlerCallerRespHeaders(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "ch", `{"path":"/ch/","enable":true,
+		"caller":[
+			{"ip":"10.0.0.1","enable":true,"resp_headers":{"x-tier":"partner"}},
+			{"ip":"10.0.1.*","enable":true,"resp_headers":{"X-Tier":"internal","X-Team":"ops"}},
+			{"ip":"*.*.*.*","enable":true}
+		],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	cases := []struct {
+		ip   string
+		tier string
+		team string
+	}{
+		{"10.0.0.1", "partner", ""},
+		{"10.0.1.8", "internal", "ops"},
+		{"10.0.2.1", "", ""},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", ts.URL+"/ch/a", nil)
+		req.Header.Set("X-Real-Ip", c.ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Header.Get("X-Tier") != c.tier || resp.Header.Get("X-Team") != c.team {
+			t.Error("ip:", c.ip, "wrong headers:", resp.Header)
+		}
+	}
+}
+
+func Test_HandlerTrustedCallerDiagnostic(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	shadow := testBackendStatus(t, 500)
+	testLoadAPI(t, apiServer, "diag", `{"path":"/diag/","enable":true,"default_master":"m",
+		"caller":[
+			{"ip":"10.0.0.1","enable":true,"trusted":true},
+			{"ip":"*.*.*.*","enable":true}
+		],
+		"hosts":{"m":{"url":"http://127.0.0.1:1/","enable":true},"s1":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(ip string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("GET", ts.URL+"/diag/a", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp, bd
+	}
+
+	resp, bd := get("10.0.0.1")
+	var ret struct {
+		Error string            `json:"error"`
+		Hosts []*diagnosticHost `json:"hosts"`
+	}
+	if err := json.Unmarshal(bd, &ret); err != nil {
+		t.Fatal("expect json body:", err, string(bd))
+	}
+	if resp.StatusCode != http.StatusBadGateway || len(ret.Hosts) != 2 {
+		t.Fatal("wrong diagnostic:", resp.StatusCode, string(bd))
+	}
+	for _, h := range ret.Hosts {
+		switch h.Name {
+		case "m":
+			if !h.Master || h.Error == "" {
+				t.Error("master should have error:", h)
+			}
+		case "s1":
+			if h.Master || h.Status != 500 || h.Error != "" {
+				t.Error("wrong shadow result:", h)
+			}
+		}
+	}
+
+	resp, bd = get("10.0.0.2")
+	if resp.StatusCode != http.StatusBadGateway || !strings.HasPrefix(string(bd), "fetch_error:") {
+		t.Error("expect the generic body:", resp.StatusCode, string(bd))
+	}
+}
+
+func Test_HandlerTrustedCallerForgedIP(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.trustedProxies = nil
+	shadow := testBackendStatus(t, 500)
+	testLoadAPI(t, apiServer, "diag", `{"path":"/diag/","enable":true,"default_master":"m",
+		"caller":[{"ip":"10.0.0.1","enable":true,"trusted":true}],
+		"hosts":{"m":{"url":"http://127.0.0.1:1/","enable":true},"s1":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/diag/a", nil)
+	req.Header.Set("X-Real-Ip", "10.0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	bd, _ := ioutil.ReadAll(resp.Body)
+	if !strings.HasPrefix(string(bd), "fetch_error:") || strings.Contains(string(bd), shadow.URL) {
+		t.Error("forged X-Real-Ip should get the generic body,got:", string(bd))
+	}
+}
+
+func Test_HandlerCallerLog(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	api := testLoadAPI(t, apiServer, "cl", `{"path":"/cl/","enable":true,"access_log":"only",
+		"caller":[
+			{"ip":"10.0.0.1","enable":true,"pref":["h2"],"note":"partner"},
+			{"ip":"10.0.*.*","enable":true,"ignore":["h2"],"note":"office"}
+		],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true},"h2":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(ip string, cookie string) {
+		req, _ := http.NewRequest("GET", ts.URL+"/cl/", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: api.cookieName(), Value: cookie})
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	get("10.0.0.1", "")
+	get("10.0.2.2", "")
+	get("192.168.0.1", "h1")
+
+	//the first line of each request
+	var lines []string
+	for i := 0; i < 100 && len(lines) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ := ioutil.ReadFile(api.accessLogPath())
+		lines = lines[:0]
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.Contains(line, "logindex=1/") {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(lines) != 3 {
+		t.Fatal("expect 3 lines,got:", lines)
+	}
+	wants := []string{
+		"caller:map[ignore:[] note:partner pref:[h2] rule:10.0.0.1]",
+		"caller:map[ignore:[h2] note:office pref:[] rule:10.0.*.*]",
+		"caller:map[ignore:[] note:default all pref:[] req_pref:map[cookie:[h1]] rule:*.*.*.*]",
+	}
+	for i, want := range wants {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("expect %q in log:%s", want, lines[i])
+		}
+	}
+}
+
+func Test_HandlerDebugHost(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	master := testBackend(t, "master")
+	shadow := testBackend(t, "shadow")
+	testLoadAPI(t, apiServer, "dbg", `{"path":"/dbg/","enable":true,"default_master":"m",
+		"caller":[
+			{"ip":"10.0.0.1","enable":true,"trusted":true},
+			{"ip":"*.*.*.*","enable":true}
+		],
+		"hosts":{"m":{"url":"`+master.URL+`/","enable":true},"s1":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	get := func(ip string, debugHost string) (*http.Response, string) {
+		req, _ := http.NewRequest("GET", ts.URL+"/dbg/a", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		req.Header.Set(debugHostHeader, debugHost)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(bd)
+	}
+	resp, body := get("10.0.0.1", "s1")
+	if body != "shadow" || resp.Header.Get("Api-Front-Master") != "m" || resp.Header.Get("Api-Front-Debug-Host") != "s1" {
+		t.Error("trusted caller should get the debug host's response:", body, resp.Header)
+	}
+	if _, body := get("10.0.0.2", "s1"); body != "master" {
+		t.Error("untrusted caller should get the master's response,got:", body)
+	}
+	if _, body := get("10.0.0.1", "none"); body != "master" {
+		t.Error("unknown debug host should be ignored,got:", body)
+	}
+}
+
+func Test_HandlerDebugHostForgedIP(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.trustedProxies = nil
+	master := testBackend(t, "master")
+	shadow := testBackend(t, "shadow")
+	testLoadAPI(t, apiServer, "dbg", `{"path":"/dbg/","enable":true,"default_master":"m",
+		"caller":[{"ip":"10.0.0.1","enable":true,"trusted":true}],
+		"hosts":{"m":{"url":"`+master.URL+`/","enable":true},"s1":{"url":"`+shadow.URL+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	//the client is not a trusted proxy,it can not pretend to be the trusted caller
+	req, _ := http.NewRequest("GET", ts.URL+"/dbg/a", nil)
+	req.Header.Set("X-Real-Ip", "10.0.0.1")
+	req.Header.Set(debugHostHeader, "s1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	bd, _ := ioutil.ReadAll(resp.Body)
+	if string(bd) != "master" || resp.Header.Get("Api-Front-Debug-Host") != "" {
+		t.Error("forged X-Real-Ip should not get the debug host,got:", string(bd))
+	}
+}
+
+func Test_HandlerCallerTimeout(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		rw.Write([]byte("slow"))
+	}))
+	defer backend.Close()
+	api := testLoadAPI(t, apiServer, "ct", `{"path":"/ct/","enable":true,"timeout_ms":100,
+		"caller":[{"ip":"10.0.0.1","enable":true,"timeout_ms":2000}],
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true,"timeout_ms":150}}}`)
+	ts := testServe(t, apiServer)
+
+	caller := api.Caller.getCallerItemByIP("10.0.0.1")
+	if d := caller.requestTimeout(api.Hosts["h1"].requestTimeout(api.requestTimeout())); d != 2*time.Second {
+		t.Error("caller timeout should win,got:", d)
+	}
+	other := api.Caller.getCallerItemByIP("10.0.0.2")
+	if d := other.requestTimeout(api.Hosts["h1"].requestTimeout(api.requestTimeout())); d != 150*time.Millisecond {
+		t.Error("host timeout should win without caller timeout,got:", d)
+	}
+
+	get := func(ip string) (int, string) {
+		req, _ := http.NewRequest("GET", ts.URL+"/ct/", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(bd)
+	}
+	if code, body := get("10.0.0.1"); code != 200 || body != "slow" {
+		t.Error("caller with longer timeout should succeed,got:", code, body)
+	}
+	if code, _ := get("10.0.0.2"); code == 200 {
+		t.Error("other callers should time out")
+	}
+}
+
+func Test_HandlerCallerOnly(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	hosts := make(map[string]string)
+	for _, name := range []string{"h1", "h2", "h3", "h4"} {
+		name := name
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			hits[name]++
+			mu.Unlock()
+			rw.Write([]byte(name))
+		}))
+		t.Cleanup(ts.Close)
+		hosts[name] = ts.URL
+	}
+	api := testLoadAPI(t, apiServer, "only", `{"path":"/only/","enable":true,"default_master":"h1",
+		"caller":[{"ip":"10.0.0.1","enable":true,"only":["h2","h3","h4"],"ignore":["h4"]}],
+		"hosts":{"h1":{"url":"`+hosts["h1"]+`/","enable":true},"h2":{"url":"`+hosts["h2"]+`/","enable":true},
+		"h3":{"url":"`+hosts["h3"]+`/","enable":true},"h4":{"url":"`+hosts["h4"]+`/","enable":true}}}`)
+	ts := testServe(t, apiServer)
+
+	hostNames := func(ip string, pref string) (names []string, master string) {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/only/?"+apiPrefParamName+"="+pref, nil)
+		req.RemoteAddr = ip + ":5555"
+		hs, master, _ := api.getAPIHostsByReq(req)
+		for _, h := range hs {
+			names = append(names, h.Name)
+		}
+		sort.Strings(names)
+		return names, master
+	}
+	for i := 0; i < 10; i++ {
+		names, master := hostNames("10.0.0.1", "")
+		if strings.Join(names, ",") != "h2,h3" || (master != "h2" && master != "h3") {
+			t.Fatal("restricted caller should reach h2 and h3 only,got:", names, master)
+		}
+	}
+	//the pref can pass ignore,but not only
+	if names, master := hostNames("10.0.0.1", "h4"); strings.Join(names, ",") != "h2,h3,h4" || master != "h4" {
+		t.Error("pref should pass ignore,got:", names, master)
+	}
+	if names, master := hostNames("10.0.0.1", "h1"); strings.Join(names, ",") != "h2,h3" || master == "h1" {
+		t.Error("pref should not pass only,got:", names, master)
+	}
+	if names, master := hostNames("10.0.0.2", ""); len(names) != 4 || master != "h1" {
+		t.Error("other callers should reach all hosts,got:", names, master)
+	}
 
-	item = caller.getCallerItemByIp(ip0)
+	req, _ := http.NewRequest("GET", ts.URL+"/only/", nil)
+	req.Header.Set("X-Real-Ip", "10.0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bd, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(bd) != "h2" && string(bd) != "h3" {
+		t.Error("wrong master response:", string(bd))
+	}
+	//wait for the other host
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := hits["h2"] + hits["h3"]
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["h1"] != 0 || hits["h4"] != 0 || hits["h2"] != 1 || hits["h3"] != 1 {
+		t.Error("restricted caller should call h2 and h3 only,got:", hits)
+	}
+}
+
+func Test_CallerCIDR(t *testing.T) {
+	var caller Caller
+	if err := json.Unmarshal([]byte(`[
+		{"ip":"10.0.0.0/8","note":"office","enable":true},
+		{"ip":"10.1.0.0/16","note":"ci","enable":true},
+		{"ip":"10.1.2.3","note":"exact","enable":true},
+		{"ip":"10.1.2.*","note":"wildcard","enable":true},
+		{"ip":"192.168.0.0/16","note":"disabled","enable":false}
+	]`), &caller); err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.init(); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"10.1.2.3":    "exact",
+		"10.1.2.4":    "ci",
+		"10.2.0.1":    "office",
+		"192.168.1.1": "default all",
+		"11.0.0.1":    "default all",
+		"not-an-ip":   "default",
+	}
+	for ip, note := range cases {
+		if got := caller.getCallerItemByIP(ip).Note; got != note {
+			t.Error(ip, "expect:", note, "got:", got)
+		}
+	}
+
+	//the exact ip wins only on the exact match,the wildcard matches the whole ip
+	caller = nil
+	if err := json.Unmarshal([]byte(`[
+		{"ip":"10.0.0.1","note":"exact","enable":true},
+		{"ip":"10.0.0.0/24","note":"net","enable":true},
+		{"ip":"20.0.0.*","note":"wildcard","enable":true}
+	]`), &caller); err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.init(); err != nil {
+		t.Fatal(err)
+	}
+	cases = map[string]string{
+		"10.0.0.1":   "exact",
+		"10.0.0.12":  "net",
+		"110.0.0.19": "default all",
+		"20.0.0.5":   "wildcard",
+		"120.0.0.5":  "default all",
+	}
+	for ip, note := range cases {
+		if got := caller.getCallerItemByIP(ip).Note; got != note {
+			t.Error(ip, "expect:", note, "got:", got)
+		}
+	}
+
+	if _, err := newCallerItem("10.0.0.0/33"); err == nil {
+		t.Error("expect error with the invalid CIDR")
+	}
+}
+
+func Test_APISaveCallerCIDRWrong(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "cidr", `{"path":"/cidr/","enable":true,
+		"hosts":{"h1":{"url":"http://127.0.0.1/","enable":true}}}`)
+	api.Caller = append(api.Caller, &CallerItem{IP: "10.0.0.0/abc", Enable: true})
+	err := api.save()
+	if err == nil || !strings.Contains(err.Error(), "10.0.0.0/abc") {
+		t.Error("expect error with the invalid CIDR,got:", err)
+	}
+}
+
+func Test_CallerWarnUntrustedRealIP(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(out)
+
+	apiServer := newTestAPIServer(t)
+	api := apiServer.newAPI("realip")
+	if err := api.init(); err != nil {
+		t.Fatal(err)
+	}
+	warns := func() int {
+		return strings.Count(buf.String(), "X-Real-Ip from untrusted peer")
+	}
+	req := httptest.NewRequest("GET", "/realip/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Real-Ip", "10.0.0.1")
+	api.warnUntrustedRealIP(req)
+	if warns() != 0 {
+		t.Error("no warning without caller rules,got:", buf.String())
+	}
+
+	api.AllowOnly = []string{"10.0.0.0/8"}
+	api.warnUntrustedRealIP(req)
+	api.warnUntrustedRealIP(req)
+	if warns() != 1 {
+		t.Error("expect one warning,got:", buf.String())
+	}
 
-	if item.Ip != ip0 {
-		t.Error("get ip wrong,cur_ip:", ip_0, "get_ip:", item.Ip)
+	trusted := newTestAPIServer(t)
+	tapi := trusted.newAPI("realip")
+	tapi.AllowOnly = []string{"10.0.0.0/8"}
+	req.RemoteAddr = "127.0.0.1:1234"
+	tapi.warnUntrustedRealIP(req)
+	if warns() != 1 {
+		t.Error("X-Real-Ip from trusted proxy should not warn,got:", buf.String())
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 )
 
@@ -20,6 +22,14 @@ type APIServerManager struct {
 	ConfPath string
 	LogFile  *os.File
 	mainConf *mainConf
+
+	// ConfigSourceFunc create the api conf source of the server,
+	// default load from files in confDir.
+	// must be set before Start
+	ConfigSourceFunc func(serverID string, confDir string) ConfigSource
+
+	// StrictCheck exit when the startup self check has errors
+	StrictCheck bool
 }
 
 // NewAPIServerManager init manager
@@ -27,8 +37,6 @@ func NewAPIServerManager(confPath string) *APIServerManager {
 	manager := &APIServerManager{}
 	manager.mainConf = loadMainConf(confPath)
 	manager.ConfPath, _ = filepath.Abs(confPath)
-
-	manager.ps = newPortServerManager(manager)
 	return manager
 }
 
@@ -37,10 +45,41 @@ func (manager *APIServerManager) Start() {
 	logPath := filepath.Dir(filepath.Dir(manager.ConfPath)) + "/log/api-front.log"
 	manager.setupLog(logPath)
 	defer manager.LogFile.Close()
+	manager.ps = newPortServerManager(manager)
+	if err := manager.selfCheck(); err != nil {
+		log.Fatalln("[fatal]", err)
+	}
+	go manager.saveOnSignal()
 	manager.ps.start()
+	manager.saveQuotas()
 	log.Println("all server shutdown")
 }
 
+// saveOnSignal save the quotas before exit,AutoSave only runs every 10s
+func (manager *APIServerManager) saveOnSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	sig := <-ch
+	log.Println("[info]got signal", sig, "save the quotas and exit")
+	manager.saveQuotas()
+	os.Exit(0)
+}
+
+func (manager *APIServerManager) saveQuotas() {
+	for _, ps := range manager.ps.PortServerMap {
+		for _, apiServer := range ps.APIServiers {
+			apiServer.quota.SaveFile()
+		}
+	}
+}
+
+func (manager *APIServerManager) newConfigSource(serverID string, confDir string) ConfigSource {
+	if manager.ConfigSourceFunc != nil {
+		return manager.ConfigSourceFunc(serverID, confDir)
+	}
+	return newFileConfigSource(confDir)
+}
+
 func (manager *APIServerManager) rootConfDir() string {
 	return filepath.Dir(manager.ConfPath) + string(filepath.Separator)
 }
@@ -1,8 +1,10 @@
 package proxy
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"strings"
@@ -13,6 +15,38 @@ type portServer struct {
 	Port        int
 	APIServiers map[string]*APIServer
 	Manager     *APIServerManager
+	H2C         bool
+	tlsConfig   *tls.Config //nil when not serving TLS
+	conns       *connStats
+}
+
+func (ps *portServer) newHTTPServer(addr string) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: ps, TLSConfig: ps.tlsConfig}
+	if ps.conns == nil {
+		ps.publishConnStats()
+	}
+	srv.ConnState = ps.conns.track
+	if ps.H2C {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols.SetHTTP2(ps.tlsConfig != nil)
+	}
+	return srv
+}
+
+// serve on the listener,listen on the addr of srv when ln is nil
+func (ps *portServer) serve(srv *http.Server, ln net.Listener) error {
+	if ln == nil {
+		if ps.tlsConfig != nil {
+			return srv.ListenAndServeTLS("", "")
+		}
+		return srv.ListenAndServe()
+	}
+	if ps.tlsConfig != nil {
+		return srv.ServeTLS(ln, "", "")
+	}
+	return srv.Serve(ln)
 }
 
 // ServeHTTP serve all http request
@@ -64,13 +98,19 @@ func newPortServerManager(manager *APIServerManager) *portServerManager {
 			log.Println("[warning]server ", signConf.Name, signConf.Port, " is not enable,skip")
 			continue
 		}
-		psm.addServer(signConf)
+		if err := psm.addServer(signConf); err != nil {
+			log.Fatalln("[fatal]add server", signConf.Id, "failed:", err)
+		}
 	}
 	return psm
 }
 
 // AddServer add new server
-func (psm *portServerManager) addServer(itemConf *serverVhost) bool {
+func (psm *portServerManager) addServer(itemConf *serverVhost) error {
+	apiServer, err := newAPIServer(itemConf, psm.manager)
+	if err != nil {
+		return err
+	}
 	if _, has := psm.PortServerMap[itemConf.Port]; !has {
 		psm.PortServerMap[itemConf.Port] = &portServer{
 			Port:        itemConf.Port,
@@ -79,24 +119,47 @@ func (psm *portServerManager) addServer(itemConf *serverVhost) bool {
 		}
 	}
 	ps := psm.PortServerMap[itemConf.Port]
-	apiServer := newAPIServer(itemConf, psm.manager)
+	if itemConf.H2C {
+		ps.H2C = true
+	}
+	if itemConf.TLS != nil && ps.tlsConfig == nil {
+		tlsConfig, err := itemConf.TLS.tlsConfig(apiServer.rootConfDir())
+		if err != nil {
+			return err
+		}
+		ps.tlsConfig = tlsConfig
+	}
 	log.Println("[info]add server", apiServer.serverNames())
 	ps.APIServiers[apiServer.GetServerID()] = apiServer
-	return true
+	return nil
 }
 
 func (psm *portServerManager) start() {
 	var wg sync.WaitGroup
 	log.Println("[info]ports total:", len(psm.PortServerMap))
+
+	lns := systemdListeners()
+
 	for port, ps := range psm.PortServerMap {
 		wg.Add(1)
-		go (func(port int, ps *portServer) {
+		go (func(port int, ps *portServer, ln net.Listener) {
 			addr := fmt.Sprintf(":%d", port)
-			log.Println(addr, "start")
-			err := http.ListenAndServe(addr, ps)
+			srv := ps.newHTTPServer(addr)
+			if ln != nil {
+				log.Println(addr, "start with systemd socket")
+			} else {
+				log.Println(addr, "start,h2c:", ps.H2C, "tls:", ps.tlsConfig != nil)
+			}
+			err := ps.serve(srv, ln)
 			log.Println("[fatal]", addr, "exit:", err)
 			wg.Done()
-		})(port, ps)
+		})(port, ps, lns[port])
+	}
+	for port, ln := range lns {
+		if _, has := psm.PortServerMap[port]; !has {
+			log.Println("[warning]systemd listener not used,close it:", ln.Addr())
+			ln.Close()
+		}
 	}
 	wg.Wait()
 	log.Println("[fatal]portServer exit")
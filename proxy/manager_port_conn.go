@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// connStats the connections of a port,set as the http.Server.ConnState hook
+type connStats struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+	idle   int64
+	total  int64 //新建连接总数
+
+	sec     int64 //当前计数的秒
+	secNew  int64 //当前秒的新建连接数
+	lastNew int64 //上一秒的新建连接数
+
+	now func() time.Time
+}
+
+// connStatsSnapshot published in expvar api_front.conns.{port}
+type connStatsSnapshot struct {
+	Active    int64 `json:"active"`
+	Idle      int64 `json:"idle"`
+	Total     int64 `json:"total"`
+	NewPerSec int64 `json:"new_per_sec"`
+}
+
+func newConnStats() *connStats {
+	return &connStats{
+		states: make(map[net.Conn]http.ConnState),
+		now:    time.Now,
+	}
+}
+
+func (s *connStats) track(conn net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states[conn] == http.StateIdle {
+		s.idle--
+	}
+	switch state {
+	case http.StateNew:
+		s.total++
+		s.countNew()
+	case http.StateIdle:
+		s.idle++
+	case http.StateClosed, http.StateHijacked:
+		delete(s.states, conn)
+		return
+	}
+	s.states[conn] = state
+}
+
+func (s *connStats) countNew() {
+	sec := s.now().Unix()
+	if sec != s.sec {
+		s.lastNew = 0
+		if sec == s.sec+1 {
+			s.lastNew = s.secNew
+		}
+		s.sec = sec
+		s.secNew = 0
+	}
+	s.secNew++
+}
+
+func (s *connStats) snapshot() *connStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss := &connStatsSnapshot{
+		Active: int64(len(s.states)),
+		Idle:   s.idle,
+		Total:  s.total,
+	}
+	//the last full second
+	switch s.now().Unix() {
+	case s.sec:
+		ss.NewPerSec = s.lastNew
+	case s.sec + 1:
+		ss.NewPerSec = s.secNew
+	}
+	return ss
+}
+
+func (ps *portServer) publishConnStats() {
+	ps.conns = newConnStats()
+	expvarConns.Set(strconv.Itoa(ps.Port), expvar.Func(func() interface{} {
+		return ps.conns.snapshot()
+	}))
+}
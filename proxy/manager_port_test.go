@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_PortServerH2C(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "h2c ok")
+	testLoadAPI(t, apiServer, "h2", `{"path":"/h2/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+
+	ps := &portServer{
+		Port:        8080,
+		APIServiers: map[string]*APIServer{apiServer.GetServerID(): apiServer},
+		H2C:         true,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := ps.newHTTPServer(ln.Addr().String())
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	//multiplexed requests on one h2c connection
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get("http://" + ln.Addr().String() + "/h2/")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			bd, _ := ioutil.ReadAll(resp.Body)
+			if resp.ProtoMajor != 2 {
+				t.Error("expect HTTP/2,got:", resp.Proto)
+			}
+			if string(bd) != "h2c ok" {
+				t.Error("wrong body:", resp.StatusCode, string(bd))
+			}
+		}()
+	}
+	wg.Wait()
+
+	//http/1.1 still works
+	_, body := testGet(t, "http://"+ln.Addr().String()+"/h2/")
+	if body != "h2c ok" {
+		t.Error("http/1.1 wrong body:", body)
+	}
+}
+
+func Test_PortServerConnStats(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	backend := testBackend(t, "ok")
+	testLoadAPI(t, apiServer, "cs", `{"path":"/cs/","enable":true,"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+	ps := &portServer{
+		Port:        18080,
+		APIServiers: map[string]*APIServer{apiServer.GetServerID(): apiServer},
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := ps.newHTTPServer(ln.Addr().String())
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	waitStats := func(check func(s *connStatsSnapshot) bool) *connStatsSnapshot {
+		var s *connStatsSnapshot
+		for i := 0; i < 100; i++ {
+			if s = ps.conns.snapshot(); check(s) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return s
+	}
+
+	//the admin page keeps the conn alive
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := waitStats(func(s *connStatsSnapshot) bool { return s.Active == 1 })
+	if s.Active != 1 || s.Total != 1 || s.Idle != 0 {
+		t.Errorf("after dial,wrong stats:%+v", s)
+	}
+
+	fmt.Fprintf(conn, "GET /_/about HTTP/1.1\r\nHost: %s\r\n\r\n", ln.Addr())
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	s = waitStats(func(s *connStatsSnapshot) bool { return s.Idle == 1 })
+	if s.Active != 1 || s.Idle != 1 {
+		t.Errorf("after request,wrong stats:%+v", s)
+	}
+
+	conn.Close()
+	s = waitStats(func(s *connStatsSnapshot) bool { return s.Active == 0 })
+	if s.Active != 0 || s.Idle != 0 || s.Total != 1 {
+		t.Errorf("after close,wrong stats:%+v", s)
+	}
+
+	var ev map[string]*connStatsSnapshot
+	json.Unmarshal([]byte(expvarConns.String()), &ev)
+	if ev["18080"] == nil || ev["18080"].Total != 1 {
+		t.Error("expvar wrong:", expvarConns.String())
+	}
+}
+
+func Test_ConnStatsNewPerSec(t *testing.T) {
+	now := time.Unix(100, 0)
+	s := newConnStats()
+	s.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		s.track(&net.TCPConn{}, http.StateNew)
+	}
+	if n := s.snapshot().NewPerSec; n != 0 {
+		t.Error("the current second is not full,got:", n)
+	}
+	now = time.Unix(101, 0)
+	if n := s.snapshot().NewPerSec; n != 3 {
+		t.Error("expect 3,got:", n)
+	}
+	s.track(&net.TCPConn{}, http.StateNew)
+	if n := s.snapshot().NewPerSec; n != 3 {
+		t.Error("expect 3 of the last second,got:", n)
+	}
+	now = time.Unix(105, 0)
+	if n := s.snapshot().NewPerSec; n != 0 {
+		t.Error("expect 0,got:", n)
+	}
+}
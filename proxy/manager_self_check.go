@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	selfCheckOk      = "ok"
+	selfCheckWarning = "warning"
+	selfCheckError   = "error"
+)
+
+// selfCheckLookupHost resolve the host,can be replaced in test
+var selfCheckLookupHost = func(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := net.DefaultResolver.LookupHost(ctx, host)
+	return err
+}
+
+type selfCheckItem struct {
+	Server string
+	API    string
+	Host   string
+	Level  string
+	Msg    string
+}
+
+// selfCheckReport result of the startup self check
+type selfCheckReport struct {
+	Items []*selfCheckItem
+}
+
+func (r *selfCheckReport) add(server, apiID, host, level, msg string) {
+	r.Items = append(r.Items, &selfCheckItem{Server: server, API: apiID, Host: host, Level: level, Msg: msg})
+}
+
+func (r *selfCheckReport) num(level string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// String the summary table
+func (r *selfCheckReport) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tAPI\tHOST\tLEVEL\tMSG")
+	for _, item := range r.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.Server, item.API, item.Host, item.Level, item.Msg)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "total:%d warning:%d error:%d", len(r.Items), r.num(selfCheckWarning), r.num(selfCheckError))
+	return buf.String()
+}
+
+// selfCheck check all servers before serving,
+// return error when StrictCheck is set and there are errors
+func (manager *APIServerManager) selfCheck() error {
+	report := &selfCheckReport{}
+	ports := make([]int, 0, len(manager.ps.PortServerMap))
+	for port := range manager.ps.PortServerMap {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		ps := manager.ps.PortServerMap[port]
+		ids := make([]string, 0, len(ps.APIServiers))
+		for id := range ps.APIServiers {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			ps.APIServiers[id].selfCheck(report)
+		}
+	}
+	log.Println("[info]self check report:\n" + report.String())
+
+	errNum := report.num(selfCheckError)
+	if errNum == 0 {
+		return nil
+	}
+	if manager.StrictCheck {
+		return fmt.Errorf("self check failed,%d errors", errNum)
+	}
+	log.Println("[warning]self check has", errNum, "errors")
+	return nil
+}
+
+func (apiServer *APIServer) selfCheck(report *selfCheckReport) {
+	serverID := apiServer.GetServerID()
+	apiNames, err := apiServer.confSource.List()
+	if err != nil {
+		report.add(serverID, "", "", selfCheckError, "list api conf failed:"+err.Error())
+		return
+	}
+	sort.Strings(apiNames)
+
+	//the conf loading and dns lookups are slow,not under the lock
+	apis := make(map[string]*apiStruct)
+	apiServer.Rw.RLock()
+	for _, apiName := range apiNames {
+		if api := apiServer.getAPIByID(apiName); api != nil {
+			apis[apiName] = api
+		}
+	}
+	apiServer.Rw.RUnlock()
+
+	for _, apiName := range apiNames {
+		api := apis[apiName]
+		if api == nil {
+			//load it again to get the reason
+			_, err := loadAPIByConf(apiServer, apiName)
+			msg := "not loaded"
+			if err != nil {
+				msg = "load failed:" + err.Error()
+			}
+			report.add(serverID, apiName, "", selfCheckError, msg)
+			continue
+		}
+		api.selfCheck(report)
+	}
+}
+
+func (api *apiStruct) selfCheck(report *selfCheckReport) {
+	serverID := api.apiServer.GetServerID()
+	api.rw.RLock()
+	if !api.Enable {
+		api.rw.RUnlock()
+		report.add(serverID, api.ID, "", selfCheckWarning, "not enable")
+		return
+	}
+	if api.Hosts.activeHostsNum() == 0 {
+		report.add(serverID, api.ID, "", selfCheckError, "no enabled hosts")
+	}
+	if api.DefaultMaster != "" {
+		if _, has := api.Hosts[api.DefaultMaster]; !has {
+			report.add(serverID, api.ID, api.DefaultMaster, selfCheckWarning, "default master not found")
+		}
+	}
+
+	names := make([]string, 0, len(api.Hosts))
+	for name := range api.Hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	//the dns lookups are slow,check the enabled hosts after unlock
+	checkNames := make([]string, 0, len(names))
+	checkHosts := make([]*Host, 0, len(names))
+	for _, name := range names {
+		host := api.Hosts[name]
+		if !host.Enable {
+			report.add(serverID, api.ID, name, selfCheckWarning, "not enable")
+			continue
+		}
+		checkNames = append(checkNames, name)
+		checkHosts = append(checkHosts, host)
+	}
+	api.rw.RUnlock()
+
+	for i, host := range checkHosts {
+		level, msg := host.selfCheck()
+		report.add(serverID, api.ID, checkNames[i], level, msg)
+	}
+}
+
+func (h *Host) selfCheck() (level string, msg string) {
+	u, err := url.Parse(h.URLStr)
+	if err != nil {
+		return selfCheckError, "url wrong:" + err.Error()
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return selfCheckError, "url scheme wrong:" + h.URLStr
+	}
+	hostname := u.Hostname()
+	if hostname == "" {
+		return selfCheckError, "url has no host:" + h.URLStr
+	}
+	if net.ParseIP(hostname) == nil {
+		if err := selfCheckLookupHost(hostname); err != nil {
+			return selfCheckError, "dns failed:" + err.Error()
+		}
+	}
+	return selfCheckOk, h.URLStr
+}
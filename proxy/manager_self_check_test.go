@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ManagerSelfCheck(t *testing.T) {
+	lookup := selfCheckLookupHost
+	defer func() {
+		selfCheckLookupHost = lookup
+	}()
+	selfCheckLookupHost = func(host string) error {
+		if host == "ok.example.com" {
+			return nil
+		}
+		return fmt.Errorf("no such host")
+	}
+
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "good", `{"path":"/good/","enable":true,"hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://ok.example.com/","enable":true}
+	}}`)
+	manager := apiServer.manager
+	manager.ps = &portServerManager{
+		PortServerMap: map[int]*portServer{
+			8080: {Port: 8080, APIServiers: map[string]*APIServer{"test": apiServer}},
+		},
+		manager: manager,
+	}
+
+	for _, strict := range []bool{false, true} {
+		manager.StrictCheck = strict
+		if err := manager.selfCheck(); err != nil {
+			t.Error("expect no error,strict:", strict, err)
+		}
+	}
+
+	testLoadAPI(t, apiServer, "bad_dns", `{"path":"/bad_dns/","enable":true,"hosts":{
+		"h1":{"url":"http://not-exists.example.com/","enable":true}
+	}}`)
+	ioutil.WriteFile(filepath.Join(apiServer.getConfDir(), "bad_conf.json"), []byte("{"), 0644)
+
+	report := &selfCheckReport{}
+	apiServer.selfCheck(report)
+	if n := report.num(selfCheckError); n != 2 {
+		t.Error("expect 2 errors,got:", n, "\n"+report.String())
+	}
+
+	manager.StrictCheck = false
+	if err := manager.selfCheck(); err != nil {
+		t.Error("non-strict should not fail:", err)
+	}
+	manager.StrictCheck = true
+	if err := manager.selfCheck(); err == nil {
+		t.Error("strict should fail")
+	}
+}
+
+func Test_ManagerSelfCheckNoLockOnLookup(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "dns", `{"path":"/dns/","enable":true,"hosts":{
+		"h1":{"url":"http://ok.example.com/","enable":true}
+	}}`)
+
+	lookup := selfCheckLookupHost
+	defer func() {
+		selfCheckLookupHost = lookup
+	}()
+	var lookups int
+	selfCheckLookupHost = func(host string) error {
+		lookups++
+		//the api can be reloaded or edited while looking up
+		if !apiServer.Rw.TryLock() {
+			t.Error("api server locked while dns lookup")
+		} else {
+			apiServer.Rw.Unlock()
+		}
+		if !api.rw.TryLock() {
+			t.Error("api locked while dns lookup")
+		} else {
+			api.rw.Unlock()
+		}
+		return nil
+	}
+
+	report := &selfCheckReport{}
+	apiServer.selfCheck(report)
+	if lookups != 1 || report.num(selfCheckError) != 0 {
+		t.Error("expect 1 lookup and no errors,got:", lookups, "\n"+report.String())
+	}
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart SD_LISTEN_FDS_START,the first fd passed by systemd
+var listenFdsStart = 3
+
+// systemdListeners get the listeners inherited from systemd socket activation,
+// key is the port they are bound to.
+// return nil when LISTEN_FDS is not set or not for current process,
+// the fds which are not tcp listeners are closed and skipped
+func systemdListeners() map[int]net.Listener {
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds < 1 {
+		return nil
+	}
+	pid := os.Getenv("LISTEN_PID")
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		log.Println("[warning]LISTEN_PID not match,skip systemd listeners,LISTEN_PID=", pid)
+		return nil
+	}
+	//not pass them to child process
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	lns := make(map[int]net.Listener)
+	for fd := listenFdsStart; fd < listenFdsStart+nfds; fd++ {
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			log.Println("[warning]skip systemd fd", fd, ",not a listener:", err)
+			continue
+		}
+		addr, ok := ln.Addr().(*net.TCPAddr)
+		if !ok {
+			log.Println("[warning]skip systemd listener,not tcp:", ln.Addr())
+			ln.Close()
+			continue
+		}
+		log.Println("[info]systemd listener fd=", fd, "addr=", addr)
+		lns[addr.Port] = ln
+	}
+	return lns
+}
@@ -0,0 +1,118 @@
+//go:build !windows
+// +build !windows
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_SystemdListeners(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	//simulate the fd inherited from systemd
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	//systemdListeners will close the fd,so pass a dup of it
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fdStartOld := listenFdsStart
+	listenFdsStart = fd
+	defer func() {
+		listenFdsStart = fdStartOld
+	}()
+
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_PID", fmt.Sprintf("%d", os.Getpid()))
+
+	lns := systemdListeners()
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("LISTEN_FDS should be unset")
+	}
+	inherited, has := lns[port]
+	if !has {
+		t.Fatal("listener for port not found:", port, lns)
+	}
+	go http.Serve(inherited, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("inherited"))
+	}))
+	defer inherited.Close()
+
+	_, body := testGet(t, fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if body != "inherited" {
+		t.Error("wrong body:", body)
+	}
+}
+
+func Test_SystemdListenersNotListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	notListener, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notListener.Close()
+
+	//the first fd is not a listener,the next one is
+	fdBad, err := syscall.Dup(int(notListener.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fdLn, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fdLn != fdBad+1 {
+		syscall.Close(fdBad)
+		syscall.Close(fdLn)
+		t.Skip("the fds are not continuous:", fdBad, fdLn)
+	}
+	fdStartOld := listenFdsStart
+	listenFdsStart = fdBad
+	defer func() {
+		listenFdsStart = fdStartOld
+	}()
+
+	t.Setenv("LISTEN_FDS", "2")
+	t.Setenv("LISTEN_PID", fmt.Sprintf("%d", os.Getpid()))
+
+	lns := systemdListeners()
+	inherited, has := lns[port]
+	if !has {
+		t.Fatal("the listener after the bad fd should be kept:", lns)
+	}
+	inherited.Close()
+}
+
+func Test_SystemdListenersOtherPid(t *testing.T) {
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_PID", "1")
+	if lns := systemdListeners(); lns != nil {
+		t.Error("should skip listeners of other process", lns)
+	}
+}
@@ -134,7 +134,7 @@ func (conf *oauth2Conf) getUserInfo(tok *oauth2.Token) (*User, error) {
 		vs[key] = val
 	}
 	if vs["id"] == "" {
-		return nil, fmt.Errorf("response has no user info:" + string(data))
+		return nil, fmt.Errorf("response has no user info:%s", data)
 	}
 	user := &User{
 		ID:       vs["id"],
@@ -6,12 +6,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type routerItem struct {
 	APIName  string
 	BindPath string
 	Hander   http.HandlerFunc
+
+	//match the path without the trailing slash,eg /a for bind path /a/
+	NoTrailingSlash bool
 }
 
 func newRouterItem(apiName string, bindPath string, hander http.HandlerFunc) *routerItem {
@@ -22,17 +26,54 @@ func newRouterItem(apiName string, bindPath string, hander http.HandlerFunc) *ro
 	}
 }
 
-type routers struct {
+// routerTable the routing table,never changed after built.
+// binding builds a new one and swaps it,so the requests in flight
+// keep using the snapshot they got
+type routerTable struct {
 	BindMap   map[string]*routerItem
 	BindPaths bindPathsStruct
-	rw        sync.RWMutex
+}
+
+func newRouterTable(bindMap map[string]*routerItem) *routerTable {
+	bindPaths := make(bindPathsStruct, 0, len(bindMap))
+	for bindPath := range bindMap {
+		bindPaths = append(bindPaths, bindPath)
+	}
+	sort.Sort(bindPaths)
+	return &routerTable{
+		BindMap:   bindMap,
+		BindPaths: bindPaths,
+	}
+}
+
+type routers struct {
+	table atomic.Value //*routerTable
+	mu    sync.Mutex   //for the writers
 }
 
 func newRouters() *routers {
-	return &routers{
-		BindMap:   make(map[string]*routerItem),
-		BindPaths: make(bindPathsStruct, 0),
+	rs := &routers{}
+	rs.table.Store(newRouterTable(make(map[string]*routerItem)))
+	return rs
+}
+
+func (rs *routers) load() *routerTable {
+	return rs.table.Load().(*routerTable)
+}
+
+// update change a copy of current table and swap it
+func (rs *routers) update(fn func(bindMap map[string]*routerItem)) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	old := rs.load()
+	bindMap := make(map[string]*routerItem, len(old.BindMap)+1)
+	for bindPath, router := range old.BindMap {
+		bindMap[bindPath] = router
 	}
+	fn(bindMap)
+	table := newRouterTable(bindMap)
+	rs.table.Store(table)
+	log.Println("routers_bind_path:", strings.Join(table.BindPaths, ","))
 }
 
 type bindPathsStruct []string
@@ -52,55 +93,31 @@ func (bs bindPathsStruct) Swap(i, j int) {
 }
 
 func (rs *routers) String() string {
-	return strings.Join(rs.BindPaths, ",")
+	return strings.Join(rs.load().BindPaths, ",")
 }
 
 func (rs *routers) getRouterByReqPath(urlPath string) *routerItem {
 	if strings.HasPrefix(urlPath, "/_") {
 		return nil
 	}
-	rs.rw.RLock()
-	defer rs.rw.RUnlock()
-	for _, bindPath := range rs.BindPaths {
+	table := rs.load()
+	if router, has := table.BindMap[urlPath+"/"]; has && router.NoTrailingSlash {
+		return router
+	}
+	for _, bindPath := range table.BindPaths {
 		if bindPath != "" && strings.HasPrefix(urlPath, bindPath) {
-			return rs.BindMap[bindPath]
+			return table.BindMap[bindPath]
 		}
 	}
 	return nil
 }
 
-// Sort by name
-func (rs *routers) Sort() {
-	rs.rw.Lock()
-	defer rs.rw.Unlock()
-
-	bindPaths := make(bindPathsStruct, 0, len(rs.BindMap))
-	for bindPath := range rs.BindMap {
-		bindPaths = append(bindPaths, bindPath)
-	}
-	sort.Sort(bindPaths)
-	rs.BindPaths = bindPaths
-	log.Println("routers_bind_path:", rs.String())
-}
-
-func (rs *routers) deleteRouterByPath(bindPath string) {
-	func() {
-		rs.rw.Lock()
-		defer rs.rw.Unlock()
-		if router, has := rs.BindMap[bindPath]; has {
-			log.Println("unbind router,apiName=", router.APIName, "bindPath=", bindPath)
-			delete(rs.BindMap, bindPath)
+// deleteAPIRouter unbind the path only when it is bound to the api
+func (rs *routers) deleteAPIRouter(bindPath string, apiName string) {
+	rs.update(func(bindMap map[string]*routerItem) {
+		if router, has := bindMap[bindPath]; has && router.APIName == apiName {
+			log.Println("unbind router,apiName=", apiName, "bindPath=", bindPath)
+			delete(bindMap, bindPath)
 		}
-	}()
-	rs.Sort()
-}
-
-func (rs *routers) bindRouter(bindPath string, router *routerItem) {
-	func() {
-		rs.rw.Lock()
-		defer rs.rw.Unlock()
-		rs.BindMap[bindPath] = router
-		log.Println("bind router,apiName=", router.APIName, "bindPath=", bindPath)
-	}()
-	rs.Sort()
+	})
 }
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"sync"
 )
 
 type mainConf struct {
@@ -18,6 +19,10 @@ type mainConf struct {
 	PortRange    *PortRange     `json:"port_range"`
 	StoreApiUrl  string         `json:"store_api_url"`
 	StoreViewUrl string         `json:"store_view_url"`
+
+	AdminMutationPerMin int               `json:"admin_mutation_per_min"` //管理后台每分钟最多保存/回滚配置的次数(所有子服务共享),超过返回429,0为不限制
+	adminLimiterOnce    sync.Once         `json:"-"`
+	adminRate           *adminRateLimiter `json:"-"`
 }
 
 type PortRange struct {
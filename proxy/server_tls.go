@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// serverTLS the port is served with TLS,
+// relative paths are relative to the conf dir
+type serverTLS struct {
+	CertFile     string `json:"cert_file"`      //证书文件,pem格式
+	KeyFile      string `json:"key_file"`       //私钥文件,pem格式
+	ClientCAFile string `json:"client_ca_file"` //签发client证书的CA,不为空时要求并验证client证书
+}
+
+// tlsConfig load the certificates,the client certificate is required when client_ca_file is set
+func (st *serverTLS) tlsConfig(confDir string) (*tls.Config, error) {
+	absPath := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(confDir, p)
+	}
+	cert, err := tls.LoadX509KeyPair(absPath(st.CertFile), absPath(st.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("tls cert wrong:%s", err)
+	}
+	conf := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if st.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(absPath(st.ClientCAFile))
+		if err != nil {
+			return nil, fmt.Errorf("tls client_ca_file wrong:%s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("tls client_ca_file wrong:no certificate found")
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testWritePem write the der certificate and the key to files in dir
+func testWritePem(t *testing.T, dir string, name string, der []byte, key *ecdsa.PrivateKey) {
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPem, 0644); err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testTLSFiles ca.crt,server.crt/key for 127.0.0.1 and client.crt/key signed by the same CA
+func testTLSFiles(t *testing.T, dir string) (roots *x509.CertPool, client tls.Certificate) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDer)
+	testWritePem(t, dir, "ca", caDer, caKey)
+
+	issue := func(name string, serial int64, tpl *x509.Certificate) tls.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tpl.SerialNumber = big.NewInt(serial)
+		tpl.Subject = pkix.Name{CommonName: name}
+		tpl.NotBefore = time.Now().Add(-time.Hour)
+		tpl.NotAfter = time.Now().Add(time.Hour)
+		tpl.KeyUsage = x509.KeyUsageDigitalSignature
+		der, err := x509.CreateCertificate(rand.Reader, tpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testWritePem(t, dir, name, der, key)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	issue("server", 2, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	client = issue("client", 3, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	roots = x509.NewCertPool()
+	roots.AddCert(caCert)
+	return roots, client
+}
+
+func Test_PortServerTLSClientCert(t *testing.T) {
+	dir := t.TempDir()
+	roots, clientCert := testTLSFiles(t, dir)
+
+	apiServer := newTestAPIServer(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.Header.Get("X-Client-Cert-Subject")))
+	}))
+	defer backend.Close()
+	testLoadAPI(t, apiServer, "tls", `{"path":"/tls/","enable":true,"client_cert_headers":{},
+		"hosts":{"h1":{"url":"`+backend.URL+`/","enable":true}}}`)
+
+	st := &serverTLS{CertFile: "server.crt", KeyFile: "server.key", ClientCAFile: "ca.crt"}
+	tlsConfig, err := st.tlsConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := &portServer{
+		Port:        8443,
+		APIServiers: map[string]*APIServer{apiServer.GetServerID(): apiServer},
+		tlsConfig:   tlsConfig,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := ps.newHTTPServer(ln.Addr().String())
+	go ps.serve(srv, ln)
+	defer srv.Close()
+
+	get := func(certs []tls.Certificate) (string, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		resp, err := client.Get("https://" + ln.Addr().String() + "/tls/")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		bd, _ := ioutil.ReadAll(resp.Body)
+		return string(bd), nil
+	}
+	body, err := get([]tls.Certificate{clientCert})
+	if err != nil || body != "CN=client" {
+		t.Error("expect the subject of the client cert,got:", body, err)
+	}
+	if _, err := get(nil); err == nil {
+		t.Error("expect error without the client cert")
+	}
+
+	st.ClientCAFile = "not_exists.crt"
+	if _, err := st.tlsConfig(dir); err == nil {
+		t.Error("expect error with the wrong client_ca_file")
+	}
+}
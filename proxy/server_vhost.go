@@ -18,6 +18,27 @@ type serverVhost struct {
 	Users        users        `json:"users"`         //具有管理权限的用户列表
 	rw           sync.RWMutex `json:"-"`
 	StoreAble    bool         `json:"store"` //是否需要保存-远程保存
+	H2C          bool         `json:"h2c"`   //端口同时支持HTTP/2 cleartext(h2c),同端口任意一个服务开启即生效
+	TLS          *serverTLS   `json:"tls"`   //端口以TLS运行,同端口的服务使用第一个配置的,如 {"cert_file":"server.crt","key_file":"server.key","client_ca_file":"ca.crt"}
+
+	AdminTitle string `json:"admin_title"` //管理页面的标题,默认为 api front
+	AdminLogo  string `json:"admin_logo"`  //管理页面的logo图片地址
+	Favicon    string `json:"favicon"`     //favicon文件路径,相对路径为相对于conf目录
+
+	NotFoundJSON         string `json:"not_found_json"`          //没有匹配的接口时返回的json,支持变量 ${status},${method},${path}
+	MethodNotAllowedJSON string `json:"method_not_allowed_json"` //接口不允许该method时返回的json,变量同上
+
+	RewriteMigrated bool `json:"rewrite_migrated"` //接口配置升级到新的格式后,是否写回配置文件
+
+	Environment string `json:"environment"` //环境,如 staging、prod,写入访问日志(env=)和expvar,多个实例的日志汇总时用于区分
+
+	RequestIDHeader string `json:"request_id_header"` //请求id的header名称,如 X-Request-Id、X-Correlation-Id,请求中有则沿用,没有则生成,转发给后端并返回给client,为空不启用
+
+	MaxConcurrentReloads int `json:"max_concurrent_reloads"` //同时加载(如批量导入、配置中心变更)的接口数,默认4
+
+	GzipLevel int `json:"gzip_level"` //gzip压缩级别,-2(HuffmanOnly)~9,用于管理页面的response和接口未设置gzip_level时,默认为-1(DefaultCompression,相当于6)
+
+	TrustedProxies []string `json:"trusted_proxies"` //可信的前端代理(如nginx)的ip或CIDR,只有来自这些ip的请求才使用X-Real-Ip作为调用方ip,为空时都使用连接的ip
 }
 
 func (sv *serverVhost) HomeUrl(serverName string) string {
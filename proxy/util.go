@@ -111,7 +111,7 @@ func URLPathClean(urlPath string) string {
 
 var textContentTypes = []string{"text", "javascript", "json"}
 
-//IsContentTypeText check contentType is text
+// IsContentTypeText check contentType is text
 func IsContentTypeText(contentType string) bool {
 	for _, v := range textContentTypes {
 		if strings.Contains(contentType, v) {
@@ -205,3 +205,7 @@ func JSONEncode(obj interface{}) string {
 	bs, _ := json.Marshal(obj)
 	return string(bs)
 }
+
+func isValidStatusCode(code int) bool {
+	return code >= 100 && code <= 999
+}
@@ -2,30 +2,79 @@ package proxy
 
 import (
 	"compress/gzip"
-	"io"
+	"fmt"
 	"net/http"
 	"strings"
 )
 
+// checkGzipLevel 0 is not set,otherwise HuffmanOnly(-2)~BestCompression(9)
+func checkGzipLevel(level int) error {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return fmt.Errorf("gzip_level wrong:%d,should be %d~%d", level, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	return nil
+}
+
+// gzipLevel the first level set,the balanced default when none is set
+func gzipLevel(levels ...int) int {
+	for _, level := range levels {
+		if level != 0 {
+			return level
+		}
+	}
+	return gzip.DefaultCompression
+}
+
+// gzipResponseWriter compress the body when the status code allows a body
 type gzipResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
+	gz          *gzip.Writer
+	level       int
+	wroteHeader bool
 }
 
-func (w gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code != http.StatusNoContent && code != http.StatusNotModified {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, gzipLevel(w.level))
+	}
+	w.ResponseWriter.WriteHeader(code)
 }
 
-func makeGzipHandler(fn http.HandlerFunc) http.HandlerFunc {
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		//sniff with the raw data,not the gzipped
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+func makeGzipHandler(fn http.HandlerFunc, level int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 			fn(w, r)
 			return
 		}
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		gzr := gzipResponseWriter{Writer: gz, ResponseWriter: w}
+		gzr := &gzipResponseWriter{ResponseWriter: w, level: level}
+		defer gzr.close()
 		fn(gzr, r)
 	}
 }
@@ -1,7 +1,6 @@
 package proxy
 
 import (
-	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"github.com/googollee/go-socket.io"
@@ -109,7 +108,13 @@ func (web *webAdmin) broadAPIPvs() {
 
 	utils.SetInterval(func() {
 		var pv uint64
+		web.apiServer.Rw.RLock()
+		apis := make(map[string]*apiStruct, len(web.apiServer.Apis))
 		for name, api := range web.apiServer.Apis {
+			apis[name] = api
+		}
+		web.apiServer.Rw.RUnlock()
+		for name, api := range apis {
 			if _, has := pvs[name]; !has {
 				pvs[name] = 0
 			}
@@ -133,17 +138,12 @@ func (web *webAdmin) broadcastAPI(api *apiStruct, broadType string, reqData *Bro
 }
 
 func (web *webAdmin) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	web.serveHTTP(rw, req)
-	return
-	if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") || strings.HasPrefix(req.URL.Path, "/_socket.io/") {
+	//socket.io need hijack the conn
+	if strings.HasPrefix(req.URL.Path, "/_socket.io/") {
 		web.serveHTTP(rw, req)
 		return
 	}
-	rw.Header().Set("Content-Encoding", "gzip")
-	gz := gzip.NewWriter(rw)
-	defer gz.Close()
-	gzr := gzipResponseWriter{Writer: gz, ResponseWriter: rw}
-	web.serveHTTP(gzr, req)
+	makeGzipHandler(web.serveHTTP, web.apiServer.ServerVhostConf.GzipLevel)(rw, req)
 }
 
 func (web *webAdmin) serveHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -193,6 +193,7 @@ func (wr *webReq) execute() {
 	port, _ := strconv.ParseInt(hostInfo[1], 10, 64)
 	wr.values["host_port"] = int(port)
 	wr.values["conf"] = wr.web.apiServer.ServerVhostConf
+	wr.setBrandValues()
 	//	wr.session.Values["aaa"] = "aaa"
 	wr.getUser()
 
@@ -216,6 +217,9 @@ func (wr *webReq) execute() {
 		http.Redirect(wr.rw, wr.req, "/_/index", 302)
 		return
 	}
+	if !wr.mutationAllowed(req_path) {
+		return
+	}
 	switch req_path {
 	case "/index":
 		wr.values["Title"] = "API List"
@@ -229,6 +233,9 @@ func (wr *webReq) execute() {
 		wr.values["Title"] = "Service List"
 		wr.serviceList()
 		return
+	case "/servers":
+		wr.serverList()
+		return
 	case "/about":
 		wr.values["Title"] = "About"
 		wr.render("about.html", true)
@@ -259,6 +266,36 @@ func (wr *webReq) execute() {
 	case "/vhost":
 		wr.vhostInfo()
 		return
+	case "/effective":
+		wr.apiEffective()
+		return
+	case "/raw":
+		wr.apiRaw()
+		return
+	case "/rollback":
+		wr.apiRollback()
+		return
+	case "/trace":
+		wr.apiTrace()
+		return
+	case "/apidiff":
+		wr.apiDiff()
+		return
+	case "/flush":
+		wr.apiFlush()
+		return
+	}
+	if wr.req.URL.Path == expvarPath {
+		wr.debugVars()
+		return
+	}
+	if wr.req.URL.Path == runtimePath {
+		wr.debugRuntime()
+		return
+	}
+	if wr.req.URL.Path == faviconPath {
+		wr.favicon()
+		return
 	}
 
 	//	wr.saveSession()
@@ -307,6 +344,11 @@ func (wr *webReq) getUser() {
 
 }
 
+// userIsAdmin user has the permissions of the whole server
+func (wr *webReq) userIsAdmin() bool {
+	return wr.user != nil && wr.web.apiServer.hasUser(wr.user.ID)
+}
+
 func (wr *webReq) getUserID() string {
 	if wr.user != nil {
 		return wr.user.ID
@@ -453,13 +495,30 @@ type JSONResult struct {
 
 func (wr *webReq) json(code int, msg string, data interface{}) {
 	ret := &JSONResult{code, msg, data}
-	bs, _ := json.Marshal(ret)
+	bs := wr.marshalJSON(ret)
 	wr.rw.Header().Set("Content-Type", "application/json;charset=utf-8")
 	wr.rw.Write(bs)
 }
 
+// marshalJSON indent the output when the query has pretty=1,for reading by human
+func (wr *webReq) marshalJSON(v interface{}) []byte {
+	if wr.req.URL.Query().Get("pretty") == "1" {
+		bs, _ := json.MarshalIndent(v, "", "  ")
+		return bs
+	}
+	bs, _ := json.Marshal(v)
+	return bs
+}
+
 func (wr *webReq) render(tplName string, layout bool) {
-	html := renderHTML(tplName, wr.values, true)
+	html, err := renderHTML(tplName, wr.values, true)
+	if err != nil {
+		log.Println("[error]render admin page failed:", tplName, err)
+		wr.rw.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		wr.rw.WriteHeader(http.StatusInternalServerError)
+		wr.rw.Write([]byte("render admin page failed:" + err.Error()))
+		return
+	}
 	wr.rw.Header().Set("Content-Type", "text/html;charset=utf-8")
 	wr.rw.Write([]byte(html))
 }
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"os"
+	"strings"
+)
+
+// apiEffective the conf which is running,with all the default values filled
+func (wr *webReq) apiEffective() {
+	apiID := strings.TrimSpace(wr.req.FormValue("name"))
+	if apiID == "" {
+		wr.json(400, "param empty", nil)
+		return
+	}
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	api := wr.web.apiServer.getAPIByID(apiID)
+	if api == nil {
+		wr.json(404, "Api Not Exists", nil)
+		return
+	}
+	api.rw.RLock()
+	defer api.rw.RUnlock()
+	wr.json(0, "Success", api)
+}
+
+// apiRaw the conf file as it is,the api may failed to load
+func (wr *webReq) apiRaw() {
+	apiID := strings.TrimSpace(wr.req.FormValue("name"))
+	if !apiIDReg.MatchString(apiID) {
+		wr.json(400, "name wrong", nil)
+		return
+	}
+	//the users of the api may be :any,only the admin can read the raw conf
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	data, err := wr.web.apiServer.confSource.Get(apiID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			wr.json(404, "Api Not Exists", nil)
+			return
+		}
+		wr.json(500, "read conf failed:"+err.Error(), nil)
+		return
+	}
+	wr.rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	wr.rw.Header().Set("Content-Disposition", `attachment; filename="`+apiID+`.json"`)
+	wr.rw.Write(data)
+}
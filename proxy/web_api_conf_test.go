@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_WebAPIEffective(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "eff", `{"enable":true,"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+
+	raw := make(map[string]interface{})
+	data, _ := ioutil.ReadFile(api.ConfPath)
+	json.Unmarshal(data, &raw)
+	if _, has := raw["path"]; has {
+		t.Fatal("raw conf should not have path")
+	}
+
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/effective?name=eff", nil), nil)
+	wr.execute()
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"code":403`)) {
+		t.Error("expect 403 without login:", rec.Body.String())
+	}
+
+	admin := &User{ID: "admin"}
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/effective?name=eff", nil), admin)
+	wr.execute()
+
+	var ret struct {
+		Code int `json:"code"`
+		Data struct {
+			Path      string                     `json:"path"`
+			TimeoutMs int                        `json:"timeout_ms"`
+			Caller    []map[string]interface{}   `json:"caller"`
+			Hosts     map[string]json.RawMessage `json:"hosts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+		t.Fatal("decode failed:", err, rec.Body.String())
+	}
+	if ret.Code != 0 {
+		t.Fatal("wrong code:", rec.Body.String())
+	}
+	if ret.Data.Path != "/eff/" {
+		t.Error("default path expected,got:", ret.Data.Path)
+	}
+	if ret.Data.TimeoutMs != 5000 {
+		t.Error("default timeout expected,got:", ret.Data.TimeoutMs)
+	}
+	if len(ret.Data.Caller) != 1 || ret.Data.Caller[0]["ip"] != ipAll {
+		t.Error("default caller expected,got:", ret.Data.Caller)
+	}
+	if _, has := ret.Data.Hosts["h1"]; !has {
+		t.Error("host h1 not found")
+	}
+
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/effective?name=not_exists", nil), admin)
+	wr.execute()
+	json.Unmarshal(rec.Body.Bytes(), &ret)
+	if ret.Code != 404 {
+		t.Error("expect 404 for not exists api:", rec.Body.String())
+	}
+}
+
+func Test_WebAPIRaw(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "raw", `{"enable":true,
+	"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+	data, _ := ioutil.ReadFile(api.ConfPath)
+
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/raw?name=raw", nil), nil)
+	wr.execute()
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"code":403`)) {
+		t.Error("expect 403 without login:", rec.Body.String())
+	}
+
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/raw?name=raw", nil), &User{ID: "admin"})
+	wr.execute()
+	if !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Errorf("raw conf not match,got:%q,expect:%q", rec.Body.String(), data)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Error("wrong Content-Type:", ct)
+	}
+
+	testLoadAPI(t, apiServer, "raw_any", `{"enable":true,"users":[":any"],
+	"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/raw?name=raw_any", nil), nil)
+	wr.execute()
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"code":403`)) {
+		t.Error("expect 403 for the anonymous user of the :any api:", rec.Body.String())
+	}
+
+	wr, rec = newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/raw?name=../server", nil), &User{ID: "admin"})
+	wr.execute()
+	if !bytes.Contains(rec.Body.Bytes(), []byte(`"code":400`)) {
+		t.Error("expect 400 for wrong name:", rec.Body.String())
+	}
+}
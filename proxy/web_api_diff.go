@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// apiDiffValue a field which is different in api a and b
+type apiDiffValue struct {
+	A interface{} `json:"a"`
+	B interface{} `json:"b"`
+}
+
+// apiDiffItems diff of the hosts(by name) or the callers(by ip)
+type apiDiffItems struct {
+	OnlyA   []string                           `json:"only_a"`
+	OnlyB   []string                           `json:"only_b"`
+	Changed map[string]map[string]apiDiffValue `json:"changed"`
+}
+
+type apiDiffResult struct {
+	Fields  map[string]apiDiffValue `json:"fields"`
+	Hosts   apiDiffItems            `json:"hosts"`
+	Callers apiDiffItems            `json:"callers"`
+}
+
+// apiDiffSkipFields not compared as fields,the version is always different
+var apiDiffSkipFields = []string{"hosts", "caller", "version"}
+
+// confValues the conf of the api as json values
+func (api *apiStruct) confValues() map[string]interface{} {
+	api.rw.RLock()
+	data, _ := json.Marshal(api)
+	api.rw.RUnlock()
+	var values map[string]interface{}
+	json.Unmarshal(data, &values)
+	return values
+}
+
+func diffValues(a, b map[string]interface{}, skip []string) map[string]apiDiffValue {
+	diff := make(map[string]apiDiffValue)
+	for k, v := range a {
+		if !InStringSlice(k, skip) && !reflect.DeepEqual(v, b[k]) {
+			diff[k] = apiDiffValue{A: v, B: b[k]}
+		}
+	}
+	for k, v := range b {
+		if _, has := a[k]; !has && !InStringSlice(k, skip) {
+			diff[k] = apiDiffValue{A: nil, B: v}
+		}
+	}
+	return diff
+}
+
+func diffItems(a, b map[string]map[string]interface{}) apiDiffItems {
+	items := apiDiffItems{
+		OnlyA:   []string{},
+		OnlyB:   []string{},
+		Changed: make(map[string]map[string]apiDiffValue),
+	}
+	for key, va := range a {
+		vb, has := b[key]
+		if !has {
+			items.OnlyA = append(items.OnlyA, key)
+			continue
+		}
+		if diff := diffValues(va, vb, nil); len(diff) > 0 {
+			items.Changed[key] = diff
+		}
+	}
+	for key := range b {
+		if _, has := a[key]; !has {
+			items.OnlyB = append(items.OnlyB, key)
+		}
+	}
+	sort.Strings(items.OnlyA)
+	sort.Strings(items.OnlyB)
+	return items
+}
+
+// hostValues the hosts by name
+func hostValues(values map[string]interface{}) map[string]map[string]interface{} {
+	result := make(map[string]map[string]interface{})
+	hosts, _ := values["hosts"].(map[string]interface{})
+	for name, h := range hosts {
+		if hv, ok := h.(map[string]interface{}); ok {
+			result[name] = hv
+		}
+	}
+	return result
+}
+
+// callerValues the callers by ip
+func callerValues(values map[string]interface{}) map[string]map[string]interface{} {
+	result := make(map[string]map[string]interface{})
+	callers, _ := values["caller"].([]interface{})
+	for _, c := range callers {
+		if cv, ok := c.(map[string]interface{}); ok {
+			ip, _ := cv["ip"].(string)
+			result[ip] = cv
+		}
+	}
+	return result
+}
+
+func diffAPI(a, b *apiStruct) *apiDiffResult {
+	va, vb := a.confValues(), b.confValues()
+	return &apiDiffResult{
+		Fields:  diffValues(va, vb, apiDiffSkipFields),
+		Hosts:   diffItems(hostValues(va), hostValues(vb)),
+		Callers: diffItems(callerValues(va), callerValues(vb)),
+	}
+}
+
+// apiDiff the differences between two apis,eg after clone and modify
+func (wr *webReq) apiDiff() {
+	//the hosts and the callers are shown,the users of the api may be :any
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	var apis []*apiStruct
+	for _, key := range []string{"a", "b"} {
+		apiID := strings.TrimSpace(wr.req.FormValue(key))
+		api := wr.web.apiServer.getAPIByID(apiID)
+		if api == nil {
+			wr.json(404, "Api Not Exists:"+apiID, nil)
+			return
+		}
+		apis = append(apis, api)
+	}
+	wr.json(0, "success", diffAPI(apis[0], apis[1]))
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WebAPIDiff(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "da", `{"path":"/da/","enable":true,"timeout_ms":1000,
+		"caller":[{"ip":"10.0.0.1","enable":true,"pref":["h1"]},{"ip":"10.0.0.2","enable":true}],
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true},"h2":{"url":"http://127.0.0.1:2/","enable":true}}}`)
+	testLoadAPI(t, apiServer, "db", `{"path":"/db/","enable":true,"timeout_ms":2000,"host_as_proxy":true,
+		"caller":[{"ip":"10.0.0.1","enable":true,"pref":["h3"]}],
+		"hosts":{"h1":{"url":"http://127.0.0.1:11/","enable":true},"h3":{"url":"http://127.0.0.1:3/","enable":true}}}`)
+
+	diff := func(query string, user *User) (int, *apiDiffResult) {
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/apidiff?"+query, nil), user)
+		wr.execute()
+		var ret struct {
+			Code int            `json:"code"`
+			Data *apiDiffResult `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+			t.Fatal(err, rec.Body.String())
+		}
+		return ret.Code, ret.Data
+	}
+	if code, _ := diff("a=da&b=db", nil); code != 403 {
+		t.Error("expect 403 without login,got:", code)
+	}
+	testLoadAPI(t, apiServer, "da_any", `{"path":"/da_any/","enable":true,"users":[":any"],
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+	if code, _ := diff("a=da_any&b=da_any", nil); code != 403 {
+		t.Error("expect 403 for the anonymous user of the :any api,got:", code)
+	}
+	admin := &User{ID: "admin"}
+	if code, _ := diff("a=da&b=none", admin); code != 404 {
+		t.Error("expect 404 for not exists api,got:", code)
+	}
+	code, ret := diff("a=da&b=db", admin)
+	if code != 0 {
+		t.Fatal("diff failed:", code)
+	}
+	if len(ret.Fields) != 3 || ret.Fields["path"].B != "/db/" || ret.Fields["timeout_ms"].A != 1000.0 || ret.Fields["host_as_proxy"].B != true {
+		t.Error("wrong fields diff:", ret.Fields)
+	}
+	if len(ret.Hosts.OnlyA) != 1 || ret.Hosts.OnlyA[0] != "h2" || len(ret.Hosts.OnlyB) != 1 || ret.Hosts.OnlyB[0] != "h3" {
+		t.Error("wrong hosts diff:", ret.Hosts)
+	}
+	if h1 := ret.Hosts.Changed["h1"]; len(h1) != 1 || h1["url"].B != "http://127.0.0.1:11/" {
+		t.Error("wrong h1 diff:", h1)
+	}
+	if len(ret.Callers.OnlyA) != 1 || ret.Callers.OnlyA[0] != "10.0.0.2" || len(ret.Callers.OnlyB) != 0 {
+		t.Error("wrong callers diff:", ret.Callers)
+	}
+	if c := ret.Callers.Changed["10.0.0.1"]; len(c) != 1 || c["pref"].A == nil {
+		t.Error("wrong caller 10.0.0.1 diff:", c)
+	}
+
+	if _, ret := diff("a=da&b=da", admin); len(ret.Fields) != 0 || len(ret.Hosts.Changed) != 0 || len(ret.Callers.Changed) != 0 {
+		t.Error("expect no diff for the same api:", ret)
+	}
+}
@@ -2,7 +2,10 @@ package proxy
 
 import (
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -55,6 +58,15 @@ func (wr *webReq) apiEdit() {
 	}
 
 	do := req.FormValue("do")
+	//the changes would be written to the files but not read
+	if !wr.web.apiServer.confWritable() {
+		if do == "base" {
+			wr.alert(errConfReadOnly.Error())
+		} else {
+			wr.json(1, errConfReadOnly.Error(), nil)
+		}
+		return
+	}
 	switch do {
 	case "base":
 		wr.apiBaseSave()
@@ -129,7 +141,7 @@ func (wr *webReq) apiBaseSave() {
 	apiPath := URLPathClean(req.FormValue("path"))
 
 	if !apiIDReg.MatchString(apiID) {
-		wr.alert(fmt.Sprintf(`api Id not allow`, apiID))
+		wr.alert(fmt.Sprintf(`api Id (%s) not allow`, apiID))
 		return
 	}
 
@@ -163,34 +175,60 @@ func (wr *webReq) apiBaseSave() {
 	hostNotes := req.PostForm["host_note"]
 	hostEnables := req.PostForm["host_enable"]
 
-	if len(hostNames) != len(hostUrls) || len(hostNames) != len(hostNotes) || len(hostNames) != len(hostEnables) {
+	if len(hostNames) != len(hostUrls) || len(hostNames) != len(hostNotes) || len(hostNames) != len(hostEnables) || len(hostNameOrigs) != len(hostNames) {
 		wr.alert("Save Failed! Params Wrong!")
 		return
 	}
 
 	tmp := make(map[string]string)
 	for _, val := range hostNames {
+		if val == "" || val == webTmpName {
+			continue
+		}
 		if _, has := tmp[val]; has {
 			wr.alert("Alias Duplicate:" + val)
 			return
 		}
+		tmp[val] = val
+	}
+
+	//the options of default master are the names before rename,
+	//check it before the hosts of the api are changed
+	defaultMaster := req.FormValue("default_master")
+	for i, nameOrig := range hostNameOrigs {
+		if defaultMaster != "" && nameOrig == defaultMaster {
+			defaultMaster = hostNames[i]
+			break
+		}
+	}
+	if defaultMaster != "" && !InStringSlice(defaultMaster, hostNames) {
+		wr.alert("default master not found:" + defaultMaster)
+		return
 	}
 
 	for i, name := range hostNames {
 		if name == "" || name == webTmpName {
 			continue
 		}
+		nameOrig := hostNameOrigs[i]
 		host := newHost(name, hostUrls[i], true)
+		//keep the fields which are not in the form
+		if hostOld, has := api.Hosts[nameOrig]; has {
+			host = hostOld.copy()
+			host.Name = name
+			host.URLStr = hostUrls[i]
+		}
 		host.Note = hostNotes[i]
 		host.Enable = hostEnables[i] == "1"
 
 		//		wr.web.apiServer.
 		api.Hosts.addNewHost(host)
-		nameOrig := hostNameOrigs[i]
 		api.hostRename(nameOrig, name)
 	}
 	api.hostCheckDelete(hostNames)
 
+	api.DefaultMaster = defaultMaster
+
 	if api == nil {
 		api = wr.web.apiServer.newAPI(apiID)
 	}
@@ -232,15 +270,38 @@ func (wr *webReq) apiBaseSave() {
 		}
 	}
 
+	oldData, _ := ioutil.ReadFile(api.ConfPath)
 	err = api.save()
 	if err != nil {
 		wr.alert("Save failed：" + err.Error())
 		return
 	}
-	wr.web.apiServer.loadAPI(apiID)
+	if err := wr.reloadSaved(apiID, api.ConfPath, oldData); err != nil {
+		wr.alert("Save failed,can not reload:" + err.Error() + ",reverted")
+		return
+	}
 	wr.alertAndGo("Save Success！", "/_/api?id="+apiID)
 }
 
+// reloadSaved load the saved conf,restore the conf before saving when it can not be loaded
+func (wr *webReq) reloadSaved(apiID string, confPath string, oldData []byte) error {
+	err := wr.web.apiServer.loadAPI(apiID)
+	if err == nil {
+		return nil
+	}
+	log.Println("[error]reload saved api failed,revert:", apiID, err)
+	if len(oldData) == 0 {
+		os.Remove(confPath)
+		return err
+	}
+	if e := ioutil.WriteFile(confPath, oldData, 0644); e != nil {
+		log.Println("[error]revert api conf failed:", apiID, e)
+		return err
+	}
+	wr.web.apiServer.loadAPI(apiID)
+	return err
+}
+
 func (wr *webReq) apiCallerSave() {
 	req := wr.req
 	apiID := req.FormValue("api_id")
@@ -257,7 +318,21 @@ func (wr *webReq) apiCallerSave() {
 	callers := newCaller()
 	for _, qs := range datas {
 		qv, _ := url.ParseQuery(qs)
-		item, _ := newCallerItem(qv.Get("ip"))
+		item, err := newCallerItem(qv.Get("ip"))
+		if err != nil {
+			wr.json(1, err.Error(), nil)
+			return
+		}
+		//keep the fields which are not in the form
+		for _, itemOld := range api.Caller {
+			if itemOld.IP == item.IP {
+				item.RespHeaders = itemOld.RespHeaders
+				item.Trusted = itemOld.Trusted
+				item.TimeoutMs = itemOld.TimeoutMs
+				item.Only = itemOld.Only
+				break
+			}
+		}
 		item.Note = qv.Get("note")
 		item.Enable = qv.Get("enable") == "1"
 		if qv.Get("host_names") != "" {
@@ -275,6 +350,7 @@ func (wr *webReq) apiCallerSave() {
 		}
 		callers.addNewCallerItem(item)
 	}
+	oldData, _ := ioutil.ReadFile(api.ConfPath)
 	api.Caller = callers
 
 	err := api.save()
@@ -282,7 +358,10 @@ func (wr *webReq) apiCallerSave() {
 		wr.json(1, "Save Failed:"+err.Error(), nil)
 		return
 	}
-	wr.web.apiServer.loadAPI(apiID)
+	if err := wr.reloadSaved(apiID, api.ConfPath, oldData); err != nil {
+		wr.json(1, "Save Failed,can not reload:"+err.Error()+",reverted", nil)
+		return
+	}
 	wr.json(0, "Success", nil)
 }
 
@@ -314,6 +393,7 @@ func (wr *webReq) apiRespModifier() {
 		}
 		ms = append(ms, item)
 	}
+	oldData, _ := ioutil.ReadFile(api.ConfPath)
 	api.RespModifier = ms
 
 	err := api.save()
@@ -321,6 +401,9 @@ func (wr *webReq) apiRespModifier() {
 		wr.json(1, "Save Failed:"+err.Error(), nil)
 		return
 	}
-	wr.web.apiServer.loadAPI(apiID)
+	if err := wr.reloadSaved(apiID, api.ConfPath, oldData); err != nil {
+		wr.json(1, "Save Failed,can not reload:"+err.Error()+",reverted", nil)
+		return
+	}
 	wr.json(0, "Success！", nil)
 }
@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_WebAPIBaseSaveDefaultMaster(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "dm", `{"path":"/dm/","enable":true,"hosts":{
+		"h1":{"url":"http://127.0.0.1:1/","enable":true},
+		"h2":{"url":"http://127.0.0.1:2/","enable":true}
+	}}`)
+
+	form := func(h2Name string, defaultMaster string) url.Values {
+		return url.Values{
+			"do":             {"base"},
+			"mod":            {"update"},
+			"api_id":         {"dm"},
+			"path":           {"/dm/"},
+			"timeout":        {"5000"},
+			"enable":         {"1"},
+			"host_name":      {"h1", h2Name},
+			"host_name_orig": {"h1", "h2"},
+			"host_url":       {"http://127.0.0.1:1/", "http://127.0.0.1:2/"},
+			"host_note":      {"", ""},
+			"host_enable":    {"1", "1"},
+			"default_master": {defaultMaster},
+		}
+	}
+	post := func(form url.Values) string {
+		req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+		wr.execute()
+		return rec.Body.String()
+	}
+	save := func(h2Name string, defaultMaster string) string {
+		return post(form(h2Name, defaultMaster))
+	}
+
+	//host_name_orig missing one item
+	short := form("h2", "h1")
+	short["host_name_orig"] = []string{"h1"}
+	if body := post(short); !strings.Contains(body, "Params Wrong") {
+		t.Error("expect params wrong:", body)
+	}
+
+	//the hosts are not changed when the default master is wrong
+	if body := save("h2_bad", "not_exists"); !strings.Contains(body, "default master not found") {
+		t.Error("expect not found error:", body)
+	}
+	if hosts := apiServer.getAPIByID("dm").Hosts; hosts["h2"] == nil || hosts["h2_bad"] != nil {
+		t.Error("the hosts should not be renamed:", hosts)
+	}
+
+	//the option is the name before rename
+	if body := save("h2_new", "h2"); !strings.Contains(body, "Success") {
+		t.Fatal("save failed:", body)
+	}
+	if dm := apiServer.getAPIByID("dm").DefaultMaster; dm != "h2_new" {
+		t.Error("default master should follow the rename,got:", dm)
+	}
+}
+
+func Test_WebAPICallerSaveKeepRespHeaders(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "cs", `{"path":"/cs/","enable":true,
+		"caller":[{"ip":"10.0.0.1","enable":true,"resp_headers":{"X-Tier":"partner"},"trusted":true,"only":["h1"]}],
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+
+	form := url.Values{
+		"do":      {"caller"},
+		"api_id":  {"cs"},
+		"datas[]": {"ip=10.0.0.1&enable=1&note=changed", "ip=10.0.0.2&enable=1"},
+	}
+	req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+	wr.execute()
+	if !strings.Contains(rec.Body.String(), "Success") {
+		t.Fatal("save failed:", rec.Body.String())
+	}
+
+	api := apiServer.getAPIByID("cs")
+	if item := api.Caller.getCallerItemByIP("10.0.0.1"); item.Note != "changed" || item.RespHeaders["X-Tier"] != "partner" || !item.Trusted || len(item.Only) != 1 {
+		t.Error("resp_headers,trusted and only should be kept:", item)
+	}
+}
+
+func Test_WebAPIBaseSaveDuplicateHost(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	form := url.Values{
+		"do":             {"base"},
+		"mod":            {"new"},
+		"api_id":         {"dup"},
+		"path":           {"/dup/"},
+		"timeout":        {"5000"},
+		"host_name":      {"h1", "h1"},
+		"host_name_orig": {"", ""},
+		"host_url":       {"http://127.0.0.1:1/", "http://127.0.0.1:2/"},
+		"host_note":      {"", ""},
+		"host_enable":    {"1", "1"},
+	}
+	req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+	wr.execute()
+	if !strings.Contains(rec.Body.String(), "Alias Duplicate:h1") {
+		t.Error("expect duplicate alert:", rec.Body.String())
+	}
+	if apiServer.getAPIByID("dup") != nil {
+		t.Error("the api should not be saved")
+	}
+}
+
+func Test_WebAPIBaseSaveChangePath(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "mv", `{"path":"/mv/","enable":true,"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+
+	post := func(form url.Values) string {
+		req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+		wr.execute()
+		return rec.Body.String()
+	}
+	routeName := func(urlPath string) string {
+		if router := apiServer.routers.getRouterByReqPath(urlPath); router != nil {
+			return router.APIName
+		}
+		return ""
+	}
+
+	body := post(url.Values{
+		"do":             {"base"},
+		"mod":            {"update"},
+		"api_id":         {"mv"},
+		"path":           {"/mv2/"},
+		"timeout":        {"5000"},
+		"enable":         {"1"},
+		"host_name":      {"h1"},
+		"host_name_orig": {"h1"},
+		"host_url":       {"http://127.0.0.1:1/"},
+		"host_note":      {""},
+		"host_enable":    {"1"},
+	})
+	if !strings.Contains(body, "Success") {
+		t.Fatal("save failed:", body)
+	}
+	if name := routeName("/mv/a"); name != "" {
+		t.Error("the old path should not route,got:", name)
+	}
+	if name := routeName("/mv2/a"); name != "mv" {
+		t.Error("the new path should route to mv,got:", name)
+	}
+
+	body = post(url.Values{"do": {"changeid"}, "orig_id": {"mv"}, "new_id": {"mv_new"}})
+	if !strings.Contains(body, "success") {
+		t.Fatal("change id failed:", body)
+	}
+	if name := routeName("/mv2/a"); name != "mv_new" {
+		t.Error("the path should route to the new id,got:", name)
+	}
+	if table := apiServer.routers.load(); len(table.BindMap) != 1 {
+		t.Error("expect only one router,got:", table.BindPaths)
+	}
+}
+
+func Test_WebAPIBaseSaveReloadFailed(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "rl", `{"path":"/rl/","enable":true,"note":"old","hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+	//saved with the wrong value,but can not be loaded
+	api.Scheme = "ftp"
+
+	form := url.Values{
+		"do":             {"base"},
+		"mod":            {"update"},
+		"api_id":         {"rl"},
+		"path":           {"/rl/"},
+		"note":           {"new"},
+		"timeout":        {"5000"},
+		"enable":         {"1"},
+		"host_name":      {"h1"},
+		"host_name_orig": {"h1"},
+		"host_url":       {"http://127.0.0.1:1/"},
+		"host_note":      {""},
+		"host_enable":    {"1"},
+	}
+	req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+	wr.execute()
+	if body := rec.Body.String(); !strings.Contains(body, "can not reload") || strings.Contains(body, "Success") {
+		t.Fatal("expect the admin informed of the reload failure,got:", body)
+	}
+
+	apiNow := apiServer.getAPIByID("rl")
+	if apiNow.Note != "old" || apiNow.Scheme != "" {
+		t.Error("expect the previous conf reloaded,got:", apiNow.Note, apiNow.Scheme)
+	}
+	data, _ := ioutil.ReadFile(apiNow.ConfPath)
+	if strings.Contains(string(data), "ftp") {
+		t.Error("expect the conf file reverted,got:", string(data))
+	}
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"log"
+	"strings"
+)
+
+// apiFlushResult how many are reset
+type apiFlushResult struct {
+	Apis      int `json:"apis"`
+	Hosts     int `json:"hosts"`
+	DNSCaches int `json:"dns_caches"`
+}
+
+// flushState reset the runtime state of the api:
+// the all-fail cooldown,the health of hosts and the dns caches
+func (api *apiStruct) flushState(ret *apiFlushResult) {
+	api.resetBackoff()
+	api.rw.RLock()
+	defer api.rw.RUnlock()
+	for _, host := range api.Hosts {
+		host.stats.reset()
+		ret.Hosts++
+		if host.dns != nil {
+			host.dns.clear()
+			ret.DNSCaches++
+		}
+	}
+	ret.Apis++
+}
+
+// apiFlush reset the state of all the apis,or the one by name,
+// eg after a backend is fixed
+func (wr *webReq) apiFlush() {
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	ret := &apiFlushResult{}
+	apiID := strings.TrimSpace(wr.req.FormValue("name"))
+	if apiID != "" {
+		api := wr.web.apiServer.getAPIByID(apiID)
+		if api == nil {
+			wr.json(404, "Api Not Exists", nil)
+			return
+		}
+		api.flushState(ret)
+	} else {
+		for _, api := range wr.web.apiServer.apiSnapshot() {
+			api.flushState(ret)
+		}
+	}
+	log.Println("[info]flush by", wr.getUserID(), "name:", apiID, "apis:", ret.Apis)
+	wr.json(0, "success", ret)
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WebAPIFlush(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "fl", `{"path":"/fl/","enable":true,"all_fail_threshold":1,"all_fail_cooldown_ms":60000,
+		"hosts":{"h1":{"url":"http://a.test/","enable":true,"dns_cache_sec":60}}}`)
+	testLoadAPI(t, apiServer, "fl2", `{"path":"/fl2/","enable":true,"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+
+	host := api.Hosts["h1"]
+	host.dns.resolver = &testResolver{addrs: map[string][]string{"a.test": {"10.0.0.1"}}}
+	host.dns.lookup(context.Background(), "a.test")
+	host.stats.record(time.Millisecond, true)
+	api.reportAllFail(true)
+	if !api.inCooldown() {
+		t.Fatal("expect in cooldown")
+	}
+
+	flush := func(query string, user *User) (int, *apiFlushResult) {
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/flush?"+query, nil), user)
+		wr.execute()
+		var ret struct {
+			Code int             `json:"code"`
+			Data *apiFlushResult `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &ret)
+		return ret.Code, ret.Data
+	}
+	if code, _ := flush("", &User{ID: "guest"}); code != 403 {
+		t.Error("expect 403 for not admin,got:", code)
+	}
+	code, ret := flush("", &User{ID: "admin"})
+	if code != 0 || ret.Apis != 2 || ret.Hosts != 2 || ret.DNSCaches != 1 {
+		t.Fatal("flush failed:", code, ret)
+	}
+	if api.inCooldown() {
+		t.Error("expect cooldown reset")
+	}
+	if fails, latency := host.stats.get(); fails != 0 || latency != 0 {
+		t.Error("expect host stats reset:", fails, latency)
+	}
+	if len(host.dns.entries) != 0 {
+		t.Error("expect dns cache empty")
+	}
+	if code, ret := flush("name=fl2", &User{ID: "admin"}); code != 0 || ret.Apis != 1 {
+		t.Error("flush one api failed:", code, ret)
+	}
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// apiTraceResult how the rules resolve for the request
+type apiTraceResult struct {
+	IP        string      `json:"ip"`
+	Caller    *CallerItem `json:"caller"`
+	Master    string      `json:"master"`
+	Hosts     []string    `json:"hosts"`
+	StickyKey string      `json:"sticky_key,omitempty"`
+}
+
+// callerIdentityHeaders the headers tell who the caller is,
+// a non-admin can not pretend to be another caller with them
+var callerIdentityHeaders = []string{"X-Real-Ip", "X-Forwarded-For"}
+
+// apiTrace resolve the caller,master and hosts for a request without calling the hosts.
+// params: name,method,path,body,header(multi,"Name:Value"),
+// ip(admin only,pretend the request is from this caller ip),
+// the identity headers(X-Real-Ip...) are for admin only too
+func (wr *webReq) apiTrace() {
+	req := wr.req
+	api := wr.web.apiServer.getAPIByID(strings.TrimSpace(req.FormValue("name")))
+	if api == nil {
+		wr.json(404, "Api Not Exists", nil)
+		return
+	}
+	if !api.userCanEdit(wr.user) {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+
+	method := strings.ToUpper(req.FormValue("method"))
+	if method == "" {
+		method = "GET"
+	}
+	reqPath := req.FormValue("path")
+	if reqPath == "" {
+		reqPath = api.Path
+	}
+	body := req.FormValue("body")
+	traceReq, err := http.NewRequest(method, "http://"+req.Host+reqPath, strings.NewReader(body))
+	if err != nil {
+		wr.json(400, "build request failed:"+err.Error(), nil)
+		return
+	}
+	traceReq.RemoteAddr = req.RemoteAddr
+	for _, line := range req.Form["header"] {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		name := http.CanonicalHeaderKey(strings.TrimSpace(kv[0]))
+		if InStringSlice(name, callerIdentityHeaders) && !wr.userIsAdmin() {
+			wr.json(403, "only admin can set the header "+name, nil)
+			return
+		}
+		traceReq.Header.Add(name, strings.TrimSpace(kv[1]))
+	}
+	if ip := strings.TrimSpace(req.FormValue("ip")); ip != "" {
+		if !wr.userIsAdmin() {
+			wr.json(403, "only admin can set the caller ip", nil)
+			return
+		}
+		if !ipReg.MatchString(ip) {
+			wr.json(400, "ip wrong", nil)
+			return
+		}
+		//as the request is from the ip directly
+		traceReq.RemoteAddr = net.JoinHostPort(ip, "0")
+		traceReq.Header.Del("X-Real-Ip")
+	}
+
+	traceReq, stickyKey := api.withStickyKey(traceReq, []byte(body))
+	hosts, master, cpf := api.getAPIHostsByReq(traceReq)
+	ret := &apiTraceResult{
+		IP:        cpf.GetIP(),
+		Caller:    api.Caller.getCallerItemByIP(cpf.GetIP()),
+		Master:    master,
+		Hosts:     make([]string, 0, len(hosts)),
+		StickyKey: stickyKey,
+	}
+	for _, h := range hosts {
+		ret.Hosts = append(ret.Hosts, h.Name)
+	}
+	wr.json(0, "Success", ret)
+}
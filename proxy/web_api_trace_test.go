@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func Test_WebAPITraceCallerIP(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "tr", `{"path":"/tr/","enable":true,
+		"caller":[
+			{"ip":"10.0.0.1","enable":true,"pref":["h2"],"note":"partner"},
+			{"ip":"10.0.0.2","enable":true,"ignore":["h2"]},
+			{"ip":"*.*.*.*","enable":true,"pref":["h1"]}
+		],
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true},"h2":{"url":"http://127.0.0.1:2/","enable":true}}}`)
+
+	trace := func(ip string, user *User) (int, *apiTraceResult) {
+		q := url.Values{"name": {"tr"}, "path": {"/tr/a"}, "ip": {ip}}
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/trace?"+q.Encode(), nil), user)
+		wr.execute()
+		var ret struct {
+			Code int             `json:"code"`
+			Data *apiTraceResult `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+			t.Fatal(err, rec.Body.String())
+		}
+		return ret.Code, ret.Data
+	}
+
+	admin := &User{ID: "admin"}
+	cases := []struct {
+		ip     string
+		master string
+		hosts  int
+	}{
+		{"10.0.0.1", "h2", 2},
+		{"10.0.0.2", "", 1},
+		{"10.0.0.3", "h1", 2},
+	}
+	for _, c := range cases {
+		code, ret := trace(c.ip, admin)
+		if code != 0 || ret.IP != c.ip || (c.master != "" && ret.Master != c.master) || len(ret.Hosts) != c.hosts {
+			t.Errorf("ip %s,wrong trace:%d %+v", c.ip, code, ret)
+		}
+	}
+	if _, ret := trace("10.0.0.1", admin); ret.Caller == nil || ret.Caller.Note != "partner" {
+		t.Error("wrong caller:", ret.Caller)
+	}
+
+	apiServer.getAPIByID("tr").Users = users{"bob"}
+	if code, _ := trace("10.0.0.1", &User{ID: "bob"}); code != 403 {
+		t.Error("only admin can set the ip,got:", code)
+	}
+	if code, ret := trace("", &User{ID: "bob"}); code != 0 || ret.IP != "192.0.2.1" {
+		t.Error("the api user can trace as self:", code, ret)
+	}
+}
+
+func Test_WebAPITraceIdentityHeader(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	api := testLoadAPI(t, apiServer, "tr", `{"path":"/tr/","enable":true,
+		"caller":[{"ip":"10.0.0.1","enable":true,"pref":["h2"]}],
+		"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true},"h2":{"url":"http://127.0.0.1:2/","enable":true}}}`)
+	api.Users = users{"bob"}
+
+	trace := func(header string, user *User) (int, *apiTraceResult) {
+		q := url.Values{"name": {"tr"}, "path": {"/tr/a"}, "header": {header}}
+		req := httptest.NewRequest("GET", "/_/trace?"+q.Encode(), nil)
+		//from the trusted front proxy
+		req.RemoteAddr = "127.0.0.1:5555"
+		wr, rec := newTestWebReq(apiServer, req, user)
+		wr.execute()
+		var ret struct {
+			Code int             `json:"code"`
+			Data *apiTraceResult `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+			t.Fatal(err, rec.Body.String())
+		}
+		return ret.Code, ret.Data
+	}
+	for _, header := range []string{"X-Real-Ip:10.0.0.1", "x-real-ip: 10.0.0.1", "X-Forwarded-For:10.0.0.1"} {
+		if code, ret := trace(header, &User{ID: "bob"}); code != 403 {
+			t.Error("non-admin can not set", header, "got:", code, ret)
+		}
+	}
+	if code, ret := trace("X-Custom:1", &User{ID: "bob"}); code != 0 || ret.IP != "127.0.0.1" {
+		t.Error("other headers are allowed:", code, ret)
+	}
+	if code, ret := trace("X-Real-Ip:10.0.0.1", &User{ID: "admin"}); code != 0 || ret.IP != "10.0.0.1" {
+		t.Error("admin can set X-Real-Ip:", code, ret)
+	}
+}
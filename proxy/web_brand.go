@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+)
+
+const faviconPath = "/favicon.ico"
+
+const defaultAdminTitle = "api front"
+
+// setBrandValues the title and logo used by layout
+func (wr *webReq) setBrandValues() {
+	conf := wr.web.apiServer.ServerVhostConf
+	wr.values["brand_title"] = defaultAdminTitle
+	if conf.AdminTitle != "" {
+		wr.values["brand_title"] = conf.AdminTitle
+	}
+	wr.values["brand_logo"] = conf.AdminLogo
+}
+
+// favicon serve the favicon file of server conf,204 when not set
+func (wr *webReq) favicon() {
+	iconPath := wr.web.apiServer.ServerVhostConf.Favicon
+	if iconPath == "" {
+		wr.rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !filepath.IsAbs(iconPath) {
+		iconPath = filepath.Join(wr.web.apiServer.rootConfDir(), iconPath)
+	}
+	data, err := ioutil.ReadFile(iconPath)
+	if err != nil {
+		log.Println("[warning]read favicon failed:", err)
+		http.NotFound(wr.rw, wr.req)
+		return
+	}
+	ct := mime.TypeByExtension(filepath.Ext(iconPath))
+	if ct == "" {
+		ct = "image/x-icon"
+	}
+	wr.rw.Header().Set("Content-Type", ct)
+	wr.rw.Header().Set("Cache-Control", "max-age=86400")
+	wr.rw.Write(data)
+}
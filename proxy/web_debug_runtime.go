@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+const runtimePath = "/_debug/runtime"
+
+// fanoutActive the async requests to the hosts which are not finished
+var fanoutActive int64
+
+func fanoutAdd(delta int64) {
+	atomic.AddInt64(&fanoutActive, delta)
+}
+
+// runtimeMem part of runtime.MemStats
+type runtimeMem struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+// runtimeStats the stats to find out the goroutines leaked by the hung backends
+type runtimeStats struct {
+	Goroutines   int        `json:"goroutines"`
+	FanoutActive int64      `json:"fanout_active"`
+	Mem          runtimeMem `json:"mem"`
+}
+
+func getRuntimeStats() *runtimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return &runtimeStats{
+		Goroutines:   runtime.NumGoroutine(),
+		FanoutActive: atomic.LoadInt64(&fanoutActive),
+		Mem: runtimeMem{
+			Alloc:        ms.Alloc,
+			TotalAlloc:   ms.TotalAlloc,
+			Sys:          ms.Sys,
+			HeapAlloc:    ms.HeapAlloc,
+			HeapInuse:    ms.HeapInuse,
+			HeapObjects:  ms.HeapObjects,
+			NumGC:        ms.NumGC,
+			PauseTotalNs: ms.PauseTotalNs,
+		},
+	}
+}
+
+func (wr *webReq) debugRuntime() {
+	if !wr.userIsAdmin() {
+		wr.json(403, "No permissions!", nil)
+		return
+	}
+	wr.json(0, "success", getRuntimeStats())
+}
@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WebDebugRuntime(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	get := func(user *User) (int, map[string]interface{}) {
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", runtimePath, nil), user)
+		wr.execute()
+		var ret struct {
+			Code int                    `json:"code"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+			t.Fatal(err, rec.Body.String())
+		}
+		return ret.Code, ret.Data
+	}
+	if code, _ := get(&User{ID: "guest"}); code != 403 {
+		t.Error("expect 403 for not admin,got:", code)
+	}
+	code, data := get(&User{ID: "admin"})
+	if code != 0 {
+		t.Fatal("expect success,got:", code)
+	}
+	for _, field := range []string{"goroutines", "fanout_active", "mem"} {
+		if _, has := data[field]; !has {
+			t.Error("field missing:", field)
+		}
+	}
+	if n, _ := data["goroutines"].(float64); n < 1 || n > 1e5 {
+		t.Error("goroutines not plausible:", data["goroutines"])
+	}
+	mem, _ := data["mem"].(map[string]interface{})
+	if alloc, _ := mem["alloc"].(float64); alloc <= 0 {
+		t.Error("expect mem.alloc,got:", mem)
+	}
+}
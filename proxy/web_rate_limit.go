@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// adminRateLimiter token bucket shared by all the admin servers,
+// refilled at perMin/minute,up to perMin tokens
+type adminRateLimiter struct {
+	mu     sync.Mutex
+	perMin int
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newAdminRateLimiter(perMin int) *adminRateLimiter {
+	return &adminRateLimiter{perMin: perMin, tokens: float64(perMin), now: time.Now}
+}
+
+// allow take one token,otherwise return how long to wait for the next one
+func (l *adminRateLimiter) allow() (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Minutes() * float64(l.perMin)
+		if l.tokens > float64(l.perMin) {
+			l.tokens = float64(l.perMin)
+		}
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - l.tokens) / float64(l.perMin) * float64(time.Minute))
+	return false, wait
+}
+
+// adminLimiter nil when admin_mutation_per_min is not set
+func (conf *mainConf) adminLimiter() *adminRateLimiter {
+	conf.adminLimiterOnce.Do(func() {
+		if conf.AdminMutationPerMin > 0 {
+			conf.adminRate = newAdminRateLimiter(conf.AdminMutationPerMin)
+		}
+	})
+	return conf.adminRate
+}
+
+// isMutation the request saves or reloads the confs
+func (wr *webReq) isMutation(reqPath string) bool {
+	switch reqPath {
+	case "/rollback":
+		return true
+	case "/api", "/vhost":
+		return wr.req.Method == "POST"
+	}
+	return false
+}
+
+// mutationAllowed write 429 when the admin changes are too frequent
+func (wr *webReq) mutationAllowed(reqPath string) bool {
+	if !wr.isMutation(reqPath) {
+		return true
+	}
+	limiter := wr.web.apiServer.manager.mainConf.adminLimiter()
+	if limiter == nil {
+		return true
+	}
+	ok, wait := limiter.allow()
+	if ok {
+		return true
+	}
+	wr.rw.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+	wr.rw.Header().Set("Content-Type", "application/json;charset=utf-8")
+	wr.rw.WriteHeader(http.StatusTooManyRequests)
+	wr.rw.Write(wr.marshalJSON(&JSONResult{http.StatusTooManyRequests, "too many changes,retry later", nil}))
+	return false
+}
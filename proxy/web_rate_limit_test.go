@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AdminRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newAdminRateLimiter(2)
+	l.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow(); !ok {
+			t.Fatal("expect allowed in burst:", i)
+		}
+	}
+	ok, wait := l.allow()
+	if ok || wait != 30*time.Second {
+		t.Error("expect rejected,wait 30s,got:", ok, wait)
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _ := l.allow(); !ok {
+		t.Error("expect allowed after refill")
+	}
+}
+
+func Test_WebAdminMutationLimit(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	apiServer.manager.mainConf.AdminMutationPerMin = 3
+	testLoadAPI(t, apiServer, "ml", `{"path":"/ml/","enable":true,"hosts":{"h1":{"url":"http://127.0.0.1:1/","enable":true}}}`)
+
+	save := func() *httptest.ResponseRecorder {
+		form := url.Values{
+			"do":      {"caller"},
+			"api_id":  {"ml"},
+			"datas[]": {"ip=10.0.0.1&enable=1"},
+		}
+		req := httptest.NewRequest("POST", "/_/api", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		wr, rec := newTestWebReq(apiServer, req, &User{ID: "admin"})
+		wr.execute()
+		return rec
+	}
+	for i := 0; i < 3; i++ {
+		if rec := save(); rec.Code != 200 || !strings.Contains(rec.Body.String(), "Success") {
+			t.Fatal("save failed:", i, rec.Code, rec.Body.String())
+		}
+	}
+	rec := save()
+	if rec.Code != 429 || rec.Header().Get("Retry-After") != "20" {
+		t.Error("expect 429 with Retry-After,got:", rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+
+	//the pages which do not change confs are not limited
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/api?id=ml", nil), &User{ID: "admin"})
+	wr.execute()
+	if rec.Code != 200 {
+		t.Error("expect GET not limited,got:", rec.Code)
+	}
+}
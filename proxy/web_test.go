@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestWebReq build a admin request,the user is logined when not nil
+func newTestWebReq(apiServer *APIServer, req *http.Request, user *User) (*webReq, *httptest.ResponseRecorder) {
+	if req.Host == "" || req.Host == "example.com" {
+		req.Host = "127.0.0.1:8080"
+	}
+	rec := httptest.NewRecorder()
+	session, _ := apiServer.web.sessionStore.Get(req, sessionName)
+	wr := &webReq{
+		rw:      rec,
+		req:     req,
+		web:     apiServer.web,
+		values:  make(map[string]interface{}),
+		session: session,
+		user:    user,
+	}
+	return wr, rec
+}
+
+func Test_WebAdminGzip(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+
+	req := httptest.NewRequest("GET", "/_/about", nil)
+	req.Host = "127.0.0.1:8080"
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	apiServer.web.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expect gzipped response,header:", rec.Header())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Error("wrong Content-Type:", ct)
+	}
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal("not gzip data:", err)
+	}
+	body, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal("decode gzip failed:", err)
+	}
+	if !strings.Contains(string(body), "</html>") {
+		t.Error("wrong body:", string(body))
+	}
+
+	req = httptest.NewRequest("GET", "/_/about", nil)
+	req.Host = "127.0.0.1:8080"
+	rec = httptest.NewRecorder()
+	apiServer.web.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "</html>") {
+		t.Error("expect plain response when gzip not accepted")
+	}
+}
+
+func Test_WebAdminGzipLevel(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	sizes := make(map[int]int)
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestCompression} {
+		apiServer.ServerVhostConf.GzipLevel = level
+		req := httptest.NewRequest("GET", "/_/about", nil)
+		req.Host = "127.0.0.1:8080"
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		apiServer.web.ServeHTTP(rec, req)
+		sizes[level] = rec.Body.Len()
+
+		gr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal("level", level, "not gzip data:", err)
+		}
+		body, err := ioutil.ReadAll(gr)
+		if err != nil || !strings.Contains(string(body), "</html>") {
+			t.Fatal("level", level, "decode failed:", err, string(body))
+		}
+	}
+	if sizes[gzip.BestCompression] >= sizes[gzip.HuffmanOnly] {
+		t.Error("expect smaller output with the higher level:", sizes)
+	}
+}
+
+func Test_GzipLevelWrong(t *testing.T) {
+	for _, level := range []int{0, -2, -1, 1, 9} {
+		if err := checkGzipLevel(level); err != nil {
+			t.Error("level", level, "should be valid:", err)
+		}
+	}
+	for _, level := range []int{-3, 10} {
+		if err := checkGzipLevel(level); err == nil {
+			t.Error("level", level, "should be wrong")
+		}
+	}
+	api := newTestAPIServer(t).newAPI("wrong")
+	api.GzipLevel = 10
+	if err := api.init(); err == nil {
+		t.Error("expect error for wrong gzip_level")
+	}
+}
+
+func Test_WebAdminBrand(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	serve := func(urlPath string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", urlPath, nil)
+		req.Host = "127.0.0.1:8080"
+		rec := httptest.NewRecorder()
+		apiServer.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(faviconPath); rec.Code != http.StatusNoContent {
+		t.Error("expect 204 when favicon not set,got:", rec.Code)
+	}
+	if body := serve("/_/about").Body.String(); !strings.Contains(body, "| "+defaultAdminTitle+" |") {
+		t.Error("default title not found")
+	}
+
+	icon := []byte("\x00\x00\x01\x00fake icon")
+	ioutil.WriteFile(filepath.Join(apiServer.rootConfDir(), "my.ico"), icon, 0644)
+	apiServer.ServerVhostConf.Favicon = "my.ico"
+	apiServer.ServerVhostConf.AdminTitle = "Order Gateway"
+	apiServer.ServerVhostConf.AdminLogo = "/_/res/img/logo.png"
+
+	rec := serve(faviconPath)
+	if rec.Code != 200 || rec.Body.String() != string(icon) {
+		t.Error("favicon not served:", rec.Code, rec.Body.String())
+	}
+	body := serve("/_/about").Body.String()
+	if !strings.Contains(body, "<title>About | Order Gateway |") {
+		t.Error("title not overridden")
+	}
+	if !strings.Contains(body, `<img src="/_/res/img/logo.png"`) {
+		t.Error("logo not found")
+	}
+}
+
+func Test_WebJSONPretty(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	get := func(query string) string {
+		wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/flush"+query, nil), &User{ID: "admin"})
+		wr.execute()
+		return rec.Body.String()
+	}
+	compact := get("")
+	if strings.Contains(compact, "\n") || !strings.HasPrefix(compact, `{"code":0,`) {
+		t.Error("expect compact json,got:", compact)
+	}
+	pretty := get("?pretty=1")
+	if !strings.HasPrefix(pretty, "{\n  \"code\": 0,\n") || !strings.Contains(pretty, "\n    \"apis\": 0") {
+		t.Error("expect indented json,got:", pretty)
+	}
+	if get("?pretty=0") != compact {
+		t.Error("expect compact json when pretty is not 1")
+	}
+}
@@ -6,29 +6,91 @@ import (
 	"github.com/hidu/goutils"
 	"html"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 )
 
-func readerHTMLInclude(fileName string) string {
-
-	html := Assest.GetContent("/res/tpl/" + fileName)
+// readerHTMLInclude the template with the included ones,
+// error with the asset name when one is missing,eg a broken package
+func readerHTMLInclude(fileName string) (string, error) {
+	assetName := "/res/tpl/" + fileName
+	file, err := Assest.GetAssestFile(assetName)
+	if err != nil {
+		return "", fmt.Errorf("template asset missing:%s", assetName)
+	}
 	myfn := template.FuncMap{
-		"my_include": func(name string) string {
+		"my_include": func(name string) (string, error) {
 			return readerHTMLInclude(name)
 		},
 	}
-	tpl, _ := template.New("page_include").Delims("{%", "%}").Funcs(myfn).Parse(html)
+	tpl, err := template.New("page_include").Delims("{%", "%}").Funcs(myfn).Parse(file.Content)
+	if err != nil {
+		return "", err
+	}
 	var bf []byte
 	w := bytes.NewBuffer(bf)
-	tpl.Execute(w, make(map[string]string))
+	if err := tpl.Execute(w, make(map[string]string)); err != nil {
+		return "", err
+	}
 	body := w.String()
-	return body
+	return body, nil
 }
 
-func renderHTML(fileName string, values map[string]interface{}, layout bool) string {
-	htmlStr := readerHTMLInclude(fileName)
-	myfn := template.FuncMap{
+// tplFuncs the template functions registered by RegisterTemplateFunc
+var tplFuncs = struct {
+	sync.RWMutex
+	m template.FuncMap
+}{m: make(template.FuncMap)}
+
+// RegisterTemplateFunc add a function for the admin templates,eg for formatting,
+// call it at startup,the built-in ones can not be replaced
+func RegisterTemplateFunc(name string, fn interface{}) (err error) {
+	if _, has := builtinTplFuncs()[name]; has {
+		return fmt.Errorf("template func [%s] is built-in", name)
+	}
+	//Funcs panics when the name or the func is wrong
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("template func [%s] wrong:%v", name, r)
+		}
+	}()
+	template.New("check").Funcs(template.FuncMap{name: fn})
+
+	tplFuncs.Lock()
+	defer tplFuncs.Unlock()
+	tplFuncs.m[name] = fn
+	return nil
+}
+
+func renderHTML(fileName string, values map[string]interface{}, layout bool) (string, error) {
+	htmlStr, err := readerHTMLInclude(fileName)
+	if err != nil {
+		return "", err
+	}
+	myfn := builtinTplFuncs()
+	tplFuncs.RLock()
+	for name, fn := range tplFuncs.m {
+		myfn[name] = fn
+	}
+	tplFuncs.RUnlock()
+
+	tpl, _ := template.New("page").Funcs(myfn).Parse(htmlStr)
+
+	var bf []byte
+	w := bytes.NewBuffer(bf)
+	tpl.Execute(w, values)
+	body := w.String()
+	if layout {
+		values["body"] = body
+		return renderHTML("layout.html", values, false)
+	}
+	//	return body
+	return utils.Html_reduceSpace(body), nil
+}
+
+func builtinTplFuncs() template.FuncMap {
+	return template.FuncMap{
 		"shortTime": func(tu int64) string {
 			t := time.Unix(tu, 0)
 			return t.Format(timeFormatStd)
@@ -64,17 +126,4 @@ func renderHTML(fileName string, values map[string]interface{}, layout bool) str
 			return strings.Join(arr, "\n")
 		},
 	}
-
-	tpl, _ := template.New("page").Funcs(myfn).Parse(htmlStr)
-
-	var bf []byte
-	w := bytes.NewBuffer(bf)
-	tpl.Execute(w, values)
-	body := w.String()
-	if layout {
-		values["body"] = body
-		return renderHTML("layout.html", values, false)
-	}
-	//	return body
-	return utils.Html_reduceSpace(body)
 }
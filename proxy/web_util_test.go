@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RegisterTemplateFunc(t *testing.T) {
+	if err := RegisterTemplateFunc("h", strings.ToUpper); err == nil {
+		t.Error("built-in func should not be replaced")
+	}
+	if err := RegisterTemplateFunc("bad", 1); err == nil {
+		t.Error("expect error for not a func")
+	}
+	if err := RegisterTemplateFunc("test_upper", strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tplFuncs.Lock()
+		delete(tplFuncs.m, "test_upper")
+		tplFuncs.Unlock()
+	})
+
+	name := "/res/tpl/_test_theme.html"
+	Assest.Files[name] = &AssestFile{Name: name, Content: `<b>{{test_upper .name}}</b>{{h .tag}}`}
+	t.Cleanup(func() {
+		delete(Assest.Files, name)
+	})
+	html, err := renderHTML("_test_theme.html", map[string]interface{}{"name": "hello", "tag": "<i>"}, false)
+	if err != nil || html != "<b>HELLO</b>&lt;i&gt;" {
+		t.Error("render with custom func failed,got:", html, err)
+	}
+}
+
+func Test_WebRenderMissingTemplate(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	req := httptest.NewRequest("GET", "/_/about", nil)
+	wr, rec := newTestWebReq(apiServer, req, nil)
+	wr.render("_not_exists.html", true)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "/res/tpl/_not_exists.html") {
+		t.Error("expect error with the missing asset name,got:", rec.Code, rec.Body.String())
+	}
+
+	//the included one is missing
+	name := "/res/tpl/_test_include.html"
+	Assest.Files[name] = &AssestFile{Name: name, Content: `<div>{% my_include "_missing_part.html" %}</div>`}
+	t.Cleanup(func() {
+		delete(Assest.Files, name)
+	})
+	if _, err := renderHTML("_test_include.html", map[string]interface{}{}, false); err == nil || !strings.Contains(err.Error(), "/res/tpl/_missing_part.html") {
+		t.Error("expect error with the missing include name,got:", err)
+	}
+}
@@ -36,3 +36,51 @@ func (wr *webReq) vhostInfo() {
 		vhost.Enable = true
 	}
 }
+
+// serverInfo one server in the servers list
+type serverInfo struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Group   string   `json:"group"`
+	Port    int      `json:"port"`
+	Domains []string `json:"domains"`
+	Enable  bool     `json:"enable"`
+	Running bool     `json:"running"`
+	Apis    int      `json:"apis"`
+}
+
+// getAPIServer the running server of the vhost,nil when it is not started
+func (manager *APIServerManager) getAPIServer(vhost *serverVhost) *APIServer {
+	if manager.ps == nil {
+		return nil
+	}
+	ps, has := manager.ps.PortServerMap[vhost.Port]
+	if !has {
+		return nil
+	}
+	return ps.APIServiers[vhost.Id]
+}
+
+// serverList all the servers as json,for the dashboards
+func (wr *webReq) serverList() {
+	manager := wr.web.apiServer.manager
+	servers := make([]*serverInfo, 0, len(manager.mainConf.VhostConfs))
+	for _, vhost := range manager.mainConf.VhostConfs {
+		info := &serverInfo{
+			ID:      vhost.Id,
+			Name:    vhost.Name,
+			Group:   vhost.Group,
+			Port:    vhost.Port,
+			Domains: vhost.Domains,
+			Enable:  vhost.Enable,
+		}
+		if apiServer := manager.getAPIServer(vhost); apiServer != nil {
+			info.Running = true
+			apiServer.Rw.RLock()
+			info.Apis = len(apiServer.Apis)
+			apiServer.Rw.RUnlock()
+		}
+		servers = append(servers, info)
+	}
+	wr.json(0, "success", servers)
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_WebServerList(t *testing.T) {
+	apiServer := newTestAPIServer(t)
+	testLoadAPI(t, apiServer, "s1", `{"path":"/s1/","enable":true,"hosts":{}}`)
+	testLoadAPI(t, apiServer, "s2", `{"path":"/s2/","enable":true,"hosts":{}}`)
+	manager := apiServer.manager
+	stopped := &serverVhost{Id: "stopped", Port: 8081, Name: "stopped one"}
+	manager.mainConf.VhostConfs = []*serverVhost{apiServer.ServerVhostConf, stopped}
+	manager.ps = &portServerManager{
+		PortServerMap: map[int]*portServer{
+			8080: {Port: 8080, APIServiers: map[string]*APIServer{"test": apiServer}},
+		},
+		manager: manager,
+	}
+
+	wr, rec := newTestWebReq(apiServer, httptest.NewRequest("GET", "/_/servers", nil), nil)
+	wr.execute()
+	var ret struct {
+		Code int           `json:"code"`
+		Data []*serverInfo `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+		t.Fatal(err, rec.Body.String())
+	}
+	if ret.Code != 0 || len(ret.Data) != 2 {
+		t.Fatal("wrong server list:", rec.Body.String())
+	}
+	if s := ret.Data[0]; s.ID != "test" || s.Port != 8080 || !s.Enable || !s.Running || s.Apis != 2 {
+		t.Errorf("wrong running server:%+v", s)
+	}
+	if s := ret.Data[1]; s.ID != "stopped" || s.Port != 8081 || s.Enable || s.Running || s.Apis != 0 {
+		t.Errorf("wrong stopped server:%+v", s)
+	}
+}